@@ -3,23 +3,39 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/briandowns/spinner"
 	"github.com/charmbracelet/glamour"
 	"github.com/j0lvera/miso/internal/agents"
+	"github.com/j0lvera/miso/internal/budget"
+	"github.com/j0lvera/miso/internal/cache"
+	"github.com/j0lvera/miso/internal/chunk"
 	"github.com/j0lvera/miso/internal/config"
 	"github.com/j0lvera/miso/internal/diff"
 	"github.com/j0lvera/miso/internal/git"
 	misoGithub "github.com/j0lvera/miso/internal/github"
+	"github.com/j0lvera/miso/internal/pool"
+	"github.com/j0lvera/miso/internal/prompts"
+	"github.com/j0lvera/miso/internal/report"
 	"github.com/j0lvera/miso/internal/resolver"
+	"github.com/j0lvera/miso/internal/rules"
+	"github.com/j0lvera/miso/internal/tools"
+	"github.com/j0lvera/miso/internal/triage"
+	"github.com/mattn/go-isatty"
 )
 
 var version = "0.5.0"
@@ -29,13 +45,63 @@ const (
 	spinnerRefreshRate = 100 * time.Millisecond
 )
 
+const supersededWarning = "⚠️ The response above couldn't be parsed and is being repaired; the review below replaces it."
+
+const truncatedWarning = "⚠️ The model response was cut off; only the complete suggestions are shown. Consider raising llm.max_tokens."
+
+// Exit codes, documented in the README. Invalid command lines exit with 80.
+const (
+	exitCodeFindings    = 1 // Findings at or above the --fail-on severity
+	exitCodeFailedFiles = 2 // Some files failed to be reviewed
+	exitCodeConfig      = 3 // Invalid configuration, flags or credentials
+	exitCodeError       = 4 // Any other error
+)
+
+// Severity thresholds of --fail-on.
+const (
+	failOnNone     = "none"
+	failOnCritical = "critical"
+	failOnWarning  = "warning"
+	failOnAny      = "any"
+)
+
+// exitError is an error that exits miso with a specific code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+func (e *exitError) ExitCode() int { return e.code }
+
+// configError marks err as a configuration error.
+func configError(err error) error {
+	return &exitError{code: exitCodeConfig, err: err}
+}
+
+// withExitCode gives errors without an exit code the code of any other error.
+func withExitCode(err error) error {
+	var coder kong.ExitCoder
+	if err != nil && !errors.As(err, &coder) {
+		return &exitError{code: exitCodeError, err: err}
+	}
+	return err
+}
+
 type CLI struct {
-	Config string `short:"c" help:"Path to config file" type:"existingfile"`
+	Config  string      `short:"c" help:"Path to config file" type:"existingfile"`
+	LLM     LLMFlags    `embed:"" prefix:"llm-" group:"LLM"`
+	Budget  BudgetFlags `embed:"" group:"Budget"`
+	Agent   AgentFlags  `embed:"" group:"Agent"`
+	Verify  VerifyFlags `embed:"" group:"Verification"`
+	NoCache bool        `name:"no-cache" help:"Don't read or write the response cache."`
 
 	Review         ReviewCmd         `cmd:"" help:"Review a code file"`
 	Diff           DiffCmd           `cmd:"" help:"Review changes in a git diff"`
 	ValidateConfig ValidateConfigCmd `cmd:"" help:"Validate configuration file"`
 	TestPattern    TestPatternCmd    `cmd:"" help:"Test which patterns match a file"`
+	Cache          CacheCmd          `cmd:"" help:"Manage the response cache"`
 	GitHub         GitHubCmd         `cmd:"" name:"github" help:"GitHub integration commands"`
 	Version        VersionCmd        `cmd:"" help:"Show version"`
 }
@@ -47,6 +113,230 @@ type ReviewCmd struct {
 	DryRun      bool   `short:"d" help:"Show what would be reviewed without calling LLM"`
 	OutputStyle string `short:"s" name:"output-style" help:"Output style: plain (default) or rich (formatted with colors and markdown)" enum:"plain,rich" default:"plain"`
 	One         bool   `short:"1" name:"one" help:"Show only the first suggestion."`
+	Format      string `name:"format" help:"Output format: text (default), json, jsonl, sarif, checkstyle, junit, rdjson or gha. Other formats write only the report to stdout and everything else to stderr." enum:"text,json,jsonl,sarif,checkstyle,junit,rdjson,gha" default:"text"`
+	FailOn      string `name:"fail-on" help:"Exit with code 1 when a finding has at least this severity: critical, warning or any (including suggestions)." enum:"none,critical,warning,any" default:"none"`
+	Triage      bool   `name:"triage" help:"After the review, step through the suggestions in a terminal UI to apply, skip, dismiss or copy each of them."`
+}
+
+// LLMFlags overrides the llm section of the config file.
+// Unset flags keep the configured (or default) values.
+type LLMFlags struct {
+	Provider    string   `help:"LLM provider: openrouter, openai, anthropic, ollama or openai-compatible."`
+	BaseURL     string   `name:"base-url" help:"Override the provider API endpoint."`
+	Model       string   `help:"Model identifier sent to the provider."`
+	Temperature *float64 `placeholder:"FLOAT" help:"Sampling temperature."`
+	MaxTokens   int      `name:"max-tokens" help:"Maximum tokens in the model response."`
+	APIKeyEnv   string   `name:"api-key-env" help:"Environment variable holding the API key."`
+
+	Timeout    time.Duration `help:"Timeout for each LLM request attempt (e.g. 90s)."`
+	MaxRetries *int          `name:"max-retries" placeholder:"N" help:"Retries for rate limits, server and network errors."`
+
+	StructuredOutput string `name:"structured-output" help:"Provider-native structured output: auto, on or off."`
+}
+
+// apply copies the flags that were set onto the LLM configuration.
+func (f LLMFlags) apply(llm *config.LLM) {
+	if f.Provider != "" {
+		llm.Provider = f.Provider
+	}
+	if f.BaseURL != "" {
+		llm.BaseURL = f.BaseURL
+	}
+	if f.Model != "" {
+		llm.Model = f.Model
+	}
+	if f.Temperature != nil {
+		llm.Temperature = *f.Temperature
+	}
+	if f.MaxTokens > 0 {
+		llm.MaxTokens = f.MaxTokens
+	}
+	if f.APIKeyEnv != "" {
+		llm.APIKeyEnv = f.APIKeyEnv
+	}
+	if f.Timeout > 0 {
+		llm.Timeout = f.Timeout
+	}
+	if f.MaxRetries != nil {
+		llm.MaxRetries = *f.MaxRetries
+	}
+	if f.StructuredOutput != "" {
+		llm.StructuredOutput = f.StructuredOutput
+	}
+}
+
+// BudgetFlags overrides the budget section of the config file.
+type BudgetFlags struct {
+	MaxTokens     int     `name:"max-tokens" help:"Token budget for the whole run, input and output."`
+	MaxCost       float64 `name:"max-cost" placeholder:"USD" help:"Cost budget in USD for the whole run."`
+	MaxFileTokens int     `name:"max-file-tokens" help:"Prompt token limit per file; larger files are truncated."`
+}
+
+// apply copies the flags that were set onto the budget configuration.
+func (f BudgetFlags) apply(b *config.Budget) {
+	if f.MaxTokens > 0 {
+		b.MaxTokens = f.MaxTokens
+	}
+	if f.MaxCost > 0 {
+		b.MaxCost = f.MaxCost
+	}
+	if f.MaxFileTokens > 0 {
+		b.MaxFileTokens = f.MaxFileTokens
+	}
+}
+
+// AgentFlags overrides the agent section of the config file.
+type AgentFlags struct {
+	Agent    bool `help:"Let the model call read-only repository tools during reviews."`
+	MaxSteps int  `name:"agent-max-steps" help:"Tool-calling rounds before the model must answer."`
+}
+
+// apply copies the flags that were set onto the agent configuration.
+func (f AgentFlags) apply(a *config.Agent) {
+	if f.Agent {
+		a.Enabled = true
+	}
+	if f.MaxSteps > 0 {
+		a.MaxSteps = f.MaxSteps
+	}
+}
+
+// VerifyFlags overrides the verify section of the config file.
+type VerifyFlags struct {
+	Verify          bool     `help:"Score each suggestion in a second LLM call and drop likely false positives."`
+	VerifyThreshold *float64 `name:"verify-threshold" placeholder:"0-1" help:"Minimum confidence to keep a suggestion."`
+	VerifyModel     string   `name:"verify-model" help:"Model of the verification call (default: the review model)."`
+}
+
+// apply copies the flags that were set onto the verify configuration.
+func (f VerifyFlags) apply(v *config.Verify) {
+	if f.Verify {
+		v.Enabled = true
+	}
+	if f.VerifyThreshold != nil {
+		v.Threshold = *f.VerifyThreshold
+	}
+	if f.VerifyModel != "" {
+		v.Model = f.VerifyModel
+	}
+}
+
+type CacheCmd struct {
+	Stats CacheStatsCmd `cmd:"" help:"Show the size of the response cache."`
+	Clear CacheClearCmd `cmd:"" help:"Remove all cached responses."`
+}
+
+type CacheStatsCmd struct{}
+
+func (cs *CacheStatsCmd) Run() error {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.New(dir).Stats()
+	if err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	fmt.Printf("Cache directory: %s\n", stats.Dir)
+	fmt.Printf("Entries: %d\n", stats.Entries)
+	fmt.Printf("Size: %.1f KiB\n", float64(stats.Bytes)/1024)
+	return nil
+}
+
+type CacheClearCmd struct{}
+
+func (cc *CacheClearCmd) Run() error {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return err
+	}
+
+	removed, err := cache.New(dir).Clear()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed %d cached responses from %s\n", removed, dir)
+	return nil
+}
+
+// newReviewer creates the code reviewer for a run, backed by the response
+// cache unless --no-cache is set or LLM calls are recorded or replayed.
+func newReviewer(cli *CLI, cfg *config.Config) (*agents.CodeReviewer, error) {
+	reviewer, err := agents.NewCodeReviewer(cfg.LLM)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache hits would skip the calls that need recording or replaying
+	mode, err := agents.LLMMode()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cli.NoCache && mode == "" {
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			log.Printf("Response cache disabled: %v", err)
+		} else {
+			reviewer.SetCache(cache.New(dir))
+		}
+	}
+
+	return reviewer, nil
+}
+
+// newRunReviewer creates the reviewer of a run like newReviewer. Without an
+// API key, the rules still check the files they apply to: the LLM reviews are
+// skipped and the returned reviewer is nil.
+func newRunReviewer(
+	cli *CLI, cfg *config.Config, checker *rules.Checker, files []string,
+) (*agents.CodeReviewer, error) {
+	reviewer, err := newReviewer(cli, cfg)
+	if err == nil {
+		return reviewer, nil
+	}
+
+	if errors.Is(err, agents.ErrMissingAPIKey) &&
+		slices.ContainsFunc(files, checker.Applies) {
+		log.Printf("Skipping LLM reviews: %v", err)
+		return nil, nil
+	}
+	return nil, configError(fmt.Errorf("failed to create reviewer: %w", err))
+}
+
+// printRuleFindings prints the report of a file checked by the rules alone.
+func printRuleFindings(
+	findings []agents.Suggestion, filename string, one bool, rich bool,
+) {
+	if one && len(findings) > 0 {
+		findings = findings[:1]
+	}
+	printReport(&agents.ReviewResult{Suggestions: findings}, filename, rich)
+}
+
+// headTools returns the repository tools for reviewing changes up to head
+// in the repository holding dir. They read the tree of head from the top
+// level through their own git client, so tool calls can run alongside the
+// diff reads of the review workers.
+func headTools(dir, head string) (*tools.Toolbox, error) {
+	gitClient, err := git.OpenRepository(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err := gitClient.TreeFS(head)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := gitClient.CommitHash(head)
+	if err != nil {
+		return nil, err
+	}
+
+	return tools.New(fsys, hash), nil
 }
 
 type VersionCmd struct{}
@@ -87,30 +377,46 @@ func (vc *ValidateConfigCmd) Run(cli *CLI) error {
 
 	if err != nil {
 		fmt.Printf("❌ Configuration validation failed: %v\n", err)
-		return err
+		return configError(err)
 	}
 
 	// Validate patterns
 	issues := validatePatterns(cfg.Patterns)
+	if err := prompts.ValidateTemplates(cfg); err != nil {
+		issues = append(issues, strings.Split(err.Error(), "\n")...)
+	}
 
 	if len(issues) == 0 {
 		fmt.Printf("✅ Configuration is valid!\n")
 		fmt.Printf("   - Content strategy: %s\n", cfg.ContentDefaults.Strategy)
 		fmt.Printf("   - Default lines: %d\n", cfg.ContentDefaults.Lines)
+		fmt.Printf("   - LLM provider: %s\n", cfg.LLM.Provider)
+		if cfg.LLM.Model != "" {
+			fmt.Printf("   - LLM model: %s\n", cfg.LLM.Model)
+		}
 		fmt.Printf("   - Patterns defined: %d\n", len(cfg.Patterns))
+		if len(cfg.Rules) > 0 {
+			fmt.Printf("   - Rules defined: %d\n", len(cfg.Rules))
+		}
+		if cfg.Prompts.Review != "" {
+			fmt.Printf("   - Review prompt template: %s\n", cfg.Prompts.Review)
+		}
+		if cfg.Prompts.Diff != "" {
+			fmt.Printf("   - Diff prompt template: %s\n", cfg.Prompts.Diff)
+		}
 	} else {
 		fmt.Printf("⚠️  Configuration has issues:\n")
 		for _, issue := range issues {
 			fmt.Printf("   - %s\n", issue)
 		}
-		return fmt.Errorf("configuration validation failed")
+		return configError(fmt.Errorf("configuration validation failed"))
 	}
 
 	return nil
 }
 
 func (tp *TestPatternCmd) Run(cli *CLI) error {
-	cfg, err := loadConfig(cli.Config, tp.Verbose)
+	cfg, err := loadConfig(cli, tp.Verbose, os.Stdout)
 	if err != nil {
 		return err
 	}
@@ -146,6 +452,14 @@ func (tp *TestPatternCmd) Run(cli *CLI) error {
 
 	fmt.Printf("Diff review guides: %v\n", diffGuides)
 
+	// Get the model selected by the matched patterns
+	selection, err := res.GetModel(tp.File)
+	if err != nil {
+		return fmt.Errorf("failed to get model: %w", err)
+	}
+
+	fmt.Printf("Model: %s\n", describeModel(cfg, selection))
+
 	if tp.Verbose {
 		fmt.Printf("\nDetailed pattern matching:\n")
 		// We need to expose pattern matching details - let's add this functionality
@@ -155,8 +469,589 @@ func (tp *TestPatternCmd) Run(cli *CLI) error {
 	return nil
 }
 
+// describeModel returns a human-readable description of the model used for a file.
+func describeModel(cfg *config.Config, selection resolver.ModelSelection) string {
+	var description string
+	if selection.Model != "" {
+		description = fmt.Sprintf(
+			"%s (from pattern %s)", selection.Model, selection.Pattern,
+		)
+	} else if cfg.LLM.Model != "" {
+		description = fmt.Sprintf("%s (llm default)", cfg.LLM.Model)
+	} else {
+		description = fmt.Sprintf("%s provider default", cfg.LLM.Provider)
+	}
+
+	if selection.Temperature != nil {
+		description += fmt.Sprintf(", temperature %g", *selection.Temperature)
+	}
+
+	ensemble, source := selection.Ensemble, "ensemble default"
+	if selection.EnsemblePattern != "" {
+		source = "from pattern " + selection.EnsemblePattern
+	}
+	if len(ensemble) > 0 {
+		minAgreement := cfg.Ensemble.MinAgreement
+		if selection.MinAgreement > 0 {
+			minAgreement = selection.MinAgreement
+		}
+		// The ensemble replaces the single model
+		description = fmt.Sprintf(
+			"ensemble of %s (%s), min agreement %d",
+			strings.Join(ensemble, ", "), source,
+			min(max(minAgreement, 1), len(ensemble)),
+		)
+		if selection.Temperature != nil {
+			description += fmt.Sprintf(", temperature %g", *selection.Temperature)
+		}
+	}
+
+	return description
+}
+
+// printGuideBudget reports the guides cut to their headings or dropped to
+// fit guides.max_tokens.
+func printGuideBudget(w io.Writer, res *resolver.Resolver, guides []string) {
+	if len(guides) == 0 {
+		return
+	}
+	set, err := res.AssembleGuides(guides)
+	if err != nil {
+		return
+	}
+	if summarized := set.Summarized(); len(summarized) > 0 {
+		fmt.Fprintf(w, "Guides cut to their headings to fit the guide budget: %v\n", summarized)
+	}
+	for _, guide := range set.Dropped {
+		fmt.Fprintf(
+			w, "Guide %s (priority %d) dropped to fit the guide budget\n",
+			guide.Name, guide.Priority,
+		)
+	}
+}
+
+// usageTotals aggregates token usage and cost over the files of a run.
+type usageTotals struct {
+	Files        int
+	Tokens       int
+	InputTokens  int
+	OutputTokens int
+	CachedTokens int // Input tokens read from the provider's prompt cache
+	Cost         float64
+	Cached       int      // Files served from the response cache
+	ToolCalls    int      // Repository tool calls made by the model
+	Dropped      int      // Suggestions dropped by the verification pass
+	Unpriced     []string // Models without a price, excluded from Cost
+}
+
+// add adds the usage of a single review to the totals.
+func (u *usageTotals) add(result *agents.ReviewResult) {
+	u.Files++
+	u.Tokens += result.TokensUsed
+	u.InputTokens += result.InputTokens
+	u.OutputTokens += result.OutputTokens
+	u.CachedTokens += result.CachedTokens
+	u.Cost += result.Cost
+	u.ToolCalls += result.ToolCalls
+	u.Dropped += len(result.Dropped)
+	if result.Cached {
+		u.Cached++
+	}
+
+	if !result.Priced && result.TokensUsed > 0 &&
+		!slices.Contains(u.Unpriced, result.Model) {
+		u.Unpriced = append(u.Unpriced, result.Model)
+	}
+}
+
+// costString formats the total cost, noting models that have no price.
+func (u *usageTotals) costString() string {
+	cost := fmt.Sprintf("$%.4f", u.Cost)
+	if len(u.Unpriced) > 0 {
+		cost += fmt.Sprintf(
+			" (excludes unpriced models: %s)", strings.Join(u.Unpriced, ", "),
+		)
+	}
+	return cost
+}
+
+// print writes the token usage and cost summary.
+func (u *usageTotals) print() {
+	fmt.Printf(
+		"Tokens used: %d (input: %d, output: %d)\n",
+		u.Tokens, u.InputTokens, u.OutputTokens,
+	)
+	if u.CachedTokens > 0 {
+		fmt.Printf("Prompt cache: %d input tokens\n", u.CachedTokens)
+	}
+	fmt.Printf("Cost: %s\n", u.costString())
+	if u.Cached > 0 {
+		fmt.Printf("Cached responses: %d of %d files\n", u.Cached, u.Files)
+	}
+	if u.ToolCalls > 0 {
+		fmt.Printf("Tool calls: %d\n", u.ToolCalls)
+	}
+	if u.Dropped > 0 {
+		fmt.Printf("Dropped by verification: %d suggestions\n", u.Dropped)
+	}
+}
+
+// runGate decides the exit code of a run from its findings and the files
+// that failed to be reviewed.
+type runGate struct {
+	failOn   string // Lowest severity that fails the run, or failOnNone
+	one      bool   // Count only the first finding of a file, as --one shows
+	findings int    // Findings at or above failOn
+	failed   int    // Files that failed to be reviewed
+}
+
+// add counts the findings of a file at or above the fail-on severity.
+func (g *runGate) add(suggestions []agents.Suggestion) {
+	if g.one && len(suggestions) > 0 {
+		suggestions = suggestions[:1]
+	}
+	for _, suggestion := range suggestions {
+		if g.fails(suggestion.Severity) {
+			g.findings++
+		}
+	}
+}
+
+// fails reports whether a finding of severity fails the run. Findings of
+// unknown severity count as warnings.
+func (g *runGate) fails(severity agents.Severity) bool {
+	switch g.failOn {
+	case failOnAny:
+		return true
+	case failOnWarning:
+		return severity != agents.SeveritySuggestion
+	case failOnCritical:
+		return severity == agents.SeverityCritical
+	}
+	return false
+}
+
+// err returns the error exiting with the code of the run, nil when it
+// passed. Findings take precedence over failed files.
+func (g *runGate) err() error {
+	if g.findings > 0 {
+		return &exitError{
+			code: exitCodeFindings,
+			err:  fmt.Errorf("%d findings fail --fail-on %s", g.findings, g.failOn),
+		}
+	}
+	if g.failed > 0 {
+		return &exitError{
+			code: exitCodeFailedFiles,
+			err:  fmt.Errorf("%d files failed to be reviewed", g.failed),
+		}
+	}
+	return nil
+}
+
+// checkTriage returns an error when --triage can't run: the triage UI needs
+// a terminal, and the text report.
+func checkTriage(enabled bool, format string) error {
+	if !enabled {
+		return nil
+	}
+	if report.Machine(format) {
+		return configError(fmt.Errorf("--triage can't be used with --format %s", format))
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return configError(fmt.Errorf("--triage needs a terminal"))
+	}
+	return nil
+}
+
+// runTriage steps through the suggestions of a run in the triage UI, then
+// prints a summary of the decisions.
+func runTriage(items []triage.Item, rich bool) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	formatter := diff.NewFormatter()
+	decisions, err := triage.Run(
+		items, triage.Options{
+			Render: func(item triage.Item) string {
+				markdown := formatSuggestionMarkdown(formatter, item.Suggestion)
+				if rich {
+					if rendered, err := renderRichOutput(markdown); err == nil {
+						return rendered
+					}
+				}
+				return markdown
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	triage.WriteSummary(os.Stdout, decisions)
+	return nil
+}
+
+// triageItems returns the suggestions of the file at path, relative to root,
+// to triage.
+func triageItems(
+	root string, path string, suggestions []agents.Suggestion,
+) []triage.Item {
+	items := make([]triage.Item, len(suggestions))
+	for i, suggestion := range suggestions {
+		items[i] = triage.Item{Path: path, Root: root, Suggestion: suggestion}
+	}
+	return items
+}
+
+// fitCodeToBudget estimates a file review and truncates or skips the file to
+// stay within the budget, recording adjustments under label. It returns the
+// code to review and the reservation to settle once the review completes, or
+// false if the file was skipped.
+func fitCodeToBudget(
+	reviewer *agents.CodeReviewer, cfg *config.Config, bud *budget.Budget,
+	filename string, label string, code string,
+) (string, budget.Reservation, bool, error) {
+	if !bud.Enabled() {
+		return code, budget.Reservation{}, true, nil
+	}
+
+	est, err := reviewer.EstimateReview(cfg, code, filename)
+	if err != nil {
+		return "", budget.Reservation{}, false, err
+	}
+
+	var truncation string
+	if limit := bud.Limits().MaxFileTokens; limit > 0 && est.InputTokens > limit {
+		fitted, err := budget.FitLines(
+			code, limit, func(candidate string) (int, error) {
+				e, err := reviewer.EstimateReview(cfg, candidate, filename)
+				return e.InputTokens, err
+			},
+		)
+		if errors.Is(err, budget.ErrTooLarge) {
+			bud.Record(
+				label, budget.ActionSkipped,
+				perFileLimitReason(est.InputTokens, limit),
+			)
+			return "", budget.Reservation{}, false, nil
+		}
+		if err != nil {
+			return "", budget.Reservation{}, false, err
+		}
+
+		truncation = fmt.Sprintf(
+			"reviewed the first %d of %d lines to fit the %d token per-file limit",
+			strings.Count(fitted, "\n"), strings.Count(code, "\n")+1, limit,
+		)
+		code = fitted
+		if est, err = reviewer.EstimateReview(cfg, code, filename); err != nil {
+			return "", budget.Reservation{}, false, err
+		}
+	}
+
+	reservation, err := reserveEstimate(bud, label, est)
+	if err != nil {
+		return "", budget.Reservation{}, false, nil
+	}
+	if truncation != "" {
+		bud.Record(label, budget.ActionTruncated, truncation)
+	}
+
+	return code, reservation, true, nil
+}
+
+// chunkNote tells the model that the code of a chunk isn't the whole file.
+const chunkNote = "\n[... part %d of %d of the file; the other parts are reviewed separately, so declarations and imports may be defined there]"
+
+// reviewChunks reviews the chunks of a file in order, applying the budget
+// to each, and merges their reviews. Suggestions are streamed to printer,
+// if any, with their lines mapped to the file. It returns nil if the budget
+// skipped every chunk.
+func reviewChunks(
+	ctx context.Context, reviewer *agents.CodeReviewer, cfg *config.Config,
+	bud *budget.Budget, filename string, chunks []chunk.Chunk,
+	printer *streamPrinter,
+) (*agents.ReviewResult, error) {
+	results := make([]*agents.ReviewResult, len(chunks))
+	for i, c := range chunks {
+		label, code := filename, c.Code
+		if len(chunks) > 1 {
+			label = fmt.Sprintf("%s (lines %d-%d)", filename, c.Start, c.End)
+			// After the code, so the line numbers of the prompt stay those of
+			// the chunk
+			code += fmt.Sprintf(chunkNote, i+1, len(chunks))
+		}
+
+		// Estimate the review and apply the budget before calling the LLM
+		code, reservation, ok, err := fitCodeToBudget(
+			reviewer, cfg, bud, filename, label, code,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate review: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		var stream *agents.Stream
+		if printer != nil {
+			stream = &agents.Stream{
+				Suggestion: func(suggestion agents.Suggestion) {
+					printer.print(c.Remap(suggestion))
+				},
+				Restart: printer.restart,
+			}
+		}
+
+		result, err := reviewer.ReviewStream(ctx, cfg, code, filename, stream)
+		settleBudget(bud, reservation, result)
+		if err != nil {
+			if len(chunks) > 1 {
+				return nil, fmt.Errorf("review of %s failed: %w", label, err)
+			}
+			return nil, fmt.Errorf("review failed: %w", err)
+		}
+		results[i] = result
+	}
+
+	return chunk.Merge(chunks, results), nil
+}
+
+// fitDiffToBudget estimates a diff review and drops trailing hunks or skips
+// the file to stay within the budget. It returns the diff to review and the
+// reservation to settle once the review completes, or false if skipped.
+func fitDiffToBudget(
+	reviewer *agents.CodeReviewer, cfg *config.Config, bud *budget.Budget,
+	file string, diffData *git.DiffData,
+) (*git.DiffData, budget.Reservation, bool, error) {
+	if !bud.Enabled() {
+		return diffData, budget.Reservation{}, true, nil
+	}
+
+	est, err := reviewer.EstimateDiff(cfg, diffData, file)
+	if err != nil {
+		return nil, budget.Reservation{}, false, err
+	}
+
+	var truncation string
+	if limit := bud.Limits().MaxFileTokens; limit > 0 && est.InputTokens > limit {
+		fitted, err := budget.FitDiff(
+			diffData, limit, func(candidate *git.DiffData) (int, error) {
+				e, err := reviewer.EstimateDiff(cfg, candidate, file)
+				return e.InputTokens, err
+			},
+		)
+		if errors.Is(err, budget.ErrTooLarge) {
+			bud.Record(
+				file, budget.ActionSkipped,
+				perFileLimitReason(est.InputTokens, limit),
+			)
+			return nil, budget.Reservation{}, false, nil
+		}
+		if err != nil {
+			return nil, budget.Reservation{}, false, err
+		}
+
+		truncation = fmt.Sprintf(
+			"reviewed the first %d of %d hunks to fit the %d token per-file limit",
+			len(fitted.Hunks), len(diffData.Hunks), limit,
+		)
+		diffData = fitted
+		if est, err = reviewer.EstimateDiff(cfg, diffData, file); err != nil {
+			return nil, budget.Reservation{}, false, err
+		}
+	}
+
+	reservation, err := reserveEstimate(bud, file, est)
+	if err != nil {
+		return nil, budget.Reservation{}, false, nil
+	}
+	if truncation != "" {
+		bud.Record(file, budget.ActionTruncated, truncation)
+	}
+
+	return diffData, reservation, true, nil
+}
+
+// reserveEstimate claims the estimated usage of a review from the budget.
+// Reviews served from the response cache use no tokens.
+func reserveEstimate(
+	bud *budget.Budget, file string, est agents.Estimate,
+) (budget.Reservation, error) {
+	if est.Cached {
+		return bud.Reserve(file, 0, 0)
+	}
+	return bud.Reserve(file, est.Tokens(), est.Cost)
+}
+
+// perFileLimitReason explains why a file was skipped by the per-file limit.
+func perFileLimitReason(tokens, limit int) string {
+	return fmt.Sprintf(
+		"prompt needs ~%d tokens and can't be truncated to the %d token per-file limit",
+		tokens, limit,
+	)
+}
+
+// settleBudget replaces a reservation with the actual usage of a review,
+// or releases it if the review failed.
+func settleBudget(
+	bud *budget.Budget, reservation budget.Reservation,
+	result *agents.ReviewResult,
+) {
+	if result == nil {
+		bud.Release(reservation)
+		return
+	}
+	bud.Commit(reservation, result.TokensUsed, result.Cost)
+}
+
+// printBudgetAdjustments lists the files that were skipped or truncated.
+func printBudgetAdjustments(w io.Writer, bud *budget.Budget) {
+	adjustments := bud.Adjustments()
+	if len(adjustments) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nBudget adjustments:\n")
+	for _, a := range adjustments {
+		fmt.Fprintf(w, "  - %s %s: %s\n", a.File, a.Action, a.Reason)
+	}
+}
+
+// newEmitter returns the emitter of format writing to stdout. In GitHub
+// Actions, the gha format also appends the run summary to the job summary
+// file, which the returned function closes.
+func newEmitter(format string) (report.Emitter, func(), error) {
+	opts := report.Options{Version: version}
+	closeEmitter := func() {}
+
+	if path := os.Getenv("GITHUB_STEP_SUMMARY"); path != "" &&
+		format == report.FormatGHA {
+		file, err := os.OpenFile(
+			path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open job summary: %w", err)
+		}
+		opts.StepSummary = file
+		closeEmitter = func() { file.Close() }
+	}
+
+	emitter, err := report.New(format, os.Stdout, opts)
+	if err != nil {
+		closeEmitter()
+		return nil, nil, err
+	}
+	return emitter, closeEmitter, nil
+}
+
+// emitFiles writes the reviewed files and the summary of the run to stdout
+// in a machine-readable format.
+func emitFiles(
+	format string, files []report.File, bud *budget.Budget, start time.Time,
+) error {
+	emitter, closeEmitter, err := newEmitter(format)
+	if err != nil {
+		return err
+	}
+	defer closeEmitter()
+
+	var summary report.Summary
+	for _, file := range files {
+		if err := emitter.File(file); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		summary.Add(file)
+	}
+	return closeReport(emitter, summary, bud, start)
+}
+
+// reportPath returns path relative to the working directory, with forward
+// slashes, as reports locate files. Paths outside of it are kept.
+func reportPath(path string) string {
+	if filepath.IsAbs(path) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, path); err == nil &&
+				!strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// repoPath returns path relative to the root of its repository, with forward
+// slashes, as diffs name files and patterns and rules match them. Outside a
+// repository it is the reportPath.
+func repoPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return reportPath(path)
+	}
+	gitClient, err := git.OpenRepository(filepath.Dir(abs))
+	if err != nil {
+		return reportPath(path)
+	}
+	root, err := gitClient.Root()
+	if err != nil {
+		return reportPath(path)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return reportPath(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// closeReport completes the summary of a machine-readable report with the
+// budget adjustments and duration of the run, and writes it.
+func closeReport(
+	emitter report.Emitter, summary report.Summary, bud *budget.Budget,
+	start time.Time,
+) error {
+	if bud != nil {
+		for _, a := range bud.Adjustments() {
+			summary.Adjustments = append(
+				summary.Adjustments, report.Adjustment{
+					File:   a.File,
+					Action: a.Action,
+					Reason: a.Reason,
+				},
+			)
+		}
+	}
+	summary.DurationMS = time.Since(start).Milliseconds()
+
+	if err := emitter.Close(summary); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// budgetMarkdown lists the files that were skipped or truncated for a PR comment.
+func budgetMarkdown(bud *budget.Budget) string {
+	adjustments := bud.Adjustments()
+	if len(adjustments) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n\n### ⚠️ Budget adjustments\n\n")
+	for _, a := range adjustments {
+		builder.WriteString(
+			fmt.Sprintf("- `%s` %s: %s\n", a.File, a.Action, a.Reason),
+		)
+	}
+	return builder.String()
+}
+
 func buildSuggestionBody(suggestion agents.Suggestion) string {
 	var bodyBuilder strings.Builder
+	if meta := suggestionMeta(suggestion); meta != "" {
+		bodyBuilder.WriteString(meta + "\n\n")
+	}
 	bodyBuilder.WriteString(strings.ReplaceAll(suggestion.Body, "\\n", "\n"))
 
 	if suggestion.Original != "" || suggestion.Suggestion != "" {
@@ -195,120 +1090,486 @@ func formatSuggestionsToMarkdown(
 
 	formatter := diff.NewFormatter()
 	for _, suggestion := range suggestions {
-		fullBody := buildSuggestionBody(suggestion)
-		// Format the body to render diffs correctly
-		formattedBody := formatter.Format(fullBody)
-		builder.WriteString(
-			fmt.Sprintf(
-				"## %s\n%s\n\n", suggestion.Title, formattedBody,
+		builder.WriteString(formatSuggestionMarkdown(formatter, suggestion))
+	}
+
+	return builder.String()
+}
+
+// formatSuggestionMarkdown formats a single suggestion as a report section.
+func formatSuggestionMarkdown(
+	formatter *diff.Formatter, suggestion agents.Suggestion,
+) string {
+	fullBody := buildSuggestionBody(suggestion)
+	// Format the body to render diffs correctly
+	formattedBody := formatter.Format(fullBody)
+	return fmt.Sprintf("## %s\n%s\n\n", suggestionTitle(suggestion), formattedBody)
+}
+
+// printDropped lists the suggestions dropped by the verification pass.
+func printDropped(w io.Writer, result *agents.ReviewResult) {
+	if len(result.Dropped) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Dropped by verification:")
+	for _, suggestion := range result.Dropped {
+		fmt.Fprintf(
+			w, "  - %s (%.0f%% confidence)\n", suggestion.Title,
+			suggestion.Confidence*100,
+		)
+	}
+}
+
+// suggestionTitle returns the title of a suggestion followed by how many
+// ensemble models reported it and its verification score, if known.
+func suggestionTitle(suggestion agents.Suggestion) string {
+	var notes []string
+	if suggestion.Ensemble > 0 {
+		notes = append(
+			notes, fmt.Sprintf(
+				"%d of %d models", len(suggestion.ReportedBy),
+				suggestion.Ensemble,
 			),
 		)
 	}
+	if suggestion.Confidence > 0 {
+		notes = append(
+			notes, fmt.Sprintf("%.0f%% confidence", suggestion.Confidence*100),
+		)
+	}
 
-	return builder.String()
+	if len(notes) == 0 {
+		return suggestion.Title
+	}
+	return fmt.Sprintf("%s (%s)", suggestion.Title, strings.Join(notes, ", "))
+}
+
+// suggestionMeta returns the location, severity, category and guide of a
+// suggestion as a single line, or an empty string when none are known.
+func suggestionMeta(suggestion agents.Suggestion) string {
+	var parts []string
+
+	location := suggestion.File
+	switch {
+	case suggestion.StartLine == 0:
+	case suggestion.EndLine > suggestion.StartLine:
+		location += fmt.Sprintf(":%d-%d", suggestion.StartLine, suggestion.EndLine)
+	default:
+		location += fmt.Sprintf(":%d", suggestion.StartLine)
+	}
+	if location != "" {
+		parts = append(parts, fmt.Sprintf("📍 `%s`", location))
+	}
+
+	if suggestion.Severity != "" {
+		parts = append(parts, string(suggestion.Severity))
+	}
+	if suggestion.Category != "" {
+		parts = append(parts, string(suggestion.Category))
+	}
+	if suggestion.Guide != "" {
+		parts = append(parts, "guide: "+suggestion.Guide)
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// printReport prints the review of a file once the response is complete.
+func printReport(result *agents.ReviewResult, filename string, rich bool) {
+	if result.Truncated {
+		fmt.Println(truncatedWarning)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Printf("⚠️ %s\n", warning)
+	}
+
+	markdownReport := formatSuggestionsToMarkdown(result.Suggestions, filename)
+
+	// Apply glamour rendering if requested
+	if rich && len(result.Suggestions) > 0 {
+		rendered, err := renderRichOutput(markdownReport)
+		if err != nil {
+			log.Printf("Failed to initialize rich renderer: %v", err)
+			fmt.Println(markdownReport) // Fallback to plain
+		} else {
+			fmt.Print(rendered)
+		}
+	} else {
+		fmt.Println(markdownReport)
+	}
+}
+
+// streamPrinter prints suggestions as they stream in, pausing the spinner
+// while it writes.
+type streamPrinter struct {
+	filename  string
+	file      string // Location of streamed suggestions, if not their own
+	rich      bool
+	limit     int // Maximum suggestions to print, 0 for all
+	spinner   *spinner.Spinner
+	formatter *diff.Formatter
+	shown     []agents.Suggestion
+}
+
+// newStreamPrinter returns a printer for the review of filename, or nil when
+// stdout isn't a terminal and the report should be printed all at once.
+func newStreamPrinter(
+	filename string, rich bool, one bool, s *spinner.Spinner,
+) *streamPrinter {
+	if !isatty.IsTerminal(os.Stdout.Fd()) {
+		return nil
+	}
+
+	p := &streamPrinter{
+		filename:  filename,
+		rich:      rich,
+		spinner:   s,
+		formatter: diff.NewFormatter(),
+	}
+	if one {
+		p.limit = 1
+	}
+	return p
 }
 
-func (r *ReviewCmd) Run(cli *CLI) error {
+// stream returns the stream of suggestions to the printer, or nil to
+// disable streaming.
+func (p *streamPrinter) stream() *agents.Stream {
+	if p == nil {
+		return nil
+	}
+	return &agents.Stream{Suggestion: p.print, Restart: p.restart}
+}
+
+// print writes a suggestion, preceded by the report header for the first one.
+func (p *streamPrinter) print(suggestion agents.Suggestion) {
+	if p.limit > 0 && len(p.shown) >= p.limit {
+		return
+	}
+
+	if p.file != "" {
+		suggestion.File = p.file
+	}
+
+	var markdown string
+	if len(p.shown) == 0 {
+		markdown = fmt.Sprintf("# 🍲 miso Code review for %s\n\n", p.filename)
+	}
+	markdown += formatSuggestionMarkdown(p.formatter, suggestion)
+	p.shown = append(p.shown, suggestion)
+
+	active := p.spinner.Active()
+	p.spinner.Stop()
+	if p.rich {
+		rendered, err := renderRichOutput(markdown)
+		if err != nil {
+			fmt.Print(markdown) // Fallback to plain
+		} else {
+			fmt.Print(rendered)
+		}
+	} else {
+		fmt.Print(markdown)
+	}
+	if active {
+		p.spinner.Start()
+	}
+}
+
+// restart marks the suggestions printed so far as superseded, so finish
+// prints the final result in full below them.
+func (p *streamPrinter) restart() {
+	if len(p.shown) == 0 {
+		return
+	}
+
+	active := p.spinner.Active()
+	p.spinner.Stop()
+	fmt.Println(supersededWarning)
+	if active {
+		p.spinner.Start()
+	}
+	p.shown = nil
+}
+
+// finish prints the suggestions of the final result that weren't streamed,
+// such as those recovered by a repair call.
+func (p *streamPrinter) finish(result *agents.ReviewResult) {
+	for _, suggestion := range result.Suggestions {
+		if !slices.ContainsFunc(
+			p.shown, func(shown agents.Suggestion) bool {
+				return shown.ID == suggestion.ID && shown.Title == suggestion.Title
+			},
+		) {
+			p.print(suggestion)
+		}
+	}
+
+	if len(p.shown) == 0 {
+		fmt.Println("✅ No issues found.")
+	}
+	if result.Truncated {
+		fmt.Println(truncatedWarning)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Printf("⚠️ %s\n", warning)
+	}
+}
+
+func (r *ReviewCmd) Run(ctx context.Context, cli *CLI) error {
+	// Machine-readable output owns stdout, so everything else goes to stderr
+	out := os.Stdout
+	machine := report.Machine(r.Format)
+	if machine {
+		out = os.Stderr
+	}
+	start := time.Now()
+	if err := checkTriage(r.Triage, r.Format); err != nil {
+		return err
+	}
+
 	// Load configuration
-	cfg, err := loadConfig(cli.Config, r.Verbose)
+	cfg, err := loadConfig(cli, r.Verbose, out)
 	if err != nil {
 		return err
 	}
 
+	checker, err := rules.New(cfg.Rules)
+	if err != nil {
+		return configError(err)
+	}
+
+	// Patterns and rules match the path in the repository, like in diffs;
+	// the file name is only displayed
+	path := repoPath(r.File)
+	filename := filepath.Base(r.File)
+
 	// Check if file should be reviewed
 	res := resolver.NewResolver(cfg)
-	if !res.ShouldReview(r.File) {
-		fmt.Printf("File %s does not match any review patterns.\n", r.File)
+	llmReview := res.ShouldReview(path)
+	if !llmReview && !checker.Applies(path) {
+		fmt.Fprintf(out, "File %s does not match any review patterns.\n", r.File)
+		if machine {
+			return emitFiles(r.Format, nil, nil, start)
+		}
 		return nil
 	}
 
 	// Get guides for this file
-	guides, err := res.GetGuides(r.File)
-	if err != nil {
-		return fmt.Errorf("failed to get guides: %w", err)
+	var guides []string
+	if llmReview {
+		guides, err = res.GetGuides(path)
+		if err != nil {
+			return fmt.Errorf("failed to get guides: %w", err)
+		}
 	}
 
 	if r.Verbose {
-		fmt.Printf("Reviewing file: %s\n", r.File)
-		fmt.Printf("Using guides: %v\n", guides)
+		fmt.Fprintf(out, "Reviewing file: %s\n", r.File)
+		fmt.Fprintf(out, "Using guides: %v\n", guides)
+		printGuideBudget(out, res, guides)
+		if selection, err := res.GetModel(path); err == nil && llmReview {
+			fmt.Fprintf(out, "Using model: %s\n", describeModel(cfg, selection))
+		}
+	}
+
+	// Read file contents
+	content, err := os.ReadFile(r.File)
+	if err != nil {
+		return fmt.Errorf("failed to read file %q: %w", r.File, err)
+	}
+
+	// Rules don't call the LLM, so they run in every mode
+	findings := checker.CheckFile(path, string(content))
+	for i := range findings {
+		findings[i].File = reportPath(r.File)
+	}
+	rich := r.OutputStyle == "rich"
+
+	gate := runGate{failOn: r.FailOn, one: r.One}
+
+	// finish triages the suggestions of the text report, if requested
+	finish := func(suggestions []agents.Suggestion) error {
+		if r.One && len(suggestions) > 0 {
+			suggestions = suggestions[:1]
+		}
+		if r.Triage {
+			items := triageItems("", reportPath(r.File), suggestions)
+			if err := runTriage(items, rich); err != nil {
+				return err
+			}
+		}
+		return gate.err()
+	}
+
+	// emitRules reports the file as checked by the rules alone
+	emitRules := func(bud *budget.Budget, skipped bool) error {
+		gate.add(findings)
+		if !machine {
+			printRuleFindings(findings, filename, r.One, rich)
+			return finish(findings)
+		}
+		file := report.NewFile(
+			reportPath(r.File), &agents.ReviewResult{Suggestions: findings}, nil,
+			time.Since(start),
+		)
+		if r.One && len(file.Suggestions) > 0 {
+			file.Suggestions = file.Suggestions[:1]
+		}
+		file.Guides = guides
+		file.Skipped = skipped
+		if err := emitFiles(r.Format, []report.File{file}, bud, start); err != nil {
+			return err
+		}
+		return gate.err()
 	}
 
-	// Dry run mode
+	// Dry run mode; rule findings still fail the run like in diff --dry-run,
+	// and machine-readable formats report them
 	if r.DryRun {
-		fmt.Printf("=== DRY RUN MODE ===\n")
-		fmt.Printf("File: %s\n", r.File)
-		fmt.Printf("Would use guides: %v\n", guides)
-		fmt.Printf("Review would be performed with these settings.\n")
-		return nil
+		fmt.Fprintf(out, "=== DRY RUN MODE ===\n")
+		fmt.Fprintf(out, "File: %s\n", r.File)
+		fmt.Fprintf(out, "Would use guides: %v\n", guides)
+		fmt.Fprintf(out, "Review would be performed with these settings.\n")
+		if machine {
+			return emitRules(nil, false)
+		}
+		if checker.Applies(path) {
+			fmt.Fprintf(out, "Rule findings:\n")
+			printRuleFindings(findings, filename, r.One, rich)
+		}
+		gate.add(findings)
+		return gate.err()
 	}
 
-	// Read file contents
-	content, err := os.ReadFile(r.File)
-	if err != nil {
-		return fmt.Errorf("failed to read file %q: %w", r.File, err)
+	if !llmReview {
+		return emitRules(nil, false)
 	}
 
 	// Initialize reviewer
-	reviewer, err := agents.NewCodeReviewer()
+	reviewer, err := newRunReviewer(cli, cfg, checker, []string{path})
 	if err != nil {
-		return fmt.Errorf("failed to create reviewer: %w", err)
+		return err
+	}
+	if reviewer == nil {
+		return emitRules(nil, false)
+	}
+	if cfg.Agent.Enabled {
+		// Tools read the repository at HEAD from its top level, like in
+		// diffs. Files outside a repository fall back to their directory,
+		// which isn't pinned to a revision, so those reviews aren't cached.
+		toolbox, err := headTools(filepath.Dir(r.File), "HEAD")
+		if err != nil {
+			toolbox = tools.New(os.DirFS(filepath.Dir(r.File)), "")
+		}
+		reviewer.SetTools(toolbox, cfg.Agent.MaxSteps)
 	}
 
-	// Get just the filename for the review
-	filename := filepath.Base(r.File)
+	// Files too long for one prompt are reviewed in chunks
+	chunks := chunk.Split(
+		r.File, string(content), cfg.Chunking.MaxLines, cfg.Chunking.Overlap,
+	)
+	if r.Verbose && len(chunks) > 1 {
+		fmt.Fprintf(out, "Reviewing in %d chunks\n", len(chunks))
+	}
 
 	// Create and start spinner
-	s := spinner.New(spinner.CharSets[spinnerCharSet], spinnerRefreshRate)
+	s := spinner.New(
+		spinner.CharSets[spinnerCharSet], spinnerRefreshRate,
+		spinner.WithWriterFile(out),
+	)
 	s.Suffix = " " + r.Message
 	s.Start()
 
-	// Perform review
-	result, err := reviewer.Review(cfg, string(content), filename)
+	// Perform review, printing suggestions as they arrive on a terminal
+	var printer *streamPrinter
+	if !machine {
+		printer = newStreamPrinter(filename, rich, r.One, s)
+	}
+	if printer != nil {
+		printer.file = reportPath(r.File)
+		for _, finding := range findings {
+			printer.print(finding)
+		}
+	}
+	bud := budget.New(cfg.Budget)
+	result, err := reviewChunks(ctx, reviewer, cfg, bud, path, chunks, printer)
 
 	// Stop spinner
 	s.Stop()
 
 	if err != nil {
-		return fmt.Errorf("review failed: %w", err)
+		if ctx.Err() != nil {
+			return err
+		}
+		return &exitError{code: exitCodeFailedFiles, err: err}
+	}
+	// Skipped by the budget
+	if result == nil {
+		if machine {
+			return emitRules(bud, true)
+		}
+		gate.add(findings)
+		if checker.Applies(path) {
+			printRuleFindings(findings, filename, r.One, rich)
+		}
+		printBudgetAdjustments(out, bud)
+		return finish(findings)
 	}
+	// Suggestions carry the path the file was given by, like in reports
+	for i := range result.Suggestions {
+		result.Suggestions[i].File = reportPath(r.File)
+	}
+	for i := range result.Dropped {
+		result.Dropped[i].File = reportPath(r.File)
+	}
+	result.Suggestions = slices.Concat(findings, result.Suggestions)
+	gate.add(result.Suggestions)
 
 	if r.One && len(result.Suggestions) > 0 {
 		result.Suggestions = result.Suggestions[:1]
 	}
 
-	markdownReport := formatSuggestionsToMarkdown(result.Suggestions, filename)
-
-	// Apply glamour rendering if requested
-	if r.OutputStyle == "rich" && len(result.Suggestions) > 0 {
-		rendered, err := renderRichOutput(markdownReport)
-		if err != nil {
-			log.Printf("Failed to initialize rich renderer: %v", err)
-			fmt.Println(markdownReport) // Fallback to plain
-		} else {
-			fmt.Print(rendered)
+	if machine {
+		if r.Verbose {
+			printDropped(out, result)
+		}
+		file := report.NewFile(reportPath(r.File), result, nil, time.Since(start))
+		file.Guides = guides
+		if err := emitFiles(r.Format, []report.File{file}, bud, start); err != nil {
+			return err
 		}
+		return gate.err()
+	}
+
+	if printer != nil {
+		printer.finish(result)
 	} else {
-		fmt.Println(markdownReport)
+		printReport(result, filename, rich)
+	}
+	if r.Verbose {
+		printDropped(out, result)
 	}
 
-	// Display token usage if available
-	if result.TokensUsed > 0 {
+	printBudgetAdjustments(out, bud)
+
+	// Display token usage and cost if available
+	var totals usageTotals
+	totals.add(result)
+	if totals.Tokens > 0 || totals.Cached > 0 {
 		fmt.Printf("\n---\n")
-		fmt.Printf(
-			"Tokens used: %d (input: %d, output: %d)\n",
-			result.TokensUsed, result.InputTokens, result.OutputTokens,
-		)
+		totals.print()
 	}
 
 	// Debug info at the very end
 	if os.Getenv("DEBUG") == "true" {
-		fmt.Printf("\n[DEBUG] Token extraction details:\n")
-		fmt.Printf("  Total tokens: %d\n", result.TokensUsed)
-		fmt.Printf("  Input tokens: %d\n", result.InputTokens)
-		fmt.Printf("  Output tokens: %d\n", result.OutputTokens)
+		fmt.Fprintf(out, "\n[DEBUG] Token extraction details:\n")
+		fmt.Fprintf(out, "  Total tokens: %d\n", result.TokensUsed)
+		fmt.Fprintf(out, "  Input tokens: %d\n", result.InputTokens)
+		fmt.Fprintf(out, "  Output tokens: %d\n", result.OutputTokens)
 	}
 
-	return nil
+	return finish(result.Suggestions)
 }
 
 type DiffCmd struct {
@@ -319,6 +1580,10 @@ type DiffCmd struct {
 	DryRun      bool   `short:"d" help:"Show what would be reviewed without calling LLM"`
 	One         bool   `short:"1" name:"one" help:"Show only the first suggestion per file."`
 	OutputStyle string `short:"s" name:"output-style" help:"Output style: plain (default) or rich (formatted with colors and markdown)" enum:"plain,rich" default:"plain"`
+	Jobs        int    `short:"j" help:"Number of files to review concurrently." default:"1"`
+	Format      string `name:"format" help:"Output format: text (default), json, jsonl, sarif, checkstyle, junit, rdjson or gha. Other formats write only the report to stdout and everything else to stderr." enum:"text,json,jsonl,sarif,checkstyle,junit,rdjson,gha" default:"text"`
+	FailOn      string `name:"fail-on" help:"Exit with code 1 when a finding has at least this severity: critical, warning or any (including suggestions)." enum:"none,critical,warning,any" default:"none"`
+	Triage      bool   `name:"triage" help:"After the review, step through the suggestions in a terminal UI to apply, skip, dismiss or copy each of them."`
 }
 
 type ValidateConfigCmd struct {
@@ -344,6 +1609,183 @@ type GitHubReviewPRCmd struct {
 	Head    string `short:"H" help:"Head commit SHA (auto-detected in GitHub Actions)."`
 	Verbose bool   `short:"v" help:"Enable verbose output."`
 	Message string `short:"m" help:"Message to display while processing." default:"Analyzing PR..."`
+	Jobs    int    `short:"j" help:"Number of files to review concurrently." default:"1"`
+	Format  string `name:"format" help:"Output format: text (default) or sarif. With sarif, the SARIF log is written to stdout besides posting the comment, and everything else to stderr." enum:"text,sarif" default:"text"`
+	FailOn  string `name:"fail-on" help:"Exit with code 1 when a finding has at least this severity: critical, warning or any (including suggestions)." enum:"none,critical,warning,any" default:"none"`
+}
+
+// progress is a single spinner for a multi-file review that counts the files
+// reviewed so far. It is safe for concurrent use.
+type progress struct {
+	spinner *spinner.Spinner
+	message string
+	total   int
+	done    int // Guarded by the spinner lock
+}
+
+// newProgress creates the progress display for reviewing total files,
+// shown on w.
+func newProgress(message string, total int, w *os.File) *progress {
+	p := &progress{
+		spinner: spinner.New(
+			spinner.CharSets[spinnerCharSet], spinnerRefreshRate,
+			spinner.WithWriterFile(w),
+		),
+		message: message,
+		total:   total,
+	}
+	p.spinner.Suffix = p.suffix()
+	return p
+}
+
+// suffix describes the progress next to the spinner.
+func (p *progress) suffix() string {
+	return fmt.Sprintf(" %s (%d/%d files)", p.message, p.done, p.total)
+}
+
+// fileDone counts a reviewed file.
+func (p *progress) fileDone() {
+	p.spinner.Lock()
+	defer p.spinner.Unlock()
+
+	p.done++
+	p.spinner.Suffix = p.suffix()
+}
+
+// start shows the spinner.
+func (p *progress) start() {
+	p.spinner.Start()
+}
+
+// pause hides the spinner while output is printed.
+func (p *progress) pause() {
+	p.spinner.Stop()
+}
+
+// resume shows the spinner again after pause, unless all files are done.
+func (p *progress) resume() {
+	p.spinner.Lock()
+	finished := p.done >= p.total
+	p.spinner.Unlock()
+
+	if !finished {
+		p.spinner.Start()
+	}
+}
+
+// stop hides the spinner for good.
+func (p *progress) stop() {
+	p.spinner.Stop()
+}
+
+// fileReview is the outcome of reviewing the diff of one file.
+type fileReview struct {
+	guides   []string
+	printer  *streamPrinter       // Printer that streamed the suggestions, if any
+	result   *agents.ReviewResult // Nil if the review failed or the budget skipped the file
+	err      error
+	skipped  bool // Skipped by the budget
+	duration time.Duration
+}
+
+// diffRun reviews the files of a diff. review can be called from several
+// workers at once.
+type diffRun struct {
+	reviewer   *agents.CodeReviewer // Nil when only the rules run
+	rules      *rules.Checker
+	cfg        *config.Config
+	bud        *budget.Budget
+	base, head string
+	progress   *progress
+	newPrinter func(file string) *streamPrinter // Nil disables streaming
+
+	mu        sync.Mutex // Guards res and gitClient, which aren't safe for concurrent use
+	res       *resolver.Resolver
+	gitClient *git.GitClient
+}
+
+// review reviews the diff of a single file and times the review.
+func (r *diffRun) review(ctx context.Context, file string) fileReview {
+	defer r.progress.fileDone()
+
+	start := time.Now()
+	review := r.reviewFile(ctx, file)
+	review.duration = time.Since(start)
+	return review
+}
+
+// reviewFile reviews the diff of a single file.
+func (r *diffRun) reviewFile(ctx context.Context, file string) fileReview {
+	// Skip files not started before the run was interrupted
+	if err := ctx.Err(); err != nil {
+		return fileReview{err: err}
+	}
+
+	r.mu.Lock()
+	llmReview := r.reviewer != nil && r.res.ShouldReview(file)
+	var guides []string
+	if llmReview {
+		var err error
+		guides, err = r.res.GetDiffGuides(file)
+		if err != nil {
+			r.mu.Unlock()
+			return fileReview{err: fmt.Errorf("failed to get guides: %w", err)}
+		}
+	}
+	// Get the structured diff data
+	diffData, err := r.gitClient.GetFileDiffData(r.base, r.head, file)
+	r.mu.Unlock()
+	if err != nil {
+		return fileReview{
+			guides: guides, err: fmt.Errorf("failed to get diff: %w", err),
+		}
+	}
+
+	// Check the rules on the whole diff, before the budget truncates it
+	findings := r.rules.CheckDiff(file, diffData)
+	rulesOnly := &agents.ReviewResult{Suggestions: findings}
+	if !llmReview {
+		return fileReview{result: rulesOnly}
+	}
+
+	// Estimate the review and apply the budget before calling the LLM
+	diffData, reservation, ok, err := fitDiffToBudget(
+		r.reviewer, r.cfg, r.bud, file, diffData,
+	)
+	if err != nil {
+		return fileReview{
+			guides: guides, err: fmt.Errorf("failed to estimate review: %w", err),
+		}
+	}
+	if !ok {
+		if r.rules.Applies(file) {
+			return fileReview{guides: guides, result: rulesOnly, skipped: true}
+		}
+		return fileReview{guides: guides, skipped: true}
+	}
+
+	var printer *streamPrinter
+	if r.newPrinter != nil {
+		printer = r.newPrinter(file)
+	}
+	if printer != nil {
+		for _, finding := range findings {
+			printer.print(finding)
+		}
+	}
+
+	// Perform diff review (reviewing only the changes)
+	result, err := r.reviewer.ReviewDiffStream(
+		ctx, r.cfg, diffData, file, printer.stream(),
+	)
+	settleBudget(r.bud, reservation, result)
+	if result != nil {
+		result.Suggestions = slices.Concat(findings, result.Suggestions)
+	}
+
+	return fileReview{
+		guides: guides, printer: printer, result: result, err: err,
+	}
 }
 
 func isValidSHA(sha string) bool {
@@ -363,15 +1805,27 @@ func (gr *GitHubReviewPRCmd) validate() error {
 	if gr.Head != "" && !isValidSHA(gr.Head) {
 		return fmt.Errorf("invalid head SHA: %s", gr.Head)
 	}
+	if gr.Jobs < 1 {
+		return fmt.Errorf("invalid number of jobs: %d", gr.Jobs)
+	}
 	return nil
 }
 
-func (gr *GitHubReviewPRCmd) Run(cli *CLI) error {
+func (gr *GitHubReviewPRCmd) Run(ctx context.Context, cli *CLI) error {
 	if err := gr.validate(); err != nil {
-		return err
+		return configError(err)
+	}
+
+	// Machine-readable output owns stdout, so everything else goes to stderr
+	out := os.Stdout
+	machine := report.Machine(gr.Format)
+	if machine {
+		out = os.Stderr
 	}
+	start := time.Now()
+
 	// Load configuration
-	cfg, err := loadConfig(cli.Config, gr.Verbose)
+	cfg, err := loadConfig(cli, gr.Verbose, out)
 	if err != nil {
 		return err
 	}
@@ -388,18 +1842,30 @@ func (gr *GitHubReviewPRCmd) Run(cli *CLI) error {
 	base, head := gr.Base, gr.Head
 	prNumber := gr.PR
 
-	if prNumber == 0 || base == "" || head == "" {
-		if event, err := ghClient.GetPRInfo(); err == nil {
-			if prNumber == 0 {
-				prNumber = event.PullRequest.Number
-			}
-			if base == "" {
-				base = event.PullRequest.Base.SHA
-			}
-			if head == "" {
-				head = event.PullRequest.Head.SHA
+	// The event also describes the pull request to the prompt templates
+	var pr *prompts.PullRequest
+	if event, err := ghClient.GetPRInfo(); err == nil {
+		number := event.PullRequest.Number
+		if number != 0 && (prNumber == 0 || number == prNumber) {
+			pr = &prompts.PullRequest{
+				Number: event.PullRequest.Number,
+				Title:  event.PullRequest.Title,
+				Body:   event.PullRequest.Body,
+				Author: event.PullRequest.User.Login,
+				Base:   event.PullRequest.Base.Ref,
+				Head:   event.PullRequest.Head.Ref,
 			}
 		}
+
+		if prNumber == 0 {
+			prNumber = event.PullRequest.Number
+		}
+		if base == "" {
+			base = event.PullRequest.Base.SHA
+		}
+		if head == "" {
+			head = event.PullRequest.Head.SHA
+		}
 	}
 
 	if base == "" || head == "" {
@@ -410,7 +1876,7 @@ func (gr *GitHubReviewPRCmd) Run(cli *CLI) error {
 	}
 
 	if gr.Verbose {
-		fmt.Printf("Reviewing PR #%d: %s..%s\n", prNumber, base, head)
+		fmt.Fprintf(out, "Reviewing PR #%d: %s..%s\n", prNumber, base, head)
 	}
 
 	// Initialize git client
@@ -426,100 +1892,176 @@ func (gr *GitHubReviewPRCmd) Run(cli *CLI) error {
 	}
 
 	if len(files) == 0 {
-		fmt.Println("No files changed in the specified range.")
+		fmt.Fprintln(out, "No files changed in the specified range.")
+		if machine {
+			return emitFiles(gr.Format, nil, nil, start)
+		}
 		return nil
 	}
 
 	if gr.Verbose {
-		fmt.Printf("Found %d changed files\n", len(files))
+		fmt.Fprintf(out, "Found %d changed files\n", len(files))
+	}
+
+	checker, err := rules.New(cfg.Rules)
+	if err != nil {
+		return configError(err)
 	}
 
 	// Filter files that should be reviewed
 	res := resolver.NewResolver(cfg)
 	var reviewableFiles []string
 	for _, file := range files {
-		if res.ShouldReview(file) {
+		if res.ShouldReview(file) || checker.Applies(file) {
 			reviewableFiles = append(reviewableFiles, file)
 		} else if gr.Verbose {
-			fmt.Printf("Skipping %s (no matching patterns)\n", file)
+			fmt.Fprintf(out, "Skipping %s (no matching patterns or rules)\n", file)
 		}
 	}
 
 	if len(reviewableFiles) == 0 {
-		fmt.Println("No files match review patterns.")
+		fmt.Fprintln(out, "No files match review patterns.")
+		if machine {
+			return emitFiles(gr.Format, nil, nil, start)
+		}
 		return nil
 	}
 
 	// Initialize reviewer
-	reviewer, err := agents.NewCodeReviewer()
+	reviewer, err := newRunReviewer(cli, cfg, checker, reviewableFiles)
 	if err != nil {
-		return fmt.Errorf("failed to create reviewer: %w", err)
+		return err
 	}
-
-	// Capture review output
-	var reviewOutput bytes.Buffer
-
-	// Review each changed file
-	totalTokens := 0
-	formatter := diff.NewFormatter()
-	for _, file := range reviewableFiles {
-		// Get guides for this file
-		guides, err := res.GetDiffGuides(file)
-		if err != nil {
-			fmt.Printf("Error getting guides for file: %v\n", err)
-			continue
-		}
-
-		if gr.Verbose {
-			fmt.Printf("Using diff guides: %v\n", guides)
+	if reviewer == nil {
+		reviewableFiles = slices.DeleteFunc(
+			reviewableFiles, func(file string) bool {
+				return !checker.Applies(file)
+			},
+		)
+	} else {
+		reviewer.SetPullRequest(pr)
+		if cfg.Agent.Enabled {
+			toolbox, err := headTools(".", head)
+			if err != nil {
+				return fmt.Errorf("failed to load repository tools: %w", err)
+			}
+			reviewer.SetTools(toolbox, cfg.Agent.MaxSteps)
 		}
+	}
 
-		// Get the structured diff data
-		diffData, err := gitClient.GetFileDiffData(base, head, file)
+	var emitter report.Emitter
+	if machine {
+		var closeEmitter func()
+		emitter, closeEmitter, err = newEmitter(gr.Format)
 		if err != nil {
-			fmt.Printf("Error getting diff for file: %v\n", err)
-			continue
+			return err
 		}
+		defer closeEmitter()
+	}
 
-		// Create spinner
-		s := spinner.New(spinner.CharSets[spinnerCharSet], spinnerRefreshRate)
-		s.Suffix = " " + gr.Message
-		s.Start()
+	// Capture review output
+	var reviewOutput bytes.Buffer
 
-		// Perform diff review (reviewing only the changes)
-		result, err := reviewer.ReviewDiff(cfg, diffData, file)
+	// Review the changed files concurrently, collecting the output in file order
+	slices.Sort(reviewableFiles)
+	var totals usageTotals
+	var summary report.Summary
+	bud := budget.New(cfg.Budget)
+	formatter := diff.NewFormatter()
+	prog := newProgress(gr.Message, len(reviewableFiles), out)
+	run := &diffRun{
+		reviewer:  reviewer,
+		rules:     checker,
+		cfg:       cfg,
+		res:       res,
+		gitClient: gitClient,
+		bud:       bud,
+		base:      base,
+		head:      head,
+		progress:  prog,
+	}
+
+	gate := runGate{failOn: gr.FailOn}
+	prog.start()
+	err = pool.Run(
+		ctx, reviewableFiles, gr.Jobs, run.review,
+		func(file string, review fileReview) error {
+			prog.pause()
+			defer prog.resume()
+
+			if gr.Verbose && review.guides != nil {
+				fmt.Fprintf(out, "Using diff guides for %s: %v\n", file, review.guides)
+				printGuideBudget(out, res, review.guides)
+			}
 
-		// Stop spinner
-		s.Stop()
+			if review.err != nil {
+				// Abort the remaining files when the run was interrupted
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// A replay missing a recording can't be trusted as a whole
+				if errors.Is(review.err, agents.ErrCassetteMiss) {
+					return fmt.Errorf("reviewing %s: %w", file, review.err)
+				}
+				fmt.Fprintf(out, "Error reviewing %s: %v\n", file, review.err)
+				gate.failed++
+			}
+			if review.result != nil {
+				gate.add(review.result.Suggestions)
+			}
 
-		if err != nil {
-			fmt.Printf("Error reviewing file: %v\n", err)
-			continue
-		}
+			if emitter != nil {
+				entry := report.NewFile(
+					file, review.result, review.err, review.duration,
+				)
+				entry.Guides = review.guides
+				entry.Skipped = review.skipped
+				summary.Add(entry)
+				if err := emitter.File(entry); err != nil {
+					return fmt.Errorf("failed to write report: %w", err)
+				}
+			}
 
-		if len(result.Suggestions) > 0 {
-			reviewOutput.WriteString(fmt.Sprintf("<details>\n"))
-			reviewOutput.WriteString(
-				fmt.Sprintf(
-					"<summary>📝 Review for <strong>%s</strong> (%d issues)</summary>\n\n",
-					file, len(result.Suggestions),
-				),
-			)
-			for _, suggestion := range result.Suggestions {
-				fullBody := buildSuggestionBody(suggestion)
-				formattedBody := formatter.Format(fullBody)
+			// Failed, or skipped by the budget
+			result := review.result
+			if review.err != nil || result == nil {
+				return nil
+			}
+
+			if len(result.Suggestions) > 0 {
+				reviewOutput.WriteString(fmt.Sprintf("<details>\n"))
 				reviewOutput.WriteString(
 					fmt.Sprintf(
-						"### %s\n%s\n\n", suggestion.Title, formattedBody,
+						"<summary>📝 Review for <strong>%s</strong> (%d issues)</summary>\n\n",
+						file, len(result.Suggestions),
 					),
 				)
+				if result.Truncated {
+					reviewOutput.WriteString(fmt.Sprintf("> %s\n\n", truncatedWarning))
+				}
+				for _, warning := range result.Warnings {
+					reviewOutput.WriteString(fmt.Sprintf("> ⚠️ %s\n\n", warning))
+				}
+				for _, suggestion := range result.Suggestions {
+					fullBody := buildSuggestionBody(suggestion)
+					formattedBody := formatter.Format(fullBody)
+					reviewOutput.WriteString(
+						fmt.Sprintf(
+							"### %s\n%s\n\n", suggestionTitle(suggestion),
+							formattedBody,
+						),
+					)
+				}
+				reviewOutput.WriteString("</details>\n")
 			}
-			reviewOutput.WriteString("</details>\n")
-		}
 
-		if result.TokensUsed > 0 {
-			totalTokens += result.TokensUsed
-		}
+			totals.add(result)
+			return nil
+		},
+	)
+	prog.stop()
+	if err != nil {
+		return err
 	}
 
 	// Post to GitHub
@@ -531,21 +2073,30 @@ func (gr *GitHubReviewPRCmd) Run(cli *CLI) error {
 	} else {
 		commentBody = "# 🍲 miso Code review\n\n✅ No issues found."
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	commentBody += budgetMarkdown(bud)
+	if totals.Tokens > 0 || totals.Cached > 0 {
+		usage := fmt.Sprintf(
+			"%d files reviewed (%d cached) · %d tokens · %s",
+			totals.Files, totals.Cached, totals.Tokens, totals.costString(),
+		)
+		if totals.CachedTokens > 0 {
+			usage += fmt.Sprintf(" · %d prompt tokens cached", totals.CachedTokens)
+		}
+		commentBody += fmt.Sprintf("\n\n---\n<sub>%s</sub>", usage)
+	}
+	postCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	if err := ghClient.PostOrUpdateComment(
-		ctx, prNumber, commentBody,
+		postCtx, prNumber, commentBody,
 	); err != nil {
 		return fmt.Errorf(
 			"failed to post comment to GitHub (PR #%d): %w", prNumber, err,
 		)
 	}
-	fmt.Printf("✅ Successfully posted review to PR #%d\n", prNumber)
+	fmt.Fprintf(out, "✅ Successfully posted review to PR #%d\n", prNumber)
 
 	// Clean up old comments
-	cleanupCtx, cleanupCancel := context.WithTimeout(
-		context.Background(), 30*time.Second,
-	)
+	cleanupCtx, cleanupCancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cleanupCancel()
 	if err := ghClient.CleanupOldComments(cleanupCtx, prNumber); err != nil {
 		// This is not a fatal error, so just log it.
@@ -557,17 +2108,37 @@ func (gr *GitHubReviewPRCmd) Run(cli *CLI) error {
 	// Summary for verbose mode
 	if gr.Verbose {
 		log.Printf(
-			"Review completed: Files=%d, Tokens=%d, PR=#%d\n",
-			len(reviewableFiles), totalTokens, prNumber,
+			"Review completed: Files=%d, Tokens=%d, Cost=%s, PR=#%d\n",
+			len(reviewableFiles), totals.Tokens, totals.costString(), prNumber,
 		)
 	}
 
-	return nil
+	if emitter != nil {
+		if err := closeReport(emitter, summary, bud, start); err != nil {
+			return err
+		}
+	}
+	return gate.err()
 }
 
-func (d *DiffCmd) Run(cli *CLI) error {
+func (d *DiffCmd) Run(ctx context.Context, cli *CLI) error {
+	if d.Jobs < 1 {
+		return configError(fmt.Errorf("invalid number of jobs: %d", d.Jobs))
+	}
+
+	// Machine-readable output owns stdout, so everything else goes to stderr
+	out := os.Stdout
+	machine := report.Machine(d.Format)
+	if machine {
+		out = os.Stderr
+	}
+	start := time.Now()
+	if err := checkTriage(d.Triage, d.Format); err != nil {
+		return err
+	}
+
 	// Load configuration
-	cfg, err := loadConfig(cli.Config, d.Verbose)
+	cfg, err := loadConfig(cli, d.Verbose, out)
 	if err != nil {
 		return err
 	}
@@ -578,6 +2149,16 @@ func (d *DiffCmd) Run(cli *CLI) error {
 		return fmt.Errorf("failed to initialize git client: %w", err)
 	}
 
+	// Diff paths are relative to the repository root, not the working
+	// directory, so triage reads and writes the files from there
+	var root string
+	if d.Triage {
+		root, err = gitClient.Root()
+		if err != nil {
+			return err
+		}
+	}
+
 	rangeStr := d.Range
 	targetFile := d.File
 
@@ -585,7 +2166,17 @@ func (d *DiffCmd) Run(cli *CLI) error {
 	base, head := git.ParseGitRange(rangeStr)
 
 	if d.Verbose {
-		fmt.Printf("Reviewing changes between %s and %s\n", base, head)
+		fmt.Fprintf(out, "Reviewing changes between %s and %s\n", base, head)
+	}
+
+	// nothingToReview ends a run without files to review, with an empty
+	// report in machine-readable formats
+	nothingToReview := func(message string) error {
+		fmt.Fprintln(out, message)
+		if machine {
+			return emitFiles(d.Format, nil, nil, start)
+		}
+		return nil
 	}
 
 	// Get changed files
@@ -595,12 +2186,16 @@ func (d *DiffCmd) Run(cli *CLI) error {
 	}
 
 	if len(files) == 0 {
-		fmt.Println("No files changed in the specified range.")
-		return nil
+		return nothingToReview("No files changed in the specified range.")
 	}
 
 	if d.Verbose {
-		fmt.Printf("Found %d changed files\n", len(files))
+		fmt.Fprintf(out, "Found %d changed files\n", len(files))
+	}
+
+	checker, err := rules.New(cfg.Rules)
+	if err != nil {
+		return configError(err)
 	}
 
 	// Filter files that should be reviewed
@@ -626,124 +2221,217 @@ func (d *DiffCmd) Run(cli *CLI) error {
 		}
 
 		if fileIsChanged {
-			if res.ShouldReview(relTargetFile) {
+			if res.ShouldReview(relTargetFile) || checker.Applies(relTargetFile) {
 				reviewableFiles = append(reviewableFiles, relTargetFile)
 			} else if d.Verbose {
-				fmt.Printf(
-					"Skipping %s (no matching patterns)\n", relTargetFile,
+				fmt.Fprintf(
+					out, "Skipping %s (no matching patterns or rules)\n",
+					relTargetFile,
 				)
 			}
 		} else {
-			fmt.Printf(
-				"File '%s' was not changed in the specified range.\n",
-				targetFile,
+			return nothingToReview(
+				fmt.Sprintf(
+					"File '%s' was not changed in the specified range.",
+					targetFile,
+				),
 			)
-			return nil
 		}
 	} else {
 		for _, file := range files {
-			if res.ShouldReview(file) {
+			if res.ShouldReview(file) || checker.Applies(file) {
 				reviewableFiles = append(reviewableFiles, file)
 			} else if d.Verbose {
-				fmt.Printf("Skipping %s (no matching patterns)\n", file)
+				fmt.Fprintf(out, "Skipping %s (no matching patterns or rules)\n", file)
 			}
 		}
 	}
 
 	if len(reviewableFiles) == 0 {
-		fmt.Println("No files match review patterns.")
-		return nil
+		return nothingToReview("No files match review patterns.")
 	}
 
 	// Dry run mode
 	if d.DryRun {
-		fmt.Printf("=== DRY RUN MODE ===\n")
-		fmt.Printf("Range: %s..%s\n", base, head)
-		fmt.Printf("Files that would be reviewed:\n")
+		fmt.Fprintf(out, "=== DRY RUN MODE ===\n")
+		fmt.Fprintf(out, "Range: %s..%s\n", base, head)
+		fmt.Fprintf(out, "Files that would be reviewed:\n")
 		for _, file := range reviewableFiles {
 			guides, _ := res.GetDiffGuides(file)
-			fmt.Printf("  - %s (guides: %v)\n", file, guides)
+			fmt.Fprintf(out, "  - %s (guides: %v)\n", file, guides)
 		}
-		return nil
-	}
-
-	// Initialize reviewer
-	reviewer, err := agents.NewCodeReviewer()
-	if err != nil {
-		return fmt.Errorf("failed to create reviewer: %w", err)
 	}
 
-	// Review each changed file
-	totalTokens := 0
-	for _, file := range reviewableFiles {
-		// Get guides for this file
-		guides, err := res.GetDiffGuides(file)
+	// Initialize reviewer; dry runs only check the rules
+	var reviewer *agents.CodeReviewer
+	if !d.DryRun {
+		reviewer, err = newRunReviewer(cli, cfg, checker, reviewableFiles)
 		if err != nil {
-			fmt.Printf("Error getting guides for file: %v\n", err)
-			continue
+			return err
 		}
-
-		if d.Verbose {
-			fmt.Printf("Using diff guides: %v\n", guides)
+	}
+	if reviewer == nil {
+		reviewableFiles = slices.DeleteFunc(
+			reviewableFiles, func(file string) bool {
+				return !checker.Applies(file)
+			},
+		)
+		if len(reviewableFiles) == 0 {
+			if machine {
+				return emitFiles(d.Format, nil, nil, start)
+			}
+			return nil
 		}
-
-		// Get the structured diff data
-		diffData, err := gitClient.GetFileDiffData(base, head, file)
+		if d.DryRun {
+			fmt.Fprintf(out, "\nRule findings:\n")
+		}
+	} else if cfg.Agent.Enabled {
+		toolbox, err := headTools(".", head)
 		if err != nil {
-			fmt.Printf("Error getting diff for file: %v\n", err)
-			continue
+			return fmt.Errorf("failed to load repository tools: %w", err)
 		}
+		reviewer.SetTools(toolbox, cfg.Agent.MaxSteps)
+	}
 
-		// Create spinner
-		s := spinner.New(spinner.CharSets[spinnerCharSet], spinnerRefreshRate)
-		s.Suffix = " " + d.Message
-		s.Start()
+	var emitter report.Emitter
+	if machine {
+		var closeEmitter func()
+		emitter, closeEmitter, err = newEmitter(d.Format)
+		if err != nil {
+			return err
+		}
+		defer closeEmitter()
+	}
+
+	// Review the changed files concurrently, printing the reports in file order
+	slices.Sort(reviewableFiles)
+	var totals usageTotals
+	var summary report.Summary
+	bud := budget.New(cfg.Budget)
+	prog := newProgress(d.Message, len(reviewableFiles), out)
+	run := &diffRun{
+		reviewer:  reviewer,
+		rules:     checker,
+		cfg:       cfg,
+		res:       res,
+		gitClient: gitClient,
+		bud:       bud,
+		base:      base,
+		head:      head,
+		progress:  prog,
+	}
+	// Suggestions can only be streamed while a single file is in flight
+	if d.Jobs <= 1 && !machine {
+		run.newPrinter = func(file string) *streamPrinter {
+			return newStreamPrinter(
+				file, d.OutputStyle == "rich", d.One, prog.spinner,
+			)
+		}
+	}
 
-		// Perform diff review (reviewing only the changes)
-		result, err := reviewer.ReviewDiff(cfg, diffData, file)
+	gate := runGate{failOn: d.FailOn, one: d.One}
+	var toTriage []triage.Item
+	prog.start()
+	err = pool.Run(
+		ctx, reviewableFiles, d.Jobs, run.review,
+		func(file string, review fileReview) error {
+			prog.pause()
+			defer prog.resume()
 
-		// Stop spinner
-		s.Stop()
+			if d.Verbose && review.guides != nil {
+				fmt.Fprintf(out, "Using diff guides for %s: %v\n", file, review.guides)
+				printGuideBudget(out, res, review.guides)
+			}
 
-		if err != nil {
-			fmt.Printf("Error reviewing file: %v\n", err)
-			continue
-		}
+			if review.err != nil {
+				// Abort the remaining files when the run was interrupted
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// A replay missing a recording can't be trusted as a whole
+				if errors.Is(review.err, agents.ErrCassetteMiss) {
+					return fmt.Errorf("reviewing %s: %w", file, review.err)
+				}
+				fmt.Fprintf(out, "Error reviewing %s: %v\n", file, review.err)
+				gate.failed++
+			}
+			if review.result != nil {
+				gate.add(review.result.Suggestions)
+			}
 
-		if d.One && len(result.Suggestions) > 0 {
-			result.Suggestions = result.Suggestions[:1]
-		}
+			result := review.result
+			if result != nil && d.One && len(result.Suggestions) > 0 {
+				result.Suggestions = result.Suggestions[:1]
+			}
+			if emitter != nil {
+				if result != nil && d.Verbose {
+					printDropped(out, result)
+				}
+				entry := report.NewFile(
+					file, result, review.err, review.duration,
+				)
+				entry.Guides = review.guides
+				entry.Skipped = review.skipped
+				summary.Add(entry)
+				if err := emitter.File(entry); err != nil {
+					return fmt.Errorf("failed to write report: %w", err)
+				}
+				return nil
+			}
 
-		markdownReport := formatSuggestionsToMarkdown(result.Suggestions, file)
+			// Failed, or skipped by the budget
+			if review.err != nil || result == nil {
+				return nil
+			}
 
-		// Apply glamour rendering if requested
-		if d.OutputStyle == "rich" && len(result.Suggestions) > 0 {
-			rendered, err := renderRichOutput(markdownReport)
-			if err != nil {
-				log.Printf("Failed to initialize rich renderer: %v", err)
-				fmt.Println(markdownReport) // Fallback to plain
+			if review.printer != nil {
+				review.printer.finish(result)
 			} else {
-				fmt.Print(rendered)
+				printReport(result, file, d.OutputStyle == "rich")
 			}
-		} else {
-			fmt.Println(markdownReport)
-		}
+			if d.Verbose {
+				printDropped(os.Stdout, result)
+			}
+			if d.Triage {
+				toTriage = append(
+					toTriage, triageItems(root, file, result.Suggestions)...,
+				)
+			}
+
+			totals.add(result)
+			return nil
+		},
+	)
+	prog.stop()
+	if err != nil {
+		return err
+	}
 
-		if result.TokensUsed > 0 {
-			totalTokens += result.TokensUsed
+	if emitter != nil {
+		if err := closeReport(emitter, summary, bud, start); err != nil {
+			return err
 		}
+		return gate.err()
 	}
 
 	// Summary for verbose mode
 	if d.Verbose {
 		fmt.Printf("\n=== Summary ===\n")
 		fmt.Printf("Files reviewed: %d\n", len(reviewableFiles))
-		if totalTokens > 0 {
-			fmt.Printf("Total tokens used: %d\n", totalTokens)
-		}
 	}
 
-	return nil
+	printBudgetAdjustments(os.Stdout, bud)
+
+	// Token usage and cost for the whole run
+	if totals.Tokens > 0 || totals.Cached > 0 {
+		fmt.Printf("\n---\n")
+		totals.print()
+	}
+
+	if err := runTriage(toTriage, d.OutputStyle == "rich"); err != nil {
+		return err
+	}
+	return gate.err()
 }
 
 func validatePatterns(patterns []config.Pattern) []string {
@@ -970,7 +2658,10 @@ func renderRichOutput(content string) (string, error) {
 	return rendered, nil
 }
 
-func loadConfig(configPath string, verbose bool) (*config.Config, error) {
+// loadConfig loads the configuration and applies the command-line flags.
+// Verbose messages are written to w.
+func loadConfig(cli *CLI, verbose bool, w io.Writer) (*config.Config, error) {
+	configPath := cli.Config
 	parser := config.NewParser()
 	var cfg *config.Config
 	var err error
@@ -978,33 +2669,54 @@ func loadConfig(configPath string, verbose bool) (*config.Config, error) {
 	if configPath != "" {
 		cfg, err = parser.LoadFile(configPath)
 		if err != nil {
-			return nil, fmt.Errorf(
-				"failed to load config file %s: %w", configPath, err,
+			return nil, configError(
+				fmt.Errorf("failed to load config file %s: %w", configPath, err),
 			)
 		}
 		if verbose {
-			fmt.Printf("Using config file: %s\n", configPath)
+			fmt.Fprintf(w, "Using config file: %s\n", configPath)
 		}
 	} else {
 		cfg, err = parser.Load()
 		if err != nil {
-			return nil, fmt.Errorf("failed to load configuration: %w", err)
+			return nil, configError(
+				fmt.Errorf("failed to load configuration: %w", err),
+			)
 		}
 		if verbose && len(cfg.Patterns) == 0 {
-			fmt.Println("Using default configuration (no config file found or config is empty)")
+			fmt.Fprintln(w, "Using default configuration (no config file found or config is empty)")
 		}
 	}
+
+	// Command-line flags take precedence over the config file
+	cli.LLM.apply(&cfg.LLM)
+	cli.Budget.apply(&cfg.Budget)
+	cli.Agent.apply(&cfg.Agent)
+	cli.Verify.apply(&cfg.Verify)
+	if err := parser.Validate(cfg); err != nil {
+		return nil, configError(fmt.Errorf("invalid configuration: %w", err))
+	}
+	if err := prompts.ValidateTemplates(cfg); err != nil {
+		return nil, configError(fmt.Errorf("invalid prompt template: %w", err))
+	}
+
 	return cfg, nil
 }
 
 func main() {
+	// Cancel in-flight LLM calls on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM,
+	)
+	defer stop()
+
 	var cli CLI
-	ctx := kong.Parse(
+	kctx := kong.Parse(
 		&cli,
 		kong.Name("miso"),
 		kong.Description("AI-powered code review tool"),
 		kong.UsageOnError(),
+		kong.BindTo(ctx, (*context.Context)(nil)),
 	)
-	err := ctx.Run()
-	ctx.FatalIfErrorf(err)
+	kctx.FatalIfErrorf(withExitCode(kctx.Run()))
 }
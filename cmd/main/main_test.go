@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/j0lvera/miso/internal/agents"
+	"github.com/j0lvera/miso/internal/report"
+)
+
+// exitCode returns the code err exits miso with, 0 for nil.
+func exitCode(err error) int {
+	var coder kong.ExitCoder
+	if errors.As(withExitCode(err), &coder) {
+		return coder.ExitCode()
+	}
+	return 0
+}
+
+func TestRunGate(t *testing.T) {
+	suggestions := []agents.Suggestion{
+		{Severity: agents.SeverityWarning},
+		{Severity: agents.SeveritySuggestion},
+	}
+
+	tests := []struct {
+		name     string
+		failOn   string
+		critical bool // Add a critical finding
+		failed   int
+		wantCode int
+	}{
+		{name: "none never fails on findings", failOn: failOnNone, critical: true},
+		{name: "critical without critical findings", failOn: failOnCritical},
+		{name: "critical", failOn: failOnCritical, critical: true, wantCode: exitCodeFindings},
+		{name: "warning", failOn: failOnWarning, wantCode: exitCodeFindings},
+		{name: "any", failOn: failOnAny, wantCode: exitCodeFindings},
+		{name: "failed files", failOn: failOnCritical, failed: 1, wantCode: exitCodeFailedFiles},
+		{
+			name:     "findings before failed files",
+			failOn:   failOnAny,
+			failed:   1,
+			wantCode: exitCodeFindings,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				gate := runGate{failOn: tt.failOn, failed: tt.failed}
+				gate.add(suggestions)
+				if tt.critical {
+					gate.add([]agents.Suggestion{{Severity: agents.SeverityCritical}})
+				}
+
+				if got := exitCode(gate.err()); got != tt.wantCode {
+					t.Errorf("exit code = %d, want %d (%v)", got, tt.wantCode, gate.err())
+				}
+			},
+		)
+	}
+}
+
+func TestRunGate_One(t *testing.T) {
+	gate := runGate{failOn: failOnCritical, one: true}
+	gate.add(
+		[]agents.Suggestion{
+			{Severity: agents.SeveritySuggestion},
+			{Severity: agents.SeverityCritical},
+		},
+	)
+	if got := exitCode(gate.err()); got != 0 {
+		t.Errorf("exit code = %d, want 0 for a finding hidden by --one", got)
+	}
+}
+
+func TestWithExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "success", err: nil, wantCode: 0},
+		{
+			name:     "findings",
+			err:      (&runGate{failOn: failOnAny, findings: 1}).err(),
+			wantCode: exitCodeFindings,
+		},
+		{
+			name:     "failed files",
+			err:      (&runGate{failed: 2}).err(),
+			wantCode: exitCodeFailedFiles,
+		},
+		{
+			name:     "wrapped config error",
+			err:      fmt.Errorf("review: %w", configError(errors.New("bad rule"))),
+			wantCode: exitCodeConfig,
+		},
+		{name: "other error", err: errors.New("network down"), wantCode: exitCodeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := exitCode(tt.err); got != tt.wantCode {
+					t.Errorf("exit code = %d, want %d", got, tt.wantCode)
+				}
+			},
+		)
+	}
+}
+
+func TestReviewCmd_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	content := "package main\n\nfunc main() {\n\tfmt.Println(1)\n}\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		return path
+	}
+	rules := write(
+		"rules.yml", `
+rules:
+  - name: no-println
+    pattern: "fmt\\.Println\\("
+    title: Debug print
+    severity: warning
+`,
+	)
+	ordered := write(
+		"ordered.yml", `
+rules:
+  - name: package
+    pattern: "^package "
+    title: Package clause
+    severity: suggestion
+  - name: no-println
+    pattern: "fmt\\.Println\\("
+    title: Debug print
+    severity: warning
+`,
+	)
+	invalid := write(
+		"invalid.yml", `
+rules:
+  - name: broken
+    pattern: "("
+`,
+	)
+
+	tests := []struct {
+		name     string
+		config   string
+		failOn   string
+		one      bool
+		wantCode int
+	}{
+		{name: "dry run finding under the threshold", config: rules, failOn: failOnCritical},
+		{
+			name:     "dry run finding at the threshold",
+			config:   rules,
+			failOn:   failOnWarning,
+			wantCode: exitCodeFindings,
+		},
+		{
+			name:     "finding hidden by --one",
+			config:   ordered,
+			failOn:   failOnWarning,
+			one:      true,
+			wantCode: 0,
+		},
+		{
+			name:     "finding shown without --one",
+			config:   ordered,
+			failOn:   failOnWarning,
+			wantCode: exitCodeFindings,
+		},
+		{
+			name:     "invalid config",
+			config:   invalid,
+			failOn:   failOnNone,
+			wantCode: exitCodeConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cmd := &ReviewCmd{
+					File:        file,
+					DryRun:      true,
+					OutputStyle: "plain",
+					Format:      "text",
+					FailOn:      tt.failOn,
+					One:         tt.one,
+				}
+				err := cmd.Run(context.Background(), &CLI{Config: tt.config})
+				if got := exitCode(err); got != tt.wantCode {
+					t.Errorf("exit code = %d, want %d (%v)", got, tt.wantCode, err)
+				}
+			},
+		)
+	}
+}
+
+func TestRepoPath(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "main.go")
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "relative to the repository root", path: "main.go", want: "cmd/main/main.go"},
+		{name: "parent directory", path: "../main/main.go", want: "cmd/main/main.go"},
+		{name: "outside of a repository", path: outside, want: filepath.ToSlash(outside)},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := repoPath(tt.path); got != tt.want {
+					t.Errorf("repoPath(%q) = %q, want %q", tt.path, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestHeadTools(t *testing.T) {
+	toolbox, err := headTools(".", "HEAD")
+	if err != nil {
+		t.Fatalf("headTools() error = %v", err)
+	}
+	if toolbox.Revision() == "" {
+		t.Error("Revision() is empty, want the commit of HEAD")
+	}
+
+	// Paths are relative to the repository root, not to dir
+	got := toolbox.Call(context.Background(), "read_file", `{"path": "go.mod", "end_line": 1}`)
+	if got != "1: module github.com/j0lvera/miso\n" {
+		t.Errorf("read_file(go.mod) = %q", got)
+	}
+}
+
+func TestReviewCmd_DryRunReport(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("fmt.Println(1)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	config := filepath.Join(dir, "rules.yml")
+	rules := `
+rules:
+  - name: no-println
+    pattern: "fmt\\.Println\\("
+    title: Debug print
+    severity: warning
+`
+	if err := os.WriteFile(config, []byte(rules), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	stdout := captureStdout(
+		t, func() {
+			cmd := &ReviewCmd{File: file, DryRun: true, Format: "json", FailOn: failOnNone}
+			if err := cmd.Run(context.Background(), &CLI{Config: config}); err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		},
+	)
+
+	var document struct {
+		Files []report.File `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &document); err != nil {
+		t.Fatalf("Expected a JSON report, got %q: %v", stdout, err)
+	}
+	if len(document.Files) != 1 || len(document.Files[0].Suggestions) != 1 {
+		t.Fatalf("Expected the rule finding in the report, got %+v", document.Files)
+	}
+	want := filepath.ToSlash(file)
+	if got := document.Files[0].Suggestions[0].File; got != want {
+		t.Errorf("Suggestion file = %q, want the report path %q", got, want)
+	}
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-output
+}
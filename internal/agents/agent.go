@@ -0,0 +1,167 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/j0lvera/miso/internal/tools"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// defaultMaxSteps is the number of tool-calling rounds used when none is set.
+const defaultMaxSteps = 8
+
+// toolsInstruction tells the model what the repository tools are for.
+const toolsInstruction = `You can call tools to read the repository at the reviewed revision: read files, list directories, grep, and look up Go symbols. Use them when the code under review isn't enough to tell whether something is a problem, e.g. to check the callers of a changed function or the definition of a type. Don't explore the repository beyond what the review needs.`
+
+// finalStepInstruction is sent when the model has used all its tool calls.
+const finalStepInstruction = `You have used all the tool calls available for this review. Reply now with your review based on what you have read, without calling any more tools.`
+
+// SetTools lets the model call the read-only repository tools of tb for up
+// to maxSteps rounds before it must answer. A nil toolbox disables tools.
+func (cr *CodeReviewer) SetTools(tb *tools.Toolbox, maxSteps int) {
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	cr.tools = tb
+	cr.maxSteps = maxSteps
+}
+
+// systemInstruction returns the system message of a review call, if any.
+func (cr *CodeReviewer) systemInstruction() string {
+	var instruction string
+	if cr.tools != nil {
+		instruction = toolsInstruction
+	}
+	if cr.structured == structuredTool {
+		if instruction != "" {
+			instruction += "\n\n"
+		}
+		instruction += "Submit your review by calling the " + reviewToolName +
+			" tool instead of replying with text."
+	}
+	return instruction
+}
+
+// complete sends messages to the model and returns its final response along
+// with the conversation, including any tool calls made along the way. Calls
+// to repository tools are answered and the model is asked again, until it
+// replies with the review or runs out of steps.
+func (cr *CodeReviewer) complete(
+	ctx context.Context, messages []llms.MessageContent,
+	options []llms.CallOption, result *ReviewResult,
+) (*llms.ContentResponse, []llms.MessageContent, error) {
+	if cr.tools == nil {
+		resp, err := cr.generateContent(ctx, messages, options, result)
+		if err != nil {
+			return nil, messages, err
+		}
+		return resp, messages, nil
+	}
+
+	definitions := cr.tools.Definitions()
+	if cr.structured == structuredTool {
+		definitions = append(definitions, reviewTool())
+	}
+	stepOptions := append(
+		options[:len(options):len(options)], llms.WithTools(definitions),
+	)
+
+	for step := 0; ; step++ {
+		if step == cr.maxSteps {
+			// Without the repository tools the model has to answer
+			messages = append(
+				messages,
+				llms.TextParts(llms.ChatMessageTypeHuman, finalStepInstruction),
+			)
+			stepOptions = options
+		}
+
+		resp, err := cr.generateContent(ctx, messages, stepOptions, result)
+		if err != nil {
+			return nil, messages, err
+		}
+
+		calls := cr.toolCalls(resp)
+		if step >= cr.maxSteps || len(calls) == 0 {
+			return resp, messages, nil
+		}
+
+		// Each call goes in its own message followed by its result, which
+		// every provider accepts, including those reading one call per message.
+		for _, call := range calls {
+			output := cr.tools.Call(
+				ctx, call.FunctionCall.Name, call.FunctionCall.Arguments,
+			)
+			messages = append(
+				messages,
+				llms.MessageContent{
+					Role:  llms.ChatMessageTypeAI,
+					Parts: []llms.ContentPart{call},
+				},
+				llms.MessageContent{
+					Role: llms.ChatMessageTypeTool,
+					Parts: []llms.ContentPart{
+						llms.ToolCallResponse{
+							ToolCallID: call.ID,
+							Name:       call.FunctionCall.Name,
+							Content:    output,
+						},
+					},
+				},
+			)
+			result.ToolCalls++
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, messages, err
+		}
+	}
+}
+
+// generateContent sends messages to the model once and adds the usage of
+// the response to the result, including the prompt tokens the provider read
+// from its prompt cache.
+func (cr *CodeReviewer) generateContent(
+	ctx context.Context, messages []llms.MessageContent,
+	options []llms.CallOption, result *ReviewResult,
+) (*llms.ContentResponse, error) {
+	ctx, cacheUsage := withPromptCacheUsage(ctx)
+	resp, err := cr.llm.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		return nil, err
+	}
+	addUsage(result, resp)
+	result.CachedTokens += int(cacheUsage.tokens.Load())
+	return resp, nil
+}
+
+// toolCalls returns the repository tool calls of a response. A response
+// submitting the review is final, so it has none.
+func (cr *CodeReviewer) toolCalls(resp *llms.ContentResponse) []llms.ToolCall {
+	var calls []llms.ToolCall
+	for _, choice := range resp.Choices {
+		for _, call := range choice.ToolCalls {
+			if call.FunctionCall == nil {
+				continue
+			}
+			if call.FunctionCall.Name == reviewToolName {
+				return nil
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// toolsCacheKey identifies the repository the tools read from, or reports
+// false when its contents aren't pinned to a revision and can't be cached.
+func (cr *CodeReviewer) toolsCacheKey() (string, bool) {
+	if cr.tools == nil {
+		return "", true
+	}
+	if cr.tools.Revision() == "" {
+		return "", false
+	}
+	return fmt.Sprintf("tools:%s:%d", cr.tools.Revision(), cr.maxSteps), true
+}
@@ -0,0 +1,196 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/tools"
+)
+
+// newMessageStub serves the scripted assistant messages of an
+// OpenAI-compatible API in order, repeating the last one, and records the
+// requests it receives.
+func newMessageStub(
+	t *testing.T, messages []map[string]any, requests *[]map[string]any,
+) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var req map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+
+				mu.Lock()
+				*requests = append(*requests, req)
+				message := messages[min(len(*requests), len(messages))-1]
+				mu.Unlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":     "chatcmpl-1",
+						"object": "chat.completion",
+						"choices": []map[string]any{
+							{"index": 0, "finish_reason": "stop", "message": message},
+						},
+						"usage": map[string]any{
+							"prompt_tokens":     10,
+							"completion_tokens": 5,
+							"total_tokens":      15,
+						},
+					},
+				)
+			},
+		),
+	)
+}
+
+// toolCallMessage is an assistant message calling a repository tool.
+func toolCallMessage(id, name, arguments string) map[string]any {
+	return map[string]any{
+		"role":    "assistant",
+		"content": "",
+		"tool_calls": []map[string]any{
+			{
+				"id":   id,
+				"type": "function",
+				"function": map[string]any{
+					"name":      name,
+					"arguments": arguments,
+				},
+			},
+		},
+	}
+}
+
+func TestCodeReviewer_Tools(t *testing.T) {
+	repo := fstest.MapFS{
+		"store.go": {Data: []byte("package main\n\nfunc Lookup() {}\n")},
+	}
+	answer := map[string]any{"role": "assistant", "content": stubSuggestions}
+	readStore := toolCallMessage("call_1", "read_file", `{"path":"store.go"}`)
+
+	tests := []struct {
+		name          string
+		messages      []map[string]any
+		maxSteps      int
+		wantRequests  int
+		wantToolCalls int
+	}{
+		{
+			name:          "tool call then answer",
+			messages:      []map[string]any{readStore, answer},
+			maxSteps:      4,
+			wantRequests:  2,
+			wantToolCalls: 1,
+		},
+		{
+			name:          "answer without tools",
+			messages:      []map[string]any{answer},
+			maxSteps:      4,
+			wantRequests:  1,
+			wantToolCalls: 0,
+		},
+		{
+			name:          "step limit forces an answer",
+			messages:      []map[string]any{readStore, answer},
+			maxSteps:      1,
+			wantRequests:  2,
+			wantToolCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var requests []map[string]any
+				server := newMessageStub(t, tt.messages, &requests)
+				defer server.Close()
+
+				reviewer, err := NewCodeReviewer(
+					config.LLM{
+						Provider: config.ProviderOpenAICompatible,
+						BaseURL:  server.URL,
+						Model:    "local-model",
+					},
+				)
+				if err != nil {
+					t.Fatalf("NewCodeReviewer() error = %v", err)
+				}
+				reviewer.SetTools(tools.New(repo, "abc123"), tt.maxSteps)
+
+				result, err := reviewer.Review(
+					context.Background(), config.DefaultConfig(),
+					"package main", "main.go",
+				)
+				if err != nil {
+					t.Fatalf("Review() error = %v", err)
+				}
+
+				if len(requests) != tt.wantRequests {
+					t.Fatalf(
+						"Expected %d requests, got %d", tt.wantRequests,
+						len(requests),
+					)
+				}
+				if result.ToolCalls != tt.wantToolCalls {
+					t.Errorf(
+						"Expected %d tool calls, got %d", tt.wantToolCalls,
+						result.ToolCalls,
+					)
+				}
+				if len(result.Suggestions) != 1 {
+					t.Errorf(
+						"Expected 1 suggestion, got %d", len(result.Suggestions),
+					)
+				}
+				if result.TokensUsed != 15*tt.wantRequests {
+					t.Errorf(
+						"Expected usage summed across steps, got %d tokens",
+						result.TokensUsed,
+					)
+				}
+
+				if _, ok := requests[0]["tools"]; !ok {
+					t.Errorf("Expected the repository tools in the first request")
+				}
+				if tt.wantToolCalls == 0 {
+					return
+				}
+
+				// The tool result is sent back with the id of its call
+				last := requests[len(requests)-1]
+				messages, _ := last["messages"].([]any)
+				var toolResult map[string]any
+				for _, m := range messages {
+					if msg, _ := m.(map[string]any); msg["role"] == "tool" {
+						toolResult = msg
+					}
+				}
+				if toolResult["tool_call_id"] != "call_1" ||
+					!strings.Contains(fmt.Sprint(toolResult["content"]), "func Lookup()") {
+					t.Errorf("Expected the read_file result, got %v", toolResult)
+				}
+
+				_, hasTools := last["tools"]
+				if atLimit := tt.maxSteps == 1; hasTools == atLimit {
+					t.Errorf(
+						"Expected tools in the last request: %v, got %v",
+						!atLimit, hasTools,
+					)
+				}
+			},
+		)
+	}
+}
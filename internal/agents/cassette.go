@@ -0,0 +1,191 @@
+package agents
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/cache"
+)
+
+// Record/replay modes of LLM calls, set with the MISO_LLM_MODE variable.
+const (
+	LLMModeRecord = "record" // Call the provider and save each exchange as a cassette
+	LLMModeReplay = "replay" // Serve responses from cassettes without calling the provider
+)
+
+// Environment variables controlling record/replay.
+const (
+	llmModeEnv     = "MISO_LLM_MODE"
+	cassetteDirEnv = "MISO_CASSETTE_DIR"
+)
+
+// defaultCassetteDir holds the cassettes when MISO_CASSETTE_DIR isn't set.
+const defaultCassetteDir = ".miso/cassettes"
+
+// ErrCassetteMiss is returned in replay mode for a request that was never
+// recorded.
+var ErrCassetteMiss = errors.New("no recorded response for request")
+
+// LLMMode returns the record/replay mode from MISO_LLM_MODE, or an empty
+// string when LLM calls go to the provider as usual.
+func LLMMode() (string, error) {
+	mode := os.Getenv(llmModeEnv)
+	switch mode {
+	case "", LLMModeRecord, LLMModeReplay:
+		return mode, nil
+	}
+	return "", fmt.Errorf(
+		"invalid %s %q: must be %s or %s", llmModeEnv, mode, LLMModeRecord,
+		LLMModeReplay,
+	)
+}
+
+// CassetteDir returns the directory of the cassettes, from MISO_CASSETTE_DIR.
+func CassetteDir() string {
+	if dir := os.Getenv(cassetteDirEnv); dir != "" {
+		return dir
+	}
+	return defaultCassetteDir
+}
+
+// cassette is a recorded request and its response.
+type cassette struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header"`
+		Body       string      `json:"body"`
+	} `json:"response"`
+}
+
+// cassetteTransport records LLM exchanges to cassettes or replays them.
+// Requests are matched by a hash of their method, URL and body; headers,
+// which carry the API key, are neither matched nor recorded.
+type cassetteTransport struct {
+	base http.RoundTripper
+	mode string
+	dir  string
+}
+
+// RoundTrip serves the request from its cassette in replay mode, and sends
+// it and records the response in record mode.
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	url := *req.URL
+	url.User = nil
+	path := filepath.Join(t.dir, cassetteKey(req.Method, url.String(), body)+".json")
+
+	if t.mode == LLMModeReplay {
+		return t.replay(req, path)
+	}
+
+	req2 := req.Clone(req.Context())
+	req2.Body = io.NopCloser(bytes.NewReader(body))
+	req2.ContentLength = int64(len(body))
+	resp, err := t.base.RoundTrip(req2)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	// Failed calls aren't recorded, so a later recording can fill them in
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var c cassette
+		c.Request.Method = req.Method
+		c.Request.URL = url.String()
+		c.Request.Body = string(body)
+		c.Response.StatusCode = resp.StatusCode
+		c.Response.Header = http.Header{
+			"Content-Type": resp.Header.Values("Content-Type"),
+		}
+		c.Response.Body = string(respBody)
+
+		if err := writeCassette(path, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// replay returns the response recorded at path.
+func (t *cassetteTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf(
+			"%w: %s %s (expected cassette %s); record it with %s=%s",
+			ErrCassetteMiss, req.Method, req.URL.Redacted(), path, llmModeEnv,
+			LLMModeRecord,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.Response.StatusCode, http.StatusText(c.Response.StatusCode)),
+		StatusCode:    c.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Response.Header,
+		Body:          io.NopCloser(strings.NewReader(c.Response.Body)),
+		ContentLength: int64(len(c.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// writeCassette saves a cassette, replacing any earlier recording.
+func writeCassette(path string, c cassette) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if err := cache.WriteFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// cassetteKey identifies a request by its method, URL and body.
+func cassetteKey(method string, url string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, url)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,139 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestLLMMode(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"", false},
+		{LLMModeRecord, false},
+		{LLMModeReplay, false},
+		{"playback", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.value, func(t *testing.T) {
+				t.Setenv(llmModeEnv, tt.value)
+				mode, err := LLMMode()
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("LLMMode() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if !tt.wantErr && mode != tt.value {
+					t.Errorf("LLMMode() = %q, want %q", mode, tt.value)
+				}
+			},
+		)
+	}
+}
+
+func TestCassette_RecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(cassetteDirEnv, dir)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	var requests []map[string]any
+	server := newScriptedStub(t, []string{stubSuggestions}, &requests)
+
+	llmCfg := config.LLM{
+		Provider: config.ProviderOpenAICompatible,
+		BaseURL:  server.URL,
+		Model:    "local-model",
+	}
+	review := func(code string) (*ReviewResult, error) {
+		t.Helper()
+		reviewer, err := NewCodeReviewer(llmCfg)
+		if err != nil {
+			t.Fatalf("NewCodeReviewer() error = %v", err)
+		}
+		return reviewer.Review(
+			context.Background(), config.DefaultConfig(), code, "main.go",
+		)
+	}
+
+	t.Setenv(llmModeEnv, LLMModeRecord)
+	recorded, err := review("package main")
+	if err != nil {
+		t.Fatalf("Review() while recording error = %v", err)
+	}
+	cassettes, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(cassettes) != 1 {
+		t.Fatalf("Expected 1 cassette, got %d", len(cassettes))
+	}
+
+	// Replays never reach the provider
+	server.Close()
+	t.Setenv(llmModeEnv, LLMModeReplay)
+
+	replayed, err := review("package main")
+	if err != nil {
+		t.Fatalf("Review() while replaying error = %v", err)
+	}
+	if len(replayed.Suggestions) != 1 ||
+		replayed.Suggestions[0].ID != recorded.Suggestions[0].ID {
+		t.Errorf("Expected the recorded suggestions, got %+v", replayed.Suggestions)
+	}
+	if replayed.TokensUsed != recorded.TokensUsed {
+		t.Errorf(
+			"Expected the recorded usage %d, got %d", recorded.TokensUsed,
+			replayed.TokensUsed,
+		)
+	}
+
+	_, err = review("package other")
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("Expected a cassette miss for a new prompt, got %v", err)
+	}
+}
+
+func TestCassette_SkipsFailedCalls(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(cassetteDirEnv, dir)
+	t.Setenv(llmModeEnv, LLMModeRecord)
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderOpenAICompatible,
+			BaseURL:  server.URL,
+			Model:    "local-model",
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	if _, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	); err == nil {
+		t.Fatal("Expected the review to fail")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no cassettes for a failed call, got %d", len(entries))
+	}
+}
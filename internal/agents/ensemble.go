@@ -0,0 +1,227 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/prompts"
+)
+
+// similarityThreshold is how alike two suggestions of different models must
+// be, from 0 to 1, to be merged into one finding.
+const similarityThreshold = 0.5
+
+// stopWords are left out when comparing titles.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true,
+	"its": true, "of": true, "on": true, "or": true, "the": true,
+	"this": true, "to": true, "when": true, "with": true,
+}
+
+// callEnsemble reviews a prompt with every model of the ensemble in
+// parallel and merges their suggestions. Any failed call fails the review,
+// since the agreement of the others would be misleading.
+func (cr *CodeReviewer) callEnsemble(
+	ctx context.Context, cfg *config.Config, prompt prompts.Prompt,
+	settings callSettings,
+) (*ReviewResult, error) {
+	results := make([]*ReviewResult, len(settings.ensemble))
+	errs := make([]error, len(settings.ensemble))
+
+	var wg sync.WaitGroup
+	for i, model := range settings.ensemble {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			modelSettings := settings
+			modelSettings.model = model
+			modelSettings.ensemble = nil
+
+			result, err := cr.callLLM(ctx, prompt, modelSettings, nil)
+			if err != nil {
+				errs[i] = fmt.Errorf("ensemble model %s: %w", model, err)
+				return
+			}
+			cr.applyCost(cfg, result)
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mergeEnsemble(settings.ensemble, results, settings.minAgreement), nil
+}
+
+// finding is a group of similar suggestions from different models.
+type finding struct {
+	suggestion Suggestion // From the first model that reported it
+	models     []string
+}
+
+// mergeEnsemble groups the similar suggestions of the ensemble results and
+// keeps the findings reported by at least minAgreement models, most agreed
+// first. Usage is summed over the results.
+func mergeEnsemble(
+	models []string, results []*ReviewResult, minAgreement int,
+) *ReviewResult {
+	merged := &ReviewResult{
+		Model:  strings.Join(models, "+"),
+		Priced: true,
+		Cached: true,
+	}
+
+	var findings []*finding
+	for i, result := range results {
+		model := models[i]
+
+		merged.TokensUsed += result.TokensUsed
+		merged.InputTokens += result.InputTokens
+		merged.OutputTokens += result.OutputTokens
+		merged.CachedTokens += result.CachedTokens
+		merged.Cost += result.Cost
+		merged.ToolCalls += result.ToolCalls
+		merged.Priced = merged.Priced && result.Priced
+		merged.Cached = merged.Cached && result.Cached
+		merged.Truncated = merged.Truncated || result.Truncated
+		merged.Warnings = append(merged.Warnings, result.Warnings...)
+
+		for _, suggestion := range result.Suggestions {
+			var best *finding
+			bestScore := similarityThreshold
+			for _, f := range findings {
+				// A model doesn't agree with itself
+				if slices.Contains(f.models, model) {
+					continue
+				}
+				if score := similarity(f.suggestion, suggestion); score >= bestScore {
+					best, bestScore = f, score
+				}
+			}
+
+			if best == nil {
+				findings = append(
+					findings,
+					&finding{suggestion: suggestion, models: []string{model}},
+				)
+				continue
+			}
+			best.models = append(best.models, model)
+		}
+	}
+
+	// Models sort their suggestions by severity; keep that order among
+	// findings with the same agreement
+	slices.SortStableFunc(
+		findings, func(a, b *finding) int {
+			return len(b.models) - len(a.models)
+		},
+	)
+
+	minAgreement = min(max(minAgreement, 1), len(models))
+	ids := make(map[string]bool)
+	merged.Suggestions = []Suggestion{}
+	for _, f := range findings {
+		if len(f.models) < minAgreement {
+			continue
+		}
+
+		suggestion := f.suggestion
+		suggestion.ReportedBy = f.models
+		suggestion.Ensemble = len(models)
+
+		// Models number their suggestions independently
+		id := suggestion.ID
+		for n := 2; ids[id]; n++ {
+			id = fmt.Sprintf("%s-%d", suggestion.ID, n)
+		}
+		suggestion.ID = id
+		ids[id] = true
+
+		merged.Suggestions = append(merged.Suggestions, suggestion)
+	}
+
+	return merged
+}
+
+// Similar reports whether two suggestions likely describe the same finding.
+func Similar(a, b Suggestion) bool {
+	return similarity(a, b) >= similarityThreshold
+}
+
+// similarity scores how likely two suggestions describe the same finding,
+// from 0 to 1, by the words their titles share and, when both quote the
+// code they change, the overlap of the quoted code. The geometric mean keeps
+// the same title on unrelated code apart.
+func similarity(a, b Suggestion) float64 {
+	titles := overlap(titleWords(a.Title), titleWords(b.Title))
+	if strings.TrimSpace(a.Original) == "" || strings.TrimSpace(b.Original) == "" {
+		return titles
+	}
+
+	code := overlap(words(a.Original), words(b.Original))
+	return math.Sqrt(titles * code)
+}
+
+// titleWords returns the significant words of a title, without the
+// severity label that precedes the summary, e.g. "🟡 Warning:".
+func titleWords(title string) map[string]bool {
+	if label, summary, ok := strings.Cut(title, ":"); ok &&
+		len(strings.Fields(label)) <= 3 {
+		title = summary
+	}
+
+	significant := make(map[string]bool)
+	for word := range words(title) {
+		if !stopWords[word] {
+			significant[word] = true
+		}
+	}
+	return significant
+}
+
+// words returns the set of lowercase words and identifiers in s.
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(
+		strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		},
+	) {
+		set[word] = true
+	}
+	return set
+}
+
+// overlap returns the share of the smaller set found in the larger one, so
+// a terse title matches a detailed one and a line matches its surrounding
+// block.
+func overlap(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	return float64(intersection(a, b)) / float64(min(len(a), len(b)))
+}
+
+// intersection returns the number of elements two sets have in common.
+func intersection(a, b map[string]bool) int {
+	common := 0
+	for element := range a {
+		if b[element] {
+			common++
+		}
+	}
+	return common
+}
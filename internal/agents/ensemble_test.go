@@ -0,0 +1,245 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Suggestion
+		want bool // Similar enough to be merged
+	}{
+		{
+			name: "same issue with different severity and wording",
+			a:    Suggestion{Title: "🟡 Warning: Unchecked error from Close"},
+			b:    Suggestion{Title: "🔴 Critical: Error from Close is unchecked"},
+			want: true,
+		},
+		{
+			name: "terse title matches a detailed one",
+			a:    Suggestion{Title: "💡 Suggestion: Unchecked error"},
+			b:    Suggestion{Title: "🟡 Warning: Unchecked error returned by the deferred Close call"},
+			want: true,
+		},
+		{
+			name: "different issues",
+			a:    Suggestion{Title: "🟡 Warning: Unchecked error"},
+			b:    Suggestion{Title: "💡 Suggestion: Rename variable for clarity"},
+			want: false,
+		},
+		{
+			name: "same title on different code",
+			a: Suggestion{
+				Title:    "🟡 Warning: Unchecked error",
+				Original: "defer f.Close()",
+			},
+			b: Suggestion{
+				Title:    "🟡 Warning: Unchecked error",
+				Original: "json.Unmarshal(data, &cfg)",
+			},
+			want: false,
+		},
+		{
+			name: "related titles on the same code",
+			a: Suggestion{
+				Title:    "🟡 Warning: Ignored error",
+				Original: "defer f.Close()",
+			},
+			b: Suggestion{
+				Title:    "🔴 Critical: Close error is lost",
+				Original: "defer f.Close()",
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				score := similarity(tt.a, tt.b)
+				if got := score >= similarityThreshold; got != tt.want {
+					t.Errorf("similarity() = %.2f, want similar = %v", score, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestMergeEnsemble(t *testing.T) {
+	models := []string{"model-a", "model-b", "model-c"}
+	results := []*ReviewResult{
+		{
+			Suggestions: []Suggestion{
+				{ID: "miso-1A", Title: "🟡 Warning: Rename variable"},
+				{ID: "miso-1B", Title: "🔴 Critical: Unchecked error from Close"},
+			},
+			TokensUsed: 10,
+			Priced:     true,
+		},
+		{
+			Suggestions: []Suggestion{
+				{ID: "miso-1A", Title: "🔴 Critical: Close error is unchecked"},
+			},
+			TokensUsed: 20,
+			Priced:     true,
+		},
+		{
+			Suggestions: []Suggestion{
+				{ID: "miso-1A", Title: "🟡 Warning: Error from Close unchecked"},
+				{ID: "miso-1B", Title: "💡 Suggestion: Add a doc comment"},
+			},
+			TokensUsed: 30,
+			Priced:     true,
+			Cached:     true,
+		},
+	}
+
+	tests := []struct {
+		name         string
+		minAgreement int
+		wantTitles   []string
+		wantIDs      []string
+	}{
+		{
+			name:         "every finding, most agreed first",
+			minAgreement: 1,
+			wantTitles: []string{
+				"🔴 Critical: Unchecked error from Close",
+				"🟡 Warning: Rename variable",
+				"💡 Suggestion: Add a doc comment",
+			},
+			wantIDs: []string{"miso-1B", "miso-1A", "miso-1B-2"},
+		},
+		{
+			name:         "majority",
+			minAgreement: 2,
+			wantTitles:   []string{"🔴 Critical: Unchecked error from Close"},
+			wantIDs:      []string{"miso-1B"},
+		},
+		{
+			name:         "agreement above the ensemble size is capped",
+			minAgreement: 5,
+			wantTitles:   []string{"🔴 Critical: Unchecked error from Close"},
+			wantIDs:      []string{"miso-1B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				merged := mergeEnsemble(models, results, tt.minAgreement)
+
+				var titles, ids []string
+				for _, suggestion := range merged.Suggestions {
+					titles = append(titles, suggestion.Title)
+					ids = append(ids, suggestion.ID)
+				}
+				if strings.Join(titles, "|") != strings.Join(tt.wantTitles, "|") {
+					t.Errorf("mergeEnsemble() titles = %q, want %q", titles, tt.wantTitles)
+				}
+				if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+					t.Errorf("mergeEnsemble() IDs = %v, want %v", ids, tt.wantIDs)
+				}
+
+				top := merged.Suggestions[0]
+				if len(top.ReportedBy) != 3 || top.Ensemble != 3 {
+					t.Errorf(
+						"Expected the top finding reported by 3 of 3 models, got %v of %d",
+						top.ReportedBy, top.Ensemble,
+					)
+				}
+				if merged.TokensUsed != 60 || merged.Cached || !merged.Priced {
+					t.Errorf("Expected the usage summed over the models, got %+v", merged)
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_Ensemble(t *testing.T) {
+	responses := map[string]string{
+		"model-a": `[{"id":"miso-1A","title":"🟡 Warning: Unchecked error","body":"b"}]`,
+		"model-b": `[{"id":"miso-1A","title":"🔴 Critical: Unchecked error","body":"b"},` +
+			`{"id":"miso-1B","title":"💡 Suggestion: Rename variable","body":"b"}]`,
+	}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var req map[string]any
+				json.NewDecoder(r.Body).Decode(&req)
+				model, _ := req["model"].(string)
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":     "chatcmpl-1",
+						"object": "chat.completion",
+						"choices": []map[string]any{
+							{
+								"index":         0,
+								"finish_reason": "stop",
+								"message": map[string]any{
+									"role":    "assistant",
+									"content": responses[model],
+								},
+							},
+						},
+						"usage": map[string]any{
+							"prompt_tokens":     10,
+							"completion_tokens": 5,
+							"total_tokens":      15,
+						},
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderOpenAICompatible,
+			BaseURL:  server.URL,
+			Model:    "local-model",
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Ensemble = config.Ensemble{
+		Models:       []string{"model-a", "model-b"},
+		MinAgreement: 2,
+	}
+
+	var streamed int
+	result, err := reviewer.ReviewStream(
+		context.Background(), cfg, "package main", "main.go",
+		&Stream{Suggestion: func(Suggestion) { streamed++ }},
+	)
+	if err != nil {
+		t.Fatalf("ReviewStream() error = %v", err)
+	}
+
+	if len(result.Suggestions) != 1 {
+		t.Fatalf("Expected the agreed finding only, got %+v", result.Suggestions)
+	}
+	if got := result.Suggestions[0].ReportedBy; strings.Join(got, ",") != "model-a,model-b" {
+		t.Errorf("Expected the finding reported by both models, got %v", got)
+	}
+	if streamed != 0 {
+		t.Errorf("Expected ensemble reviews not to stream, got %d suggestions", streamed)
+	}
+	if result.TokensUsed != 30 {
+		t.Errorf("Expected the usage of both models, got %d tokens", result.TokensUsed)
+	}
+}
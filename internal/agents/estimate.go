@@ -0,0 +1,151 @@
+package agents
+
+import (
+	"fmt"
+
+	"github.com/j0lvera/miso/internal/budget"
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/git"
+	"github.com/j0lvera/miso/internal/prompts"
+)
+
+// defaultOutputEstimate is the response size assumed by estimates when
+// llm.max_tokens doesn't bound it.
+const defaultOutputEstimate = 1024
+
+// Estimate is the expected usage of a review, computed locally before any
+// call is made.
+type Estimate struct {
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Cost         float64 // USD, zero if the model has no price
+	Priced       bool
+	Cached       bool // The response cache already holds every call
+
+	// Further calls of the review, to the other ensemble models and for the
+	// verification; included in Cost
+	Extra []Estimate
+}
+
+// Tokens returns the estimated total tokens of the review, including the
+// further calls.
+func (e Estimate) Tokens() int {
+	tokens := e.InputTokens + e.OutputTokens
+	for _, call := range e.Extra {
+		tokens += call.Tokens()
+	}
+	return tokens
+}
+
+// EstimateReview estimates the usage of reviewing code with Review.
+func (cr *CodeReviewer) EstimateReview(
+	cfg *config.Config, code string, filename string,
+) (Estimate, error) {
+	prompt, err := prompts.CodeReview(cfg, code, filename)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("failed to format prompt: %w", err)
+	}
+
+	return cr.estimate(cfg, prompt, filename)
+}
+
+// EstimateDiff estimates the usage of reviewing a diff with ReviewDiff.
+func (cr *CodeReviewer) EstimateDiff(
+	cfg *config.Config, diffData *git.DiffData, filename string,
+) (Estimate, error) {
+	prompt, err := prompts.DiffReview(cfg, diffData, filename, cr.pr)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("failed to format diff prompt: %w", err)
+	}
+
+	return cr.estimate(cfg, prompt, filename)
+}
+
+// estimate counts the prompt tokens and prices them for the routed model,
+// or each model of the ensemble. The response is assumed to use all of
+// llm.max_tokens, so estimates err on the high side, except for repository
+// tool calls, which can't be predicted.
+func (cr *CodeReviewer) estimate(
+	cfg *config.Config, prompt prompts.Prompt, filename string,
+) (Estimate, error) {
+	settings, err := cr.settingsFor(cfg, filename)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	inputTokens := budget.CountTokens(prompt.String())
+	outputTokens := cr.maxTokens
+	if outputTokens <= 0 {
+		outputTokens = defaultOutputEstimate
+	}
+
+	models := []string{settings.model}
+	if len(settings.ensemble) > 0 {
+		models = settings.ensemble
+	}
+
+	var est Estimate
+	for i, model := range models {
+		call := Estimate{
+			Model:        model,
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+		}
+		callSettings := settings
+		callSettings.model = model
+		if key, ok := cr.cacheKey(prompt.String(), callSettings); ok && cr.cache != nil {
+			call.Cached = cr.cache.Has(key)
+		}
+		call.Cost, call.Priced = cr.estimateCost(
+			cfg, model, inputTokens, outputTokens,
+		)
+
+		if i == 0 {
+			est = call
+		} else {
+			est.addCall(call)
+		}
+	}
+
+	if cfg.Verify.Enabled {
+		// The verification call resends the reviewed code along with the
+		// suggestions and answers with a score for each
+		verification := Estimate{
+			Model:        settings.model,
+			InputTokens:  inputTokens + outputTokens,
+			OutputTokens: outputTokens,
+		}
+		if cfg.Verify.Model != "" {
+			verification.Model = cfg.Verify.Model
+		}
+		verification.Cost, verification.Priced = cr.estimateCost(
+			cfg, verification.Model, verification.InputTokens,
+			verification.OutputTokens,
+		)
+		est.addCall(verification)
+	}
+
+	return est, nil
+}
+
+// addCall adds a further call of the review to the estimate.
+func (e *Estimate) addCall(call Estimate) {
+	e.Extra = append(e.Extra, call)
+	e.Cost += call.Cost
+	e.Priced = e.Priced && call.Priced
+	e.Cached = e.Cached && call.Cached
+}
+
+// estimateCost prices the estimated usage of a call to model.
+func (cr *CodeReviewer) estimateCost(
+	cfg *config.Config, model string, inputTokens, outputTokens int,
+) (float64, bool) {
+	result := ReviewResult{
+		Model:        model,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+	}
+	cr.applyCost(cfg, &result)
+	return result.Cost, result.Priced
+}
@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestCodeReviewer_EstimateReview(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+
+	tests := []struct {
+		name       string
+		maxTokens  int
+		wantOutput int
+	}{
+		{"default output estimate", 0, defaultOutputEstimate},
+		{"bounded by max tokens", 500, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				reviewer, err := NewCodeReviewer(
+					config.LLM{
+						Provider:  config.ProviderOpenAI,
+						Model:     "gpt-4o",
+						MaxTokens: tt.maxTokens,
+					},
+				)
+				if err != nil {
+					t.Fatalf("NewCodeReviewer() error = %v", err)
+				}
+
+				est, err := reviewer.EstimateReview(
+					config.DefaultConfig(), "package main\n\nfunc main() {}\n",
+					"main.go",
+				)
+				if err != nil {
+					t.Fatalf("EstimateReview() error = %v", err)
+				}
+
+				if est.InputTokens <= 0 {
+					t.Errorf("Expected input tokens, got %d", est.InputTokens)
+				}
+				if est.OutputTokens != tt.wantOutput {
+					t.Errorf("OutputTokens = %d, want %d", est.OutputTokens, tt.wantOutput)
+				}
+				if !est.Priced || est.Cost <= 0 {
+					t.Errorf("Expected a priced estimate, got %+v", est)
+				}
+			},
+		)
+	}
+}
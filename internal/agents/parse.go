@@ -0,0 +1,173 @@
+package agents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errNoJSON is returned when a response contains no JSON suggestions at all.
+var errNoJSON = errors.New("no JSON array of suggestions found")
+
+// reviewEnvelope is the object shape produced by structured output, where
+// the schema root must be an object rather than an array.
+type reviewEnvelope struct {
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// parseSuggestions extracts the suggestions from an LLM response.
+// It accepts a bare JSON array, a {"suggestions": [...]} object, either one
+// wrapped in prose or markdown fences, and a truncated array, in which case
+// the complete suggestions are returned and truncated is true. Suggestions
+// with invalid typed fields fail the parse, so the repair loop can fix them.
+func parseSuggestions(content string) (
+	suggestions []Suggestion, truncated bool, err error,
+) {
+	suggestions, truncated, err = decodeResponse(content)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := normalizeSuggestions(suggestions); err != nil {
+		return nil, false, err
+	}
+	return suggestions, truncated, nil
+}
+
+// parseSuggestionsLenient is parseSuggestions for the last repair attempt:
+// invalid typed fields are replaced by defaults rather than failing the whole
+// response, and warnings describe each replacement.
+func parseSuggestionsLenient(content string) (
+	suggestions []Suggestion, truncated bool, warnings []string, err error,
+) {
+	suggestions, truncated, err = decodeResponse(content)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	for i := range suggestions {
+		for _, replaced := range suggestions[i].coerce() {
+			warnings = append(
+				warnings, fmt.Sprintf(
+					"suggestion %s: %s replaced by the default",
+					suggestions[i].ID, replaced,
+				),
+			)
+		}
+	}
+	if err := normalizeSuggestions(suggestions); err != nil {
+		return nil, false, nil, err
+	}
+	return suggestions, truncated, warnings, nil
+}
+
+// decodeResponse finds and decodes the suggestions of a response, without
+// validating them.
+func decodeResponse(content string) (
+	suggestions []Suggestion, truncated bool, err error,
+) {
+	trimmed := strings.TrimSpace(content)
+
+	// Fast path: the whole response is the JSON value
+	if suggestions, ok := decodeSuggestions([]byte(trimmed)); ok {
+		return suggestions, false, nil
+	}
+
+	// Look for the first complete JSON value that holds suggestions.
+	// Prose may contain brackets, so every candidate start is tried.
+	var firstErr error
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] != '[' && trimmed[i] != '{' {
+			continue
+		}
+
+		dec := json.NewDecoder(strings.NewReader(trimmed[i:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if suggestions, ok := decodeSuggestions(raw); ok {
+			return suggestions, false, nil
+		}
+	}
+
+	// No complete value: try to salvage a truncated array
+	if suggestions, ok := recoverTruncated(trimmed); ok {
+		return suggestions, true, nil
+	}
+
+	if firstErr != nil {
+		return nil, false, fmt.Errorf("%w: %v", errNoJSON, firstErr)
+	}
+	return nil, false, errNoJSON
+}
+
+// decodeSuggestions decodes data as an array of suggestions or as an envelope.
+func decodeSuggestions(data []byte) ([]Suggestion, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, false
+	}
+
+	switch data[0] {
+	case '[':
+		var suggestions []Suggestion
+		if err := json.Unmarshal(data, &suggestions); err != nil {
+			return nil, false
+		}
+		if suggestions == nil {
+			suggestions = []Suggestion{}
+		}
+		return suggestions, true
+	case '{':
+		var envelope reviewEnvelope
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if err := dec.Decode(&envelope); err != nil || envelope.Suggestions == nil {
+			return nil, false
+		}
+		return envelope.Suggestions, true
+	}
+
+	return nil, false
+}
+
+// recoverTruncated decodes the complete objects of an array that was cut off,
+// for example when the model hit its max tokens. It reports false when no
+// suggestion could be recovered.
+func recoverTruncated(content string) ([]Suggestion, bool) {
+	// Prefer the array inside an envelope, otherwise the first array
+	start := -1
+	if idx := strings.Index(content, `"suggestions"`); idx != -1 {
+		if rel := strings.Index(content[idx:], "["); rel != -1 {
+			start = idx + rel
+		}
+	}
+	if start == -1 {
+		start = strings.Index(content, "[")
+	}
+	if start == -1 {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(strings.NewReader(content[start:]))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, false
+	}
+
+	var suggestions []Suggestion
+	for dec.More() {
+		var suggestion Suggestion
+		if err := dec.Decode(&suggestion); err != nil {
+			break
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, len(suggestions) > 0
+}
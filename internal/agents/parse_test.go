@@ -0,0 +1,124 @@
+package agents
+
+import (
+	"testing"
+)
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantIDs       []string
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{
+			name:    "bare array",
+			content: `[{"id":"miso-1A","title":"t","body":"b"}]`,
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name:    "empty array",
+			content: `[]`,
+			wantIDs: []string{},
+		},
+		{
+			name:    "structured envelope",
+			content: `{"suggestions":[{"id":"miso-1A","title":"t","body":"b","original":"","suggestion":""}]}`,
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name: "prose with brackets around the array",
+			content: "Looking at items [1] and [2], here is the review:\n" +
+				"```json\n" +
+				`[{"id":"miso-1A","title":"Use a[i]","body":"Index [i] is unchecked"}]` +
+				"\n```\nLet me know [if] anything is unclear.",
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name: "truncated array keeps complete suggestions",
+			content: `[{"id":"miso-1A","title":"t","body":"b"},` +
+				`{"id":"miso-1B","title":"t","body":"b"},` +
+				`{"id":"miso-1C","title":"t","bo`,
+			wantIDs:       []string{"miso-1A", "miso-1B"},
+			wantTruncated: true,
+		},
+		{
+			name: "truncated envelope",
+			content: `{"suggestions":[{"id":"miso-1A","title":"t","body":"b"},` +
+				`{"id":"miso-1B","title":"`,
+			wantIDs:       []string{"miso-1A"},
+			wantTruncated: true,
+		},
+		{
+			name:    "truncated before the first suggestion completes",
+			content: `[{"id":"miso-1A","title":"t`,
+			wantErr: true,
+		},
+		{
+			name:    "no JSON",
+			content: "I could not review this file.",
+			wantErr: true,
+		},
+		{
+			name: "typed fields",
+			content: `[{"id":"miso-1A","title":"t","body":"b","severity":"Critical",` +
+				`"category":"security","start_line":3,"end_line":5,"confidence":0.8}]`,
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name:    "invalid severity",
+			content: `[{"id":"miso-1A","title":"t","body":"b","severity":"blocker"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "inverted line range",
+			content: `[{"id":"miso-1A","title":"t","body":"b","start_line":9,"end_line":3}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `[{"id": "miso-1A", "title": "unterminated}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				suggestions, truncated, err := parseSuggestions(tt.content)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf(
+						"parseSuggestions() error = %v, wantErr %v", err,
+						tt.wantErr,
+					)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				if truncated != tt.wantTruncated {
+					t.Errorf(
+						"parseSuggestions() truncated = %v, want %v",
+						truncated, tt.wantTruncated,
+					)
+				}
+
+				if len(suggestions) != len(tt.wantIDs) {
+					t.Fatalf(
+						"parseSuggestions() returned %d suggestions, want %d",
+						len(suggestions), len(tt.wantIDs),
+					)
+				}
+				for i, id := range tt.wantIDs {
+					if suggestions[i].ID != id {
+						t.Errorf(
+							"suggestion %d ID = %s, want %s", i,
+							suggestions[i].ID, id,
+						)
+					}
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,100 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+// defaultPrices lists the prices of common review models in USD per million
+// tokens. Models are listed under both their OpenRouter and native names.
+// Prices change; override them with the pricing section of the config.
+var defaultPrices = map[string]config.ModelPrice{
+	// Anthropic
+	"anthropic/claude-3.5-sonnet": {Input: 3, Output: 15},
+	"anthropic/claude-3.7-sonnet": {Input: 3, Output: 15},
+	"anthropic/claude-sonnet-4":   {Input: 3, Output: 15},
+	"anthropic/claude-3.5-haiku":  {Input: 0.8, Output: 4},
+	"anthropic/claude-3-haiku":    {Input: 0.25, Output: 1.25},
+	"anthropic/claude-opus-4":     {Input: 15, Output: 75},
+	"claude-3-5-sonnet-latest":    {Input: 3, Output: 15},
+	"claude-3-7-sonnet-latest":    {Input: 3, Output: 15},
+	"claude-sonnet-4-0":           {Input: 3, Output: 15},
+	"claude-3-5-haiku-latest":     {Input: 0.8, Output: 4},
+	"claude-opus-4-0":             {Input: 15, Output: 75},
+
+	// OpenAI
+	"openai/gpt-4o":       {Input: 2.5, Output: 10},
+	"openai/gpt-4o-mini":  {Input: 0.15, Output: 0.6},
+	"openai/gpt-4.1":      {Input: 2, Output: 8},
+	"openai/gpt-4.1-mini": {Input: 0.4, Output: 1.6},
+	"openai/o3-mini":      {Input: 1.1, Output: 4.4},
+	"gpt-4o":              {Input: 2.5, Output: 10},
+	"gpt-4o-mini":         {Input: 0.15, Output: 0.6},
+	"gpt-4.1":             {Input: 2, Output: 8},
+	"gpt-4.1-mini":        {Input: 0.4, Output: 1.6},
+	"o3-mini":             {Input: 1.1, Output: 4.4},
+
+	// Google
+	"google/gemini-2.5-pro":   {Input: 1.25, Output: 10},
+	"google/gemini-2.5-flash": {Input: 0.3, Output: 2.5},
+}
+
+// Pricing computes the cost of LLM calls from their token usage.
+type Pricing struct {
+	prices map[string]config.ModelPrice
+}
+
+// NewPricing creates a pricing table from the defaults and the given overrides.
+// Overrides replace the default price of a model or add new models.
+func NewPricing(overrides map[string]config.ModelPrice) *Pricing {
+	prices := make(map[string]config.ModelPrice, len(defaultPrices)+len(overrides))
+	for model, price := range defaultPrices {
+		prices[model] = price
+	}
+	for model, price := range overrides {
+		prices[model] = price
+	}
+	return &Pricing{prices: prices}
+}
+
+// Price returns the price of a model. Models routed through OpenRouter are
+// also looked up without their vendor prefix, and vice versa.
+func (p *Pricing) Price(model string) (config.ModelPrice, bool) {
+	if price, ok := p.prices[model]; ok {
+		return price, true
+	}
+
+	// "vendor/model" -> "model"
+	if idx := strings.Index(model, "/"); idx != -1 {
+		if price, ok := p.prices[model[idx+1:]]; ok {
+			return price, true
+		}
+		return config.ModelPrice{}, false
+	}
+
+	// "model" -> "vendor/model", the first vendor in order if several match
+	var prefixed []string
+	for name := range p.prices {
+		if strings.HasSuffix(name, "/"+model) {
+			prefixed = append(prefixed, name)
+		}
+	}
+	if len(prefixed) > 0 {
+		return p.prices[slices.Min(prefixed)], true
+	}
+
+	return config.ModelPrice{}, false
+}
+
+// Cost returns the cost in USD of a call and whether the model has a price.
+func (p *Pricing) Cost(model string, inputTokens, outputTokens int) (float64, bool) {
+	price, ok := p.Price(model)
+	if !ok {
+		return 0, false
+	}
+
+	return (float64(inputTokens)*price.Input +
+		float64(outputTokens)*price.Output) / 1_000_000, true
+}
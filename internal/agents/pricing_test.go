@@ -0,0 +1,123 @@
+package agents
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestPricing_Cost(t *testing.T) {
+	pricing := NewPricing(
+		map[string]config.ModelPrice{
+			"gpt-4o":       {Input: 5, Output: 20},
+			"custom/model": {Input: 1, Output: 2},
+		},
+	)
+
+	tests := []struct {
+		name       string
+		model      string
+		input      int
+		output     int
+		wantCost   float64
+		wantPriced bool
+	}{
+		{
+			name:       "default price",
+			model:      "anthropic/claude-3.5-sonnet",
+			input:      1_000_000,
+			output:     100_000,
+			wantCost:   4.5,
+			wantPriced: true,
+		},
+		{
+			name:       "override replaces default",
+			model:      "gpt-4o",
+			input:      1_000,
+			output:     1_000,
+			wantCost:   0.025,
+			wantPriced: true,
+		},
+		{
+			name:       "vendor prefix falls back to the native name",
+			model:      "openai/gpt-4o",
+			input:      1_000_000,
+			wantCost:   2.5,
+			wantPriced: true,
+		},
+		{
+			name:       "native name falls back to the vendor prefix",
+			model:      "gemini-2.5-pro",
+			input:      1_000_000,
+			wantCost:   1.25,
+			wantPriced: true,
+		},
+		{
+			name:       "added model",
+			model:      "custom/model",
+			input:      500_000,
+			output:     500_000,
+			wantCost:   1.5,
+			wantPriced: true,
+		},
+		{
+			name:       "unknown model",
+			model:      "unknown/model",
+			input:      1_000,
+			wantPriced: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cost, priced := pricing.Cost(tt.model, tt.input, tt.output)
+				if priced != tt.wantPriced {
+					t.Errorf("Cost() priced = %v, want %v", priced, tt.wantPriced)
+				}
+				if math.Abs(cost-tt.wantCost) > 1e-9 {
+					t.Errorf("Cost() = %g, want %g", cost, tt.wantCost)
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_ReviewCost(t *testing.T) {
+	var lastModel string
+	server := newOpenAIStub(t, &lastModel)
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.LLM = config.LLM{
+		Provider: config.ProviderOpenAICompatible,
+		BaseURL:  server.URL,
+		Model:    "local-model",
+	}
+	cfg.Pricing = map[string]config.ModelPrice{
+		"local-model": {Input: 1, Output: 10},
+	}
+
+	reviewer, err := NewCodeReviewer(cfg.LLM)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), cfg, "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	// 120 input tokens at $1/M plus 30 output tokens at $10/M
+	want := (120*1.0 + 30*10.0) / 1_000_000
+	if !result.Priced || math.Abs(result.Cost-want) > 1e-12 {
+		t.Errorf(
+			"Expected priced cost %g, got %g (priced=%v)", want, result.Cost,
+			result.Priced,
+		)
+	}
+}
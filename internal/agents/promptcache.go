@@ -0,0 +1,209 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// promptCacheUsage counts the prompt tokens that the provider read from its
+// prompt cache during a call.
+type promptCacheUsage struct {
+	tokens atomic.Int64
+}
+
+type promptCacheUsageKey struct{}
+
+// withPromptCacheUsage returns a context whose requests report their cached
+// prompt tokens to the returned usage.
+func withPromptCacheUsage(ctx context.Context) (context.Context, *promptCacheUsage) {
+	usage := &promptCacheUsage{}
+	return context.WithValue(ctx, promptCacheUsageKey{}, usage), usage
+}
+
+// cacheControl marks the system prompt of a request body as cacheable and
+// reports whether it changed anything.
+type cacheControl func(body map[string]any) bool
+
+// promptCacheTransport marks the system prompt of requests as cacheable for
+// providers that only cache marked prompts, and reads the cached prompt
+// tokens of the responses. Providers that cache on their own, like OpenAI,
+// need no marking.
+type promptCacheTransport struct {
+	base http.RoundTripper
+	mark cacheControl // Nil when the provider needs no marking
+}
+
+// RoundTrip marks the request body and wraps the response body to count its
+// cached prompt tokens once it has been read.
+func (t *promptCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mark != nil && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		body = markCacheable(body, t.mark)
+
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, _ := req.Context().Value(promptCacheUsageKey{}).(*promptCacheUsage)
+	if usage != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		resp.Body = &usageReader{ReadCloser: resp.Body, usage: usage}
+	}
+	return resp, nil
+}
+
+// markCacheable applies mark to a JSON request body. Bodies that aren't JSON
+// objects, or that mark leaves alone, are sent as they are.
+func markCacheable(body []byte, mark cacheControl) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var request map[string]any
+	if err := decoder.Decode(&request); err != nil || !mark(request) {
+		return body
+	}
+
+	marked, err := json.Marshal(request)
+	if err != nil {
+		return body
+	}
+	return marked
+}
+
+// ephemeral is the cache_control of cacheable content blocks.
+var ephemeral = map[string]any{"type": "ephemeral"}
+
+// cacheableText returns text as a content block marked as cacheable.
+func cacheableText(text string) []any {
+	return []any{
+		map[string]any{"type": "text", "text": text, "cache_control": ephemeral},
+	}
+}
+
+// markAnthropicSystem marks the system prompt of an Anthropic Messages API
+// request as cacheable.
+func markAnthropicSystem(body map[string]any) bool {
+	system, ok := body["system"].(string)
+	if !ok || system == "" {
+		return false
+	}
+	body["system"] = cacheableText(system)
+	return true
+}
+
+// markOpenRouterSystem marks the system messages of an OpenRouter chat
+// request to an Anthropic model as cacheable; OpenRouter passes the marks on
+// to Anthropic, which only caches marked prompts.
+func markOpenRouterSystem(body map[string]any) bool {
+	if model, _ := body["model"].(string); !strings.HasPrefix(model, "anthropic/") {
+		return false
+	}
+
+	marked := false
+	messages, _ := body["messages"].([]any)
+	for _, m := range messages {
+		message, _ := m.(map[string]any)
+		content, ok := message["content"].(string)
+		if message["role"] != "system" || !ok || content == "" {
+			continue
+		}
+		message["content"] = cacheableText(content)
+		marked = true
+	}
+	return marked
+}
+
+// usageReader passes a response body through, counting the cached prompt
+// tokens of the response once it has been read or closed.
+type usageReader struct {
+	io.ReadCloser
+	usage   *promptCacheUsage
+	payload bytes.Buffer
+	once    sync.Once
+}
+
+// Read reads from the response body, keeping a copy of the payload.
+func (r *usageReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.payload.Write(p[:n])
+	if err == io.EOF {
+		r.once.Do(r.count)
+	}
+	return n, err
+}
+
+// Close counts the cached tokens of what was read and closes the body.
+func (r *usageReader) Close() error {
+	r.once.Do(r.count)
+	return r.ReadCloser.Close()
+}
+
+// count adds the cached prompt tokens of the payload to the usage. Streamed
+// payloads are server-sent events, of which the usage is in one or more.
+func (r *usageReader) count() {
+	payload := bytes.TrimSpace(r.payload.Bytes())
+	if bytes.HasPrefix(payload, []byte("{")) {
+		r.usage.tokens.Add(int64(cachedTokens(payload)))
+		return
+	}
+
+	tokens := 0
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+			tokens = max(tokens, cachedTokens(bytes.TrimSpace(data)))
+		}
+	}
+	r.usage.tokens.Add(int64(tokens))
+}
+
+// cacheUsage holds the cached prompt tokens of a response's usage.
+// OpenAI-style providers (OpenRouter, OpenAI) report
+// prompt_tokens_details.cached_tokens, Anthropic cache_read_input_tokens.
+type cacheUsage struct {
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens"`
+}
+
+// tokens returns the cached prompt tokens of the usage.
+func (u *cacheUsage) tokens() int {
+	if u == nil {
+		return 0
+	}
+	return max(u.PromptTokensDetails.CachedTokens, u.CacheReadInputTokens)
+}
+
+// cachedTokens returns the cached prompt tokens of a response, or of the
+// message_start event of a streamed Anthropic response.
+func cachedTokens(data []byte) int {
+	var response struct {
+		Usage   *cacheUsage `json:"usage"`
+		Message struct {
+			Usage *cacheUsage `json:"usage"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return 0
+	}
+	return max(response.Usage.tokens(), response.Message.Usage.tokens())
+}
@@ -0,0 +1,196 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestMarkCacheable(t *testing.T) {
+	tests := []struct {
+		name string
+		mark cacheControl
+		body string
+		want string
+	}{
+		{
+			name: "openrouter anthropic model",
+			mark: markOpenRouterSystem,
+			body: `{"model":"anthropic/claude-sonnet-4","messages":[{"role":"system","content":"guides"},{"role":"user","content":"code"}]}`,
+			want: `{"messages":[{"content":[{"cache_control":{"type":"ephemeral"},"text":"guides","type":"text"}],"role":"system"},{"content":"code","role":"user"}],"model":"anthropic/claude-sonnet-4"}`,
+		},
+		{
+			name: "openrouter model caching on its own",
+			mark: markOpenRouterSystem,
+			body: `{"model":"openai/gpt-4.1","messages":[{"role":"system","content":"guides"}]}`,
+			want: `{"model":"openai/gpt-4.1","messages":[{"role":"system","content":"guides"}]}`,
+		},
+		{
+			name: "anthropic system prompt",
+			mark: markAnthropicSystem,
+			body: `{"model":"claude-sonnet-4-0","max_tokens":1024,"system":"guides"}`,
+			want: `{"max_tokens":1024,"model":"claude-sonnet-4-0","system":[{"cache_control":{"type":"ephemeral"},"text":"guides","type":"text"}]}`,
+		},
+		{
+			name: "not json",
+			mark: markAnthropicSystem,
+			body: `system=guides`,
+			want: `system=guides`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := string(markCacheable([]byte(tt.body), tt.mark)); got != tt.want {
+					t.Errorf("markCacheable() = %s, want %s", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestCachedTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    int
+	}{
+		{
+			name:    "openai usage",
+			payload: `{"usage":{"prompt_tokens":2000,"prompt_tokens_details":{"cached_tokens":1536}}}`,
+			want:    1536,
+		},
+		{
+			name:    "anthropic usage",
+			payload: `{"usage":{"input_tokens":50,"cache_read_input_tokens":1800}}`,
+			want:    1800,
+		},
+		{
+			name: "openai stream",
+			payload: "data: {\"choices\":[{\"delta\":{\"content\":\"[]\"}}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens_details\":{\"cached_tokens\":1024}}}\n\n" +
+				"data: [DONE]\n\n",
+			want: 1024,
+		},
+		{
+			name: "anthropic stream",
+			payload: "event: message_start\n" +
+				"data: {\"type\":\"message_start\",\"message\":{\"usage\":{\"cache_read_input_tokens\":1800}}}\n\n" +
+				"event: message_delta\n" +
+				"data: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":20,\"cache_read_input_tokens\":1800}}\n\n",
+			want: 1800,
+		},
+		{
+			name:    "no usage",
+			payload: `{"choices":[]}`,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, usage := withPromptCacheUsage(context.Background())
+				reader := &usageReader{
+					ReadCloser: http.NoBody,
+					usage:      usage,
+				}
+				reader.payload.WriteString(tt.payload)
+				reader.Close()
+
+				if got := int(usage.tokens.Load()); got != tt.want {
+					t.Errorf("cached tokens = %d, want %d", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_PromptCache(t *testing.T) {
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+
+	var request struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":     "chatcmpl-1",
+						"object": "chat.completion",
+						"choices": []map[string]any{
+							{
+								"index":         0,
+								"finish_reason": "stop",
+								"message": map[string]any{
+									"role":    "assistant",
+									"content": stubSuggestions,
+								},
+							},
+						},
+						"usage": map[string]any{
+							"prompt_tokens":     2000,
+							"completion_tokens": 30,
+							"total_tokens":      2030,
+							"prompt_tokens_details": map[string]any{
+								"cached_tokens": 1800,
+							},
+						},
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderOpenRouter,
+			BaseURL:  server.URL,
+			Model:    "anthropic/claude-sonnet-4",
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if result.CachedTokens != 1800 {
+		t.Errorf("Expected 1800 cached tokens, got %d", result.CachedTokens)
+	}
+
+	// The instructions are sent as a cacheable system prompt, the code after
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" {
+		t.Fatalf("Expected a system and a user message, got %+v", request.Messages)
+	}
+	var system []map[string]any
+	if err := json.Unmarshal(request.Messages[0].Content, &system); err != nil ||
+		len(system) != 1 || system[0]["cache_control"] == nil {
+		t.Errorf("Expected the system prompt marked as cacheable, got %s", request.Messages[0].Content)
+	}
+	var user string
+	if err := json.Unmarshal(request.Messages[1].Content, &user); err != nil ||
+		user == "" {
+		t.Errorf("Expected the code in the user message, got %s", request.Messages[1].Content)
+	}
+}
@@ -0,0 +1,224 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// placeholderAPIKey is sent to OpenAI-compatible endpoints that don't require
+// authentication, since the client refuses to start without a token.
+const placeholderAPIKey = "not-needed"
+
+// ErrMissingAPIKey is returned when the provider requires an API key and its
+// environment variable is empty.
+var ErrMissingAPIKey = errors.New("missing API key")
+
+// providerDefaults holds the fallback settings for a provider.
+type providerDefaults struct {
+	baseURL   string
+	model     string
+	apiKeyEnv string
+}
+
+var defaultsByProvider = map[string]providerDefaults{
+	config.ProviderOpenRouter: {
+		baseURL:   "https://openrouter.ai/api/v1",
+		model:     "anthropic/claude-3.5-sonnet",
+		apiKeyEnv: "OPENROUTER_API_KEY",
+	},
+	config.ProviderOpenAI: {
+		baseURL:   "https://api.openai.com/v1",
+		model:     "gpt-4o",
+		apiKeyEnv: "OPENAI_API_KEY",
+	},
+	config.ProviderAnthropic: {
+		baseURL:   "https://api.anthropic.com/v1",
+		model:     "claude-3-5-sonnet-latest",
+		apiKeyEnv: "ANTHROPIC_API_KEY",
+	},
+	config.ProviderOllama: {
+		baseURL: "http://localhost:11434",
+		model:   "llama3.1",
+	},
+	config.ProviderOpenAICompatible: {
+		apiKeyEnv: "OPENAI_API_KEY",
+	},
+}
+
+// cacheControlByProvider marks the system prompt of requests as cacheable,
+// for providers that only cache marked prompts.
+var cacheControlByProvider = map[string]cacheControl{
+	config.ProviderOpenRouter: markOpenRouterSystem,
+	config.ProviderAnthropic:  markAnthropicSystem,
+}
+
+// resolveLLMConfig fills empty provider settings with the provider's defaults.
+func resolveLLMConfig(cfg config.LLM) (config.LLM, error) {
+	if cfg.Provider == "" {
+		cfg.Provider = config.ProviderOpenRouter
+	}
+
+	defaults, ok := defaultsByProvider[cfg.Provider]
+	if !ok {
+		return cfg, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaults.baseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaults.model
+	}
+	if cfg.APIKeyEnv == "" {
+		cfg.APIKeyEnv = defaults.apiKeyEnv
+	}
+
+	if cfg.BaseURL == "" {
+		return cfg, fmt.Errorf("provider %s requires a base URL", cfg.Provider)
+	}
+	if cfg.Model == "" {
+		return cfg, fmt.Errorf("provider %s requires a model", cfg.Provider)
+	}
+
+	return cfg, nil
+}
+
+// newModel creates the langchaingo model for the configured provider.
+// The cfg must already have been resolved with resolveLLMConfig.
+func newModel(cfg config.LLM) (llms.Model, error) {
+	apiKey := ""
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
+	}
+
+	// Retry transient failures for every provider
+	client := &http.Client{
+		Transport: newRetryTransport(
+			http.DefaultTransport, cfg.MaxRetries, cfg.RetryDelay, cfg.Timeout,
+		),
+	}
+
+	mode, err := LLMMode()
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		// Cassettes hold the final responses, after any retries
+		client.Transport = &cassetteTransport{
+			base: client.Transport,
+			mode: mode,
+			dir:  CassetteDir(),
+		}
+	}
+
+	// Count the cached prompt tokens of every call, including replayed ones,
+	// and mark the prompts of providers that only cache marked prompts
+	client.Transport = &promptCacheTransport{
+		base: client.Transport,
+		mark: cacheControlByProvider[cfg.Provider],
+	}
+
+	if mode == LLMModeReplay && apiKey == "" {
+		// Replayed calls never reach the provider
+		apiKey = placeholderAPIKey
+	}
+
+	// Enforce the review schema on OpenAI-style endpoints that support it
+	var openaiOptions []openai.Option
+	if structuredModeFor(cfg) == structuredResponseFormat {
+		openaiOptions = append(
+			openaiOptions, openai.WithResponseFormat(reviewResponseFormat()),
+		)
+	}
+
+	switch cfg.Provider {
+	case config.ProviderOpenRouter:
+		if apiKey == "" {
+			return nil, missingAPIKey(cfg.APIKeyEnv)
+		}
+
+		// Set custom headers for OpenRouter
+		client.Transport = &headerTransport{
+			base: client.Transport,
+			headers: map[string]string{
+				"HTTP-Referer": website,
+				"X-Title":      name,
+			},
+		}
+
+		return openai.New(
+			append(
+				openaiOptions,
+				openai.WithToken(apiKey),
+				openai.WithBaseURL(cfg.BaseURL),
+				openai.WithModel(cfg.Model),
+				openai.WithHTTPClient(client),
+			)...,
+		)
+
+	case config.ProviderOpenAI:
+		if apiKey == "" {
+			return nil, missingAPIKey(cfg.APIKeyEnv)
+		}
+
+		return openai.New(
+			append(
+				openaiOptions,
+				openai.WithToken(apiKey),
+				openai.WithBaseURL(cfg.BaseURL),
+				openai.WithModel(cfg.Model),
+				openai.WithHTTPClient(client),
+			)...,
+		)
+
+	case config.ProviderOpenAICompatible:
+		// Self-hosted endpoints often don't check the key at all
+		if apiKey == "" {
+			apiKey = placeholderAPIKey
+		}
+
+		return openai.New(
+			append(
+				openaiOptions,
+				openai.WithToken(apiKey),
+				openai.WithBaseURL(cfg.BaseURL),
+				openai.WithModel(cfg.Model),
+				openai.WithHTTPClient(client),
+			)...,
+		)
+
+	case config.ProviderAnthropic:
+		if apiKey == "" {
+			return nil, missingAPIKey(cfg.APIKeyEnv)
+		}
+
+		return anthropic.New(
+			anthropic.WithToken(apiKey),
+			anthropic.WithBaseURL(cfg.BaseURL),
+			anthropic.WithModel(cfg.Model),
+			anthropic.WithHTTPClient(client),
+		)
+
+	case config.ProviderOllama:
+		return ollama.New(
+			ollama.WithServerURL(cfg.BaseURL),
+			ollama.WithModel(cfg.Model),
+			ollama.WithHTTPClient(client),
+		)
+	}
+
+	return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+}
+
+// missingAPIKey returns ErrMissingAPIKey naming the variable to set.
+func missingAPIKey(env string) error {
+	return fmt.Errorf("%w: %s environment variable is not set", ErrMissingAPIKey, env)
+}
@@ -0,0 +1,295 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+const stubSuggestions = `[{"id":"miso-1A","title":"🟡 Warning: Unchecked error","body":"Check the error."}]`
+
+// newOpenAIStub returns a server that answers chat completions in the
+// OpenAI format and records the model of the last request.
+func newOpenAIStub(t *testing.T, lastModel *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Model string `json:"model"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				*lastModel = req.Model
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":     "chatcmpl-1",
+						"object": "chat.completion",
+						"model":  req.Model,
+						"choices": []map[string]any{
+							{
+								"index":         0,
+								"finish_reason": "stop",
+								"message": map[string]any{
+									"role":    "assistant",
+									"content": stubSuggestions,
+								},
+							},
+						},
+						"usage": map[string]any{
+							"prompt_tokens":     120,
+							"completion_tokens": 30,
+							"total_tokens":      150,
+						},
+					},
+				)
+			},
+		),
+	)
+}
+
+// newScriptedStub returns an OpenAI-format server that answers with the
+// given contents in order, repeating the last one, and records each request.
+func newScriptedStub(
+	t *testing.T, contents []string, requests *[]map[string]any,
+) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var req map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+
+				mu.Lock()
+				*requests = append(*requests, req)
+				content := contents[min(len(*requests), len(contents))-1]
+				mu.Unlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":     "chatcmpl-1",
+						"object": "chat.completion",
+						"choices": []map[string]any{
+							{
+								"index":         0,
+								"finish_reason": "stop",
+								"message": map[string]any{
+									"role":    "assistant",
+									"content": content,
+								},
+							},
+						},
+						"usage": map[string]any{
+							"prompt_tokens":     10,
+							"completion_tokens": 5,
+							"total_tokens":      15,
+						},
+					},
+				)
+			},
+		),
+	)
+}
+
+func TestResolveLLMConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.LLM
+		wantModel string
+		wantURL   string
+		wantErr   bool
+	}{
+		{
+			name:      "openrouter defaults",
+			cfg:       config.LLM{Provider: config.ProviderOpenRouter},
+			wantModel: "anthropic/claude-3.5-sonnet",
+			wantURL:   "https://openrouter.ai/api/v1",
+		},
+		{
+			name:      "empty provider falls back to openrouter",
+			cfg:       config.LLM{},
+			wantModel: "anthropic/claude-3.5-sonnet",
+			wantURL:   "https://openrouter.ai/api/v1",
+		},
+		{
+			name: "explicit model wins",
+			cfg: config.LLM{
+				Provider: config.ProviderOpenAI, Model: "gpt-4o-mini",
+			},
+			wantModel: "gpt-4o-mini",
+			wantURL:   "https://api.openai.com/v1",
+		},
+		{
+			name:    "openai-compatible requires base url",
+			cfg:     config.LLM{Provider: config.ProviderOpenAICompatible, Model: "m"},
+			wantErr: true,
+		},
+		{
+			name: "openai-compatible requires model",
+			cfg: config.LLM{
+				Provider: config.ProviderOpenAICompatible,
+				BaseURL:  "http://localhost:8000/v1",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown provider",
+			cfg:     config.LLM{Provider: "unknown"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := resolveLLMConfig(tt.cfg)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf(
+						"resolveLLMConfig() error = %v, wantErr %v", err,
+						tt.wantErr,
+					)
+				}
+				if tt.wantErr {
+					return
+				}
+				if got.Model != tt.wantModel {
+					t.Errorf("Model = %s, want %s", got.Model, tt.wantModel)
+				}
+				if got.BaseURL != tt.wantURL {
+					t.Errorf("BaseURL = %s, want %s", got.BaseURL, tt.wantURL)
+				}
+			},
+		)
+	}
+}
+
+func TestNewCodeReviewer_APIKeyEnv(t *testing.T) {
+	t.Setenv("MISO_TEST_KEY", "")
+
+	_, err := NewCodeReviewer(
+		config.LLM{
+			Provider:  config.ProviderOpenAI,
+			APIKeyEnv: "MISO_TEST_KEY",
+		},
+	)
+	if err == nil {
+		t.Fatal("Expected error when the custom API key env var is empty")
+	}
+	if !strings.Contains(err.Error(), "MISO_TEST_KEY") {
+		t.Errorf("Error should mention MISO_TEST_KEY, got: %v", err)
+	}
+}
+
+func TestCodeReviewer_OpenAICompatibleStub(t *testing.T) {
+	var lastModel string
+	server := newOpenAIStub(t, &lastModel)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider:    config.ProviderOpenAICompatible,
+			BaseURL:     server.URL,
+			Model:       "local-model",
+			Temperature: 0.2,
+			MaxTokens:   512,
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if lastModel != "local-model" {
+		t.Errorf("Expected request for local-model, got %s", lastModel)
+	}
+	if len(result.Suggestions) != 1 {
+		t.Fatalf("Expected 1 suggestion, got %d", len(result.Suggestions))
+	}
+	if result.Suggestions[0].ID != "miso-1A" {
+		t.Errorf("Expected suggestion miso-1A, got %s", result.Suggestions[0].ID)
+	}
+	if result.InputTokens != 120 || result.OutputTokens != 30 || result.TokensUsed != 150 {
+		t.Errorf(
+			"Unexpected token usage: input=%d output=%d total=%d",
+			result.InputTokens, result.OutputTokens, result.TokensUsed,
+		)
+	}
+}
+
+func TestCodeReviewer_AnthropicStub(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("x-api-key"); got != "test-key" {
+					t.Errorf("Expected x-api-key test-key, got %q", got)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":          "msg_1",
+						"type":        "message",
+						"role":        "assistant",
+						"model":       "claude-3-5-sonnet-latest",
+						"stop_reason": "end_turn",
+						"content": []map[string]any{
+							{"type": "text", "text": stubSuggestions},
+						},
+						"usage": map[string]any{
+							"input_tokens":  80,
+							"output_tokens": 20,
+						},
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderAnthropic,
+			BaseURL:  server.URL,
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if len(result.Suggestions) != 1 {
+		t.Fatalf("Expected 1 suggestion, got %d", len(result.Suggestions))
+	}
+	if result.InputTokens != 80 || result.OutputTokens != 20 || result.TokensUsed != 100 {
+		t.Errorf(
+			"Unexpected token usage: input=%d output=%d total=%d",
+			result.InputTokens, result.OutputTokens, result.TokensUsed,
+		)
+	}
+}
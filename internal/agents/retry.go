@@ -0,0 +1,192 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryDelay caps the exponential backoff between attempts.
+const maxRetryDelay = 30 * time.Second
+
+// retryableStatus lists the HTTP status codes worth retrying: rate limits,
+// server errors and Anthropic's "overloaded" status.
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+	529:                            true,
+}
+
+// retryTransport is a http.RoundTripper that retries transient failures with
+// exponential backoff and jitter, honoring Retry-After, and applies a timeout
+// to each attempt.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+	timeout    time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// newRetryTransport wraps base with the given retry policy.
+func newRetryTransport(
+	base http.RoundTripper, maxRetries int, baseDelay, timeout time.Duration,
+) *retryTransport {
+	return &retryTransport{
+		base:       base,
+		maxRetries: maxRetries,
+		baseDelay:  baseDelay,
+		timeout:    timeout,
+		sleep:      sleepContext,
+	}
+}
+
+// RoundTrip sends the request, retrying transient errors until the attempts
+// run out or the request context is cancelled.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+
+		// Stop as soon as the caller gives up
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		if err == nil && !retryableStatus[resp.StatusCode] {
+			return resp, nil
+		}
+
+		if attempt >= t.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			if err != nil {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+			}
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				// Capped like the backoff, so one header can't stall the run
+				delay = min(retryAfter, maxRetryDelay)
+			}
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt performs a single request with the per-attempt timeout applied.
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	attemptReq := req
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attemptReq = req.Clone(req.Context())
+		attemptReq.Body = body
+	}
+
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(attemptReq)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(attemptReq.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Keep the deadline until the caller has read the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns the delay before the next attempt: the base delay doubled
+// for every previous attempt, capped, with up to half of it randomized.
+// Without a base delay, attempts follow each other immediately.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	if t.baseDelay <= 0 {
+		return 0
+	}
+
+	// Compared before shifting, so large attempts can't overflow
+	delay := maxRetryDelay
+	if attempt < 63 && t.baseDelay <= maxRetryDelay>>attempt {
+		delay = t.baseDelay << attempt
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelOnClose releases the attempt context once the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the attempt context.
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
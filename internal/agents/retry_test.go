@@ -0,0 +1,298 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRetryTransport returns a retryTransport that records its delays
+// instead of sleeping.
+func newTestRetryTransport(maxRetries int, delays *[]time.Duration) *retryTransport {
+	t := newRetryTransport(http.DefaultTransport, maxRetries, time.Second, 0)
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	return t
+}
+
+func TestRetryTransport_RetriesTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("Expected replayed body 'payload', got %q", body)
+				}
+
+				switch calls.Add(1) {
+				case 1:
+					w.Header().Set("Retry-After", "7")
+					w.WriteHeader(http.StatusTooManyRequests)
+				case 2:
+					w.WriteHeader(http.StatusBadGateway)
+				default:
+					w.Write([]byte("ok"))
+				}
+			},
+		),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestRetryTransport(3, &delays)}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+	if len(delays) != 2 {
+		t.Fatalf("Expected 2 delays, got %d", len(delays))
+	}
+	if delays[0] != 7*time.Second {
+		t.Errorf("Expected Retry-After delay 7s, got %s", delays[0])
+	}
+	if delays[1] < time.Second || delays[1] > 2*time.Second {
+		t.Errorf("Expected backoff between 1s and 2s, got %s", delays[1])
+	}
+}
+
+func TestRetryTransport_CapsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.Header().Set("Retry-After", "86400")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte("ok"))
+			},
+		),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestRetryTransport(1, &delays)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if len(delays) != 1 || delays[0] != maxRetryDelay {
+		t.Errorf("Expected the Retry-After delay capped to %s, got %v", maxRetryDelay, delays)
+	}
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseDelay time.Duration
+		attempt   int
+		wantMin   time.Duration
+		wantMax   time.Duration
+	}{
+		{name: "no delay", baseDelay: 0, attempt: 0, wantMin: 0, wantMax: 0},
+		{name: "no delay after many attempts", baseDelay: 0, attempt: 10, wantMin: 0, wantMax: 0},
+		{
+			name:      "first attempt",
+			baseDelay: time.Second,
+			attempt:   0,
+			wantMin:   time.Second / 2,
+			wantMax:   time.Second,
+		},
+		{
+			name:      "doubled",
+			baseDelay: time.Second,
+			attempt:   2,
+			wantMin:   2 * time.Second,
+			wantMax:   4 * time.Second,
+		},
+		{
+			name:      "capped",
+			baseDelay: time.Second,
+			attempt:   10,
+			wantMin:   maxRetryDelay / 2,
+			wantMax:   maxRetryDelay,
+		},
+		{
+			name:      "shift overflow",
+			baseDelay: time.Second,
+			attempt:   70,
+			wantMin:   maxRetryDelay / 2,
+			wantMax:   maxRetryDelay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				transport := newRetryTransport(http.DefaultTransport, 3, tt.baseDelay, 0)
+				got := transport.backoff(tt.attempt)
+				if got < tt.wantMin || got > tt.wantMax {
+					t.Errorf("backoff(%d) = %s, want %s to %s", tt.attempt, got, tt.wantMin, tt.wantMax)
+				}
+			},
+		)
+	}
+}
+
+func TestRetryTransport_GivesUp(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestRetryTransport(2, &delays)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last status 503, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+func TestRetryTransport_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+		),
+	)
+	defer server.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestRetryTransport(3, &delays)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 attempt, got %d", calls.Load())
+	}
+}
+
+func TestRetryTransport_Cancellation(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+		),
+	)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := newRetryTransport(http.DefaultTransport, 5, time.Hour, 0)
+	transport.sleep = func(context.Context, time.Duration) error {
+		cancel()
+		return context.Canceled
+	}
+	client := &http.Client{Transport: transport}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err := client.Do(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRetryTransport_Timeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					// Hang until the attempt times out
+					<-r.Context().Done()
+					return
+				}
+				w.Write([]byte("ok"))
+			},
+		),
+	)
+	defer server.Close()
+
+	transport := newRetryTransport(
+		http.DefaultTransport, 1, time.Millisecond, 50*time.Millisecond,
+	)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("Expected body 'ok' from the second attempt, got %q", body)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "3", want: 3 * time.Second, wantOK: true},
+		{value: "-1", wantOK: false},
+		{value: "soon", wantOK: false},
+		{value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.value, func(t *testing.T) {
+				got, ok := parseRetryAfter(tt.value)
+				if ok != tt.wantOK || got != tt.want {
+					t.Errorf(
+						"parseRetryAfter(%q) = %s, %v; want %s, %v", tt.value,
+						got, ok, tt.want, tt.wantOK,
+					)
+				}
+			},
+		)
+	}
+}
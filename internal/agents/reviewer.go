@@ -2,17 +2,18 @@ package agents
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 
+	"github.com/j0lvera/miso/internal/cache"
 	"github.com/j0lvera/miso/internal/config"
 	"github.com/j0lvera/miso/internal/git"
 	"github.com/j0lvera/miso/internal/prompts"
+	"github.com/j0lvera/miso/internal/resolver"
+	"github.com/j0lvera/miso/internal/tools"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/openai"
 )
 
 const (
@@ -42,72 +43,185 @@ type Suggestion struct {
 	Body       string `json:"body"`
 	Original   string `json:"original,omitempty"`
 	Suggestion string `json:"suggestion,omitempty"`
+
+	Severity  Severity `json:"severity,omitempty"`
+	Category  Category `json:"category,omitempty"`
+	File      string   `json:"file,omitempty"`       // File the finding is in, set by the reviewer
+	StartLine int      `json:"start_line,omitempty"` // First line of the finding, zero if unknown
+	EndLine   int      `json:"end_line,omitempty"`   // Last line of the finding, zero if unknown
+	Guide     string   `json:"guide,omitempty"`      // Guide the finding is based on, empty for general issues
+
+	Confidence float64  `json:"confidence,omitempty"`  // From 0 to 1, as scored by verification or else by the model
+	ReportedBy []string `json:"reported_by,omitempty"` // Ensemble models that reported the finding
+	Ensemble   int      `json:"ensemble,omitempty"`    // Models in the ensemble, zero without one
 }
 
 // ReviewResult holds the review content and token usage information from an LLM call.
 // Provides details about the review content and associated costs.
 type ReviewResult struct {
 	Suggestions  []Suggestion
+	Model        string
+	Truncated    bool // The response was cut off and only complete suggestions were kept
 	TokensUsed   int
 	InputTokens  int
 	OutputTokens int
-	Cost         float64
+	CachedTokens int          // Input tokens read from the provider's prompt cache
+	Cost         float64      // USD, computed from the pricing table
+	Priced       bool         // The model has a price, so Cost is meaningful
+	Cached       bool         // Served from the response cache; no tokens were used
+	ToolCalls    int          // Repository tool calls made by the model
+	Dropped      []Suggestion // Suggestions scored below the verification threshold
+	Warnings     []string     // Invalid fields replaced by defaults after the last repair attempt
 }
 
 // CodeReviewer represents an AI-powered code reviewer agent.
 // It uses large language models to provide intelligent code review feedback.
 type CodeReviewer struct {
-	llm llms.Model
+	llm            llms.Model
+	verifier       llms.Model // Like llm, without the review schema enforced
+	provider       string
+	baseURL        string
+	model          string
+	temperature    float64
+	maxTokens      int
+	structured     structuredMode
+	repairAttempts int
+	cache          *cache.Cache
+	tools          *tools.Toolbox
+	maxSteps       int
+	pr             *prompts.PullRequest
 }
 
-// NewCodeReviewer creates a new CodeReviewer instance with OpenRouter configuration.
-// Requires OPENROUTER_API_KEY environment variable to be set.
-func NewCodeReviewer() (*CodeReviewer, error) {
-	// Get API key from environment
-	apiKey := os.Getenv("OPENROUTER_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENROUTER_API_KEY environment variable is not set")
+// NewCodeReviewer creates a new CodeReviewer instance for the configured provider.
+// Empty settings fall back to the provider defaults; the API key is read from
+// the provider's environment variable (OPENROUTER_API_KEY by default).
+func NewCodeReviewer(llmCfg config.LLM) (*CodeReviewer, error) {
+	resolved, err := resolveLLMConfig(llmCfg)
+	if err != nil {
+		return nil, err
 	}
 
-	// Set custom headers for OpenRouter
-	headers := map[string]string{
-		"HTTP-Referer": website,
-		"X-Title":      name,
+	llm, err := newModel(resolved)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to initialize %s client: %w", resolved.Provider, err,
+		)
 	}
 
-	// Create a custom transport to add headers
-	transport := &headerTransport{
-		base:    http.DefaultTransport,
-		headers: headers,
+	// The verification pass answers with scores rather than suggestions
+	verifier := llm
+	if structuredModeFor(resolved) == structuredResponseFormat {
+		plain := resolved
+		plain.StructuredOutput = config.StructuredOutputOff
+		verifier, err = newModel(plain)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to initialize %s client: %w", resolved.Provider, err,
+			)
+		}
 	}
 
-	// Create a custom HTTP client
-	client := &http.Client{
-		Transport: transport,
+	return &CodeReviewer{
+		llm:            llm,
+		verifier:       verifier,
+		provider:       resolved.Provider,
+		baseURL:        resolved.BaseURL,
+		model:          resolved.Model,
+		temperature:    resolved.Temperature,
+		maxTokens:      resolved.MaxTokens,
+		structured:     structuredModeFor(resolved),
+		repairAttempts: resolved.RepairAttempts,
+	}, nil
+}
+
+// SetCache stores successful reviews in c and serves identical requests
+// from it. A nil cache disables caching.
+func (cr *CodeReviewer) SetCache(c *cache.Cache) {
+	cr.cache = c
+}
+
+// SetPullRequest describes the pull request under review to the diff
+// prompt templates. A nil pull request leaves it out.
+func (cr *CodeReviewer) SetPullRequest(pr *prompts.PullRequest) {
+	cr.pr = pr
+}
+
+// Model returns the default model identifier used for review calls.
+func (cr *CodeReviewer) Model() string {
+	return cr.model
+}
+
+// applyCost fills in the cost of a result from its token usage.
+// Local models served by Ollama and cached responses are free.
+func (cr *CodeReviewer) applyCost(cfg *config.Config, result *ReviewResult) {
+	if cr.provider == config.ProviderOllama || result.Cached {
+		result.Cost, result.Priced = 0, true
+		return
 	}
 
-	// Configure for OpenRouter
-	llm, err := openai.New(
-		openai.WithToken(apiKey),
-		openai.WithBaseURL("https://openrouter.ai/api/v1"),
-		openai.WithModel("anthropic/claude-3.5-sonnet"),
-		openai.WithHTTPClient(client),
+	result.Cost, result.Priced = NewPricing(cfg.Pricing).Cost(
+		result.Model, result.InputTokens, result.OutputTokens,
 	)
+}
+
+// callSettings holds the per-call model parameters.
+type callSettings struct {
+	model       string
+	temperature float64
+
+	ensemble     []string // Models reviewing the file instead of model, if any
+	minAgreement int      // Ensemble models that must report a finding
+}
+
+// defaultSettings returns the call settings from the llm configuration.
+func (cr *CodeReviewer) defaultSettings() callSettings {
+	return callSettings{
+		model:       cr.model,
+		temperature: cr.temperature,
+	}
+}
+
+// settingsFor applies the model routing of the patterns matching filename.
+func (cr *CodeReviewer) settingsFor(
+	cfg *config.Config, filename string,
+) (callSettings, error) {
+	settings := cr.defaultSettings()
+
+	selection, err := resolver.NewResolver(cfg).GetModel(filename)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to initialize OpenRouter client: %w", err,
-		)
+		return settings, fmt.Errorf("failed to resolve model: %w", err)
 	}
 
-	return &CodeReviewer{
-		llm: llm,
-	}, nil
+	if selection.Model != "" {
+		settings.model = selection.Model
+	}
+	if selection.Temperature != nil {
+		settings.temperature = *selection.Temperature
+	}
+
+	settings.ensemble = selection.Ensemble
+	settings.minAgreement = cfg.Ensemble.MinAgreement
+	if selection.MinAgreement > 0 {
+		settings.minAgreement = selection.MinAgreement
+	}
+
+	return settings, nil
 }
 
 // Review performs a comprehensive code review on the provided code.
 // Uses configured review guides and patterns to provide contextual feedback.
 func (cr *CodeReviewer) Review(
-	cfg *config.Config, code string, filename string,
+	ctx context.Context, cfg *config.Config, code string, filename string,
+) (*ReviewResult, error) {
+	return cr.ReviewStream(ctx, cfg, code, filename, nil)
+}
+
+// ReviewStream performs Review, streaming the response to stream.
+// Ensemble and verified reviews aren't streamed, since suggestions may still
+// be merged or dropped.
+func (cr *CodeReviewer) ReviewStream(
+	ctx context.Context, cfg *config.Config, code string, filename string,
+	stream *Stream,
 ) (*ReviewResult, error) {
 	// Get the formatted prompt
 	prompt, err := prompts.CodeReview(cfg, code, filename)
@@ -115,96 +229,318 @@ func (cr *CodeReviewer) Review(
 		return nil, fmt.Errorf("failed to format prompt: %w", err)
 	}
 
-	return cr.callLLM(prompt)
+	return cr.run(ctx, cfg, prompt, code, filename, stream)
 }
 
 // ReviewDiff performs a focused code review on the provided diff data.
 // Analyzes only the changes rather than the full file, using diff-specific guides.
 func (cr *CodeReviewer) ReviewDiff(
-	cfg *config.Config, diffData *git.DiffData, filename string,
+	ctx context.Context, cfg *config.Config, diffData *git.DiffData,
+	filename string,
+) (*ReviewResult, error) {
+	return cr.ReviewDiffStream(ctx, cfg, diffData, filename, nil)
+}
+
+// ReviewDiffStream performs ReviewDiff, streaming the response to stream.
+// Ensemble and verified reviews aren't streamed, since suggestions may still
+// be merged or dropped.
+func (cr *CodeReviewer) ReviewDiffStream(
+	ctx context.Context, cfg *config.Config, diffData *git.DiffData,
+	filename string, stream *Stream,
 ) (*ReviewResult, error) {
 	// Get the formatted diff prompt
-	prompt, err := prompts.DiffReview(cfg, diffData, filename)
+	prompt, err := prompts.DiffReview(cfg, diffData, filename, cr.pr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format diff prompt: %w", err)
 	}
 
-	return cr.callLLM(prompt)
+	return cr.run(
+		ctx, cfg, prompt, diffData.FormatForReview(), filename, stream,
+	)
 }
 
-// callLLM is a helper method to make LLM calls and parse responses
-func (cr *CodeReviewer) callLLM(prompt string) (*ReviewResult, error) {
-	// Call the LLM with GenerateContent for detailed response
-	ctx := context.Background()
-	messages := []llms.MessageContent{
-		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+// run reviews a prompt with the model or ensemble routed for filename and
+// verifies the suggestions if enabled. subject is the reviewed code or diff.
+func (cr *CodeReviewer) run(
+	ctx context.Context, cfg *config.Config, prompt prompts.Prompt, subject string,
+	filename string, stream *Stream,
+) (*ReviewResult, error) {
+	settings, err := cr.settingsFor(cfg, filename)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := cr.llm.GenerateContent(
-		ctx, messages,
-		llms.WithTemperature(0.3),
-	)
+	// Suggestions are only final once merged and verified
+	if len(settings.ensemble) > 0 || cfg.Verify.Enabled {
+		stream = nil
+	}
+	if stream != nil && stream.Suggestion != nil {
+		// Streamed suggestions haven't been through parseSuggestions yet;
+		// invalid ones are left to the repair loop
+		emit := stream.Suggestion
+		stream = &Stream{
+			Suggestion: func(suggestion Suggestion) {
+				if suggestion.normalize() == nil {
+					suggestion.File = filename
+					emit(suggestion)
+				}
+			},
+			Restart: stream.Restart,
+		}
+	}
+
+	var result *ReviewResult
+	if len(settings.ensemble) > 0 {
+		result, err = cr.callEnsemble(ctx, cfg, prompt, settings)
+	} else {
+		result, err = cr.callLLM(ctx, prompt, settings, stream)
+		if err == nil {
+			cr.applyCost(cfg, result)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("LLM call failed: %w", err)
+		return nil, err
 	}
 
-	// Extract the response content
-	content := ""
-	if len(resp.Choices) > 0 {
-		content = resp.Choices[0].Content
+	if err := cr.verify(ctx, cfg, subject, filename, settings, result); err != nil {
+		return nil, err
 	}
 
-	// Find the start of the JSON array to strip any leading text.
-	startIndex := strings.Index(content, "[")
-	if startIndex == -1 {
-		return nil, fmt.Errorf("failed to find start of JSON array in LLM response\nRaw response:\n%s", content)
+	for i := range result.Suggestions {
+		result.Suggestions[i].File = filename
 	}
+	for i := range result.Dropped {
+		result.Dropped[i].File = filename
+	}
+	return result, nil
+}
+
+// repairInstruction asks the model to resend a response that failed to parse.
+const repairInstruction = `Your previous response could not be parsed: %v
+
+Reply again with only the JSON array of suggestion objects described above. Do not add any text or markdown around it, and make sure every string is properly escaped and the array is closed.`
 
-	// Find the end of the JSON array
-	endIndex := strings.LastIndex(content, "]")
-	if endIndex == -1 {
-		return nil, fmt.Errorf("failed to find end of JSON array in LLM response\nRaw response:\n%s", content)
+// cacheVersion is part of every cache key; bump it when the layout of
+// ReviewResult or the way prompts are sent changes.
+const cacheVersion = "3"
+
+// cacheKey identifies a review call by everything that affects its response:
+// the final prompt, the endpoint, the model, the sampling parameters and the
+// repository revision the tools read. It reports false when the call can't
+// be cached because the tools read a working tree.
+func (cr *CodeReviewer) cacheKey(prompt string, settings callSettings) (string, bool) {
+	toolsKey, ok := cr.toolsCacheKey()
+	if !ok {
+		return "", false
+	}
+	return cache.Key(
+		cacheVersion, cr.provider, cr.baseURL, settings.model,
+		strconv.FormatFloat(settings.temperature, 'g', -1, 64),
+		strconv.Itoa(cr.maxTokens), strconv.Itoa(int(cr.structured)), toolsKey,
+		prompt,
+	), true
+}
+
+// callLLM returns the cached result of an identical call when there is one,
+// replaying its suggestions to stream, and otherwise calls the model
+// and caches the result.
+func (cr *CodeReviewer) callLLM(
+	ctx context.Context, prompt prompts.Prompt, settings callSettings,
+	stream *Stream,
+) (*ReviewResult, error) {
+	key, cacheable := cr.cacheKey(prompt.String(), settings)
+	if cr.cache == nil || !cacheable {
+		return cr.generate(ctx, prompt, settings, stream)
+	}
+
+	var cached ReviewResult
+	if cr.cache.Get(key, &cached) {
+		if stream != nil && stream.Suggestion != nil {
+			for _, suggestion := range cached.Suggestions {
+				stream.Suggestion(suggestion)
+			}
+		}
+		return &ReviewResult{
+			Suggestions: cached.Suggestions,
+			Model:       cached.Model,
+			Truncated:   cached.Truncated,
+			Warnings:    cached.Warnings,
+			Cached:      true,
+		}, nil
+	}
+
+	result, err := cr.generate(ctx, prompt, settings, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed write only costs a cache miss on the next run
+	_ = cr.cache.Put(key, result)
+	return result, nil
+}
+
+// generate is a helper method to make LLM calls and parse responses.
+// The prompt prefix goes in the system message, which providers can cache
+// across files, and the suffix in the human message.
+// Transient failures are retried by the HTTP transport; cancelling ctx
+// aborts the in-flight request. Responses that can't be parsed are sent back
+// to the model with the parse error, up to the configured repair attempts.
+// With a stream, the first attempt is streamed; repairs are not, and the
+// stream is told to restart when the streamed attempt fails to parse.
+func (cr *CodeReviewer) generate(
+	ctx context.Context, prompt prompts.Prompt, settings callSettings,
+	stream *Stream,
+) (*ReviewResult, error) {
+	// Custom templates may leave the prefix out
+	system := strings.TrimSpace(
+		strings.Join([]string{cr.systemInstruction(), prompt.Prefix}, "\n\n"),
+	)
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt.Suffix),
+	}
+	if system != "" {
+		messages = append(
+			[]llms.MessageContent{
+				llms.TextParts(llms.ChatMessageTypeSystem, system),
+			}, messages...,
+		)
 	}
 
-	jsonStr := content[startIndex : endIndex+1]
+	options := []llms.CallOption{
+		llms.WithModel(settings.model),
+		llms.WithTemperature(settings.temperature),
+	}
+	if cr.maxTokens > 0 {
+		options = append(options, llms.WithMaxTokens(cr.maxTokens))
+	}
 
-	var suggestions []Suggestion
-	if err := json.Unmarshal([]byte(jsonStr), &suggestions); err != nil {
-		return nil, fmt.Errorf("failed to parse LLM JSON response: %w\nRaw response:\n%s", err, content)
+	switch cr.structured {
+	case structuredTool:
+		options = append(options, llms.WithTools([]llms.Tool{reviewTool()}))
+	case structuredJSONMode:
+		options = append(options, llms.WithJSONMode())
 	}
 
-	// Create result with content
 	result := &ReviewResult{
-		Suggestions: suggestions,
+		Model: settings.model,
 	}
 
-	// Check if usage information is available in the response
-	// Based on the debug output, OpenRouter returns these fields in GenerationInfo
-	if len(resp.Choices) > 0 && resp.Choices[0].GenerationInfo != nil {
-		genInfo := resp.Choices[0].GenerationInfo
+	streaming := stream != nil && stream.Suggestion != nil && cr.canStream()
+	for attempt := 0; ; attempt++ {
+		attemptOptions := options
+		if attempt == 0 && streaming {
+			parser := newSuggestionStream(stream.Suggestion)
+			attemptOptions = append(
+				options[:len(options):len(options)], llms.WithStreamingFunc(
+					func(_ context.Context, chunk []byte) error {
+						parser.Write(chunk)
+						return nil
+					},
+				),
+			)
+		}
 
-		// Extract the actual fields from the GenerationInfo map
-		// The values might be int or float64, so we need to handle both
-		if completionTokens, ok := genInfo["CompletionTokens"].(int); ok {
-			result.OutputTokens = completionTokens
-		} else if completionTokens, ok := genInfo["CompletionTokens"].(float64); ok {
-			result.OutputTokens = int(completionTokens)
+		resp, conversation, err := cr.complete(ctx, messages, attemptOptions, result)
+		if err != nil {
+			return nil, fmt.Errorf("LLM call failed: %w", err)
+		}
+		messages = conversation
+
+		content := responseContent(resp)
+		suggestions, truncated, err := parseSuggestions(content)
+		var warnings []string
+		if err != nil && attempt >= cr.repairAttempts {
+			// Keep the valid suggestions of the last attempt rather than
+			// failing the whole review over a few invalid fields
+			suggestions, truncated, warnings, err = parseSuggestionsLenient(content)
+		}
+		if err == nil {
+			result.Suggestions = suggestions
+			result.Truncated = truncated
+			result.Warnings = warnings
+			return result, nil
 		}
 
-		if promptTokens, ok := genInfo["PromptTokens"].(int); ok {
-			result.InputTokens = promptTokens
-		} else if promptTokens, ok := genInfo["PromptTokens"].(float64); ok {
-			result.InputTokens = int(promptTokens)
+		if attempt >= cr.repairAttempts {
+			return nil, fmt.Errorf(
+				"failed to parse LLM JSON response after %d attempts: %w\nRaw response:\n%s",
+				attempt+1, err, content,
+			)
 		}
 
-		if totalTokens, ok := genInfo["TotalTokens"].(int); ok {
-			result.TokensUsed = totalTokens
-		} else if totalTokens, ok := genInfo["TotalTokens"].(float64); ok {
-			result.TokensUsed = int(totalTokens)
+		// The streamed preview is superseded by the repaired response
+		if attempt == 0 && streaming && stream.Restart != nil {
+			stream.Restart()
 		}
 
-		// Don't print debug here - we'll do it after the review content
+		// Send the parse error back so the model can fix its output
+		messages = append(
+			messages,
+			llms.TextParts(llms.ChatMessageTypeAI, content),
+			llms.TextParts(
+				llms.ChatMessageTypeHuman, fmt.Sprintf(repairInstruction, err),
+			),
+		)
 	}
+}
 
-	return result, nil
+// canStream reports whether responses can be streamed. Tool call arguments
+// aren't streamed by the Anthropic client, so tool mode and reviews with
+// repository tools stay buffered.
+func (cr *CodeReviewer) canStream() bool {
+	return cr.structured != structuredTool && cr.tools == nil
+}
+
+// responseContent returns the review payload of a response: the arguments
+// of the review tool call when present, otherwise the text of all choices.
+func responseContent(resp *llms.ContentResponse) string {
+	var text strings.Builder
+	for _, choice := range resp.Choices {
+		for _, call := range choice.ToolCalls {
+			if call.FunctionCall != nil && call.FunctionCall.Name == reviewToolName {
+				return call.FunctionCall.Arguments
+			}
+		}
+		text.WriteString(choice.Content)
+	}
+	return text.String()
+}
+
+// addUsage adds the token usage reported in a response to the result.
+// OpenAI-style providers (OpenRouter, OpenAI, Ollama) report
+// PromptTokens/CompletionTokens, Anthropic reports InputTokens/OutputTokens.
+func addUsage(result *ReviewResult, resp *llms.ContentResponse) {
+	if len(resp.Choices) == 0 || resp.Choices[0].GenerationInfo == nil {
+		return
+	}
+	genInfo := resp.Choices[0].GenerationInfo
+
+	outputTokens := tokenCount(genInfo, "CompletionTokens", "OutputTokens")
+	inputTokens := tokenCount(genInfo, "PromptTokens", "InputTokens")
+	totalTokens := tokenCount(genInfo, "TotalTokens")
+	if totalTokens == 0 {
+		totalTokens = inputTokens + outputTokens
+	}
+
+	result.InputTokens += inputTokens
+	result.OutputTokens += outputTokens
+	result.TokensUsed += totalTokens
+}
+
+// tokenCount returns the first non-zero token count found under the given keys.
+// The values might be int or float64 depending on the provider.
+func tokenCount(genInfo map[string]any, keys ...string) int {
+	for _, key := range keys {
+		switch v := genInfo[key].(type) {
+		case int:
+			if v != 0 {
+				return v
+			}
+		case float64:
+			if v != 0 {
+				return int(v)
+			}
+		}
+	}
+	return 0
 }
@@ -1,12 +1,15 @@
 package agents
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/j0lvera/miso/internal/cache"
 	"github.com/j0lvera/miso/internal/config"
 	"github.com/j0lvera/miso/internal/git"
+	"github.com/j0lvera/miso/internal/prompts"
 )
 
 func TestNewCodeReviewer(t *testing.T) {
@@ -24,7 +27,7 @@ func TestNewCodeReviewer(t *testing.T) {
 		"missing API key", func(t *testing.T) {
 			os.Unsetenv("OPENROUTER_API_KEY")
 
-			_, err := NewCodeReviewer()
+			_, err := NewCodeReviewer(config.DefaultConfig().LLM)
 			if err == nil {
 				t.Error("Expected error when OPENROUTER_API_KEY is not set")
 			}
@@ -40,7 +43,7 @@ func TestNewCodeReviewer(t *testing.T) {
 		"with API key", func(t *testing.T) {
 			os.Setenv("OPENROUTER_API_KEY", "test-key")
 
-			reviewer, err := NewCodeReviewer()
+			reviewer, err := NewCodeReviewer(config.DefaultConfig().LLM)
 			if err != nil {
 				t.Errorf("Unexpected error with API key set: %v", err)
 			}
@@ -55,7 +58,7 @@ func TestCodeReviewer_Review(t *testing.T) {
 	// Skip API tests to avoid costs
 	t.Skip("Skipping API test to avoid costs")
 
-	reviewer, err := NewCodeReviewer()
+	reviewer, err := NewCodeReviewer(config.DefaultConfig().LLM)
 	if err != nil {
 		t.Fatalf("Failed to create reviewer: %v", err)
 	}
@@ -90,7 +93,9 @@ func main() {
 	for _, tt := range tests {
 		t.Run(
 			tt.name, func(t *testing.T) {
-				result, err := reviewer.Review(cfg, tt.code, tt.filename)
+				result, err := reviewer.Review(
+					context.Background(), cfg, tt.code, tt.filename,
+				)
 
 				if (err != nil) != tt.wantErr {
 					t.Errorf("Review() error = %v, wantErr %v", err, tt.wantErr)
@@ -114,7 +119,7 @@ func TestCodeReviewer_ReviewDiff(t *testing.T) {
 	// Skip API tests to avoid costs
 	t.Skip("Skipping API test to avoid costs")
 
-	reviewer, err := NewCodeReviewer()
+	reviewer, err := NewCodeReviewer(config.DefaultConfig().LLM)
 	if err != nil {
 		t.Fatalf("Failed to create reviewer: %v", err)
 	}
@@ -172,7 +177,7 @@ func TestCodeReviewer_ReviewDiff(t *testing.T) {
 		t.Run(
 			tt.name, func(t *testing.T) {
 				result, err := reviewer.ReviewDiff(
-					cfg, tt.diffData, tt.filename,
+					context.Background(), cfg, tt.diffData, tt.filename,
 				)
 
 				if (err != nil) != tt.wantErr {
@@ -199,24 +204,24 @@ func TestCodeReviewer_callLLM(t *testing.T) {
 	// Skip API tests to avoid costs
 	t.Skip("Skipping API test to avoid costs")
 
-	reviewer, err := NewCodeReviewer()
+	reviewer, err := NewCodeReviewer(config.DefaultConfig().LLM)
 	if err != nil {
 		t.Fatalf("Failed to create reviewer: %v", err)
 	}
 
 	tests := []struct {
 		name    string
-		prompt  string
+		prompt  prompts.Prompt
 		wantErr bool
 	}{
 		{
 			name:    "simple prompt",
-			prompt:  "Review this code: package main",
+			prompt:  prompts.Prompt{Prefix: "Review this code:", Suffix: "package main"},
 			wantErr: false,
 		},
 		{
 			name:    "empty prompt",
-			prompt:  "",
+			prompt:  prompts.Prompt{},
 			wantErr: true, // Empty prompts should cause an error
 		},
 	}
@@ -224,7 +229,10 @@ func TestCodeReviewer_callLLM(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(
 			tt.name, func(t *testing.T) {
-				result, err := reviewer.callLLM(tt.prompt)
+				result, err := reviewer.callLLM(
+					context.Background(), tt.prompt,
+					reviewer.defaultSettings(), nil,
+				)
 
 				if (err != nil) != tt.wantErr {
 					t.Errorf(
@@ -283,3 +291,266 @@ func TestReviewResult_Structure(t *testing.T) {
 		t.Errorf("Expected cost 0.001, got %f", result.Cost)
 	}
 }
+
+func TestCodeReviewer_ModelRouting(t *testing.T) {
+	var lastModel string
+	server := newOpenAIStub(t, &lastModel)
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.LLM = config.LLM{
+		Provider: config.ProviderOpenAICompatible,
+		BaseURL:  server.URL,
+		Model:    "default-model",
+	}
+	cfg.Patterns = []config.Pattern{
+		{
+			Name:     "test-files",
+			Filename: `_test\.go$`,
+			Context:  []string{"testing.md"},
+			Model:    "cheap-model",
+			Stop:     true,
+		},
+		{
+			Name:     "go-files",
+			Filename: `\.go$`,
+			Context:  []string{"go.md"},
+		},
+	}
+
+	reviewer, err := NewCodeReviewer(cfg.LLM)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	tests := []struct {
+		filename  string
+		wantModel string
+	}{
+		{filename: "main_test.go", wantModel: "cheap-model"},
+		{filename: "main.go", wantModel: "default-model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.filename, func(t *testing.T) {
+				result, err := reviewer.ReviewDiff(
+					context.Background(), cfg,
+					&git.DiffData{FilePath: tt.filename}, tt.filename,
+				)
+				if err != nil {
+					t.Fatalf("ReviewDiff() error = %v", err)
+				}
+
+				if lastModel != tt.wantModel {
+					t.Errorf(
+						"Request used model %s, want %s", lastModel,
+						tt.wantModel,
+					)
+				}
+				if result.Model != tt.wantModel {
+					t.Errorf(
+						"Result model = %s, want %s", result.Model,
+						tt.wantModel,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_RepairLoop(t *testing.T) {
+	tests := []struct {
+		name           string
+		contents       []string
+		repairAttempts int
+		wantRequests   int
+		wantErr        bool
+	}{
+		{
+			name:           "valid response needs no repair",
+			contents:       []string{stubSuggestions},
+			repairAttempts: 2,
+			wantRequests:   1,
+		},
+		{
+			name:           "invalid response is repaired",
+			contents:       []string{"Sorry, here you go: [oops", stubSuggestions},
+			repairAttempts: 2,
+			wantRequests:   2,
+		},
+		{
+			name:           "gives up after the repair attempts",
+			contents:       []string{"not json"},
+			repairAttempts: 1,
+			wantRequests:   2,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var requests []map[string]any
+				server := newScriptedStub(t, tt.contents, &requests)
+				defer server.Close()
+
+				reviewer, err := NewCodeReviewer(
+					config.LLM{
+						Provider:       config.ProviderOpenAICompatible,
+						BaseURL:        server.URL,
+						Model:          "local-model",
+						RepairAttempts: tt.repairAttempts,
+					},
+				)
+				if err != nil {
+					t.Fatalf("NewCodeReviewer() error = %v", err)
+				}
+
+				result, err := reviewer.Review(
+					context.Background(), config.DefaultConfig(),
+					"package main", "main.go",
+				)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Review() error = %v, wantErr %v", err, tt.wantErr)
+				}
+
+				if len(requests) != tt.wantRequests {
+					t.Errorf(
+						"Expected %d requests, got %d", tt.wantRequests,
+						len(requests),
+					)
+				}
+
+				if tt.wantErr {
+					return
+				}
+
+				if len(result.Suggestions) != 1 {
+					t.Errorf(
+						"Expected 1 suggestion, got %d", len(result.Suggestions),
+					)
+				}
+
+				// Usage is summed across the repair calls
+				if result.TokensUsed != 15*tt.wantRequests {
+					t.Errorf(
+						"Expected %d tokens, got %d", 15*tt.wantRequests,
+						result.TokensUsed,
+					)
+				}
+
+				// The repair request carries the failed response and the error
+				// after the system and human messages of the prompt
+				if tt.wantRequests > 1 {
+					messages, _ := requests[1]["messages"].([]any)
+					if len(messages) != 4 {
+						t.Fatalf(
+							"Expected 4 messages in the repair request, got %d",
+							len(messages),
+						)
+					}
+					last, _ := messages[3].(map[string]any)
+					if content, _ := last["content"].(string); !strings.Contains(
+						content, "could not be parsed",
+					) {
+						t.Errorf("Repair message missing parse error: %q", content)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_RepairLoop_LastAttempt(t *testing.T) {
+	// One suggestion keeps an invalid severity through every attempt
+	content := `[` +
+		`{"id":"miso-1A","title":"🔴 Critical: Nil map","body":"b","severity":"critical"},` +
+		`{"id":"miso-1B","title":"💡 Suggestion: Rename","body":"b","severity":"urgent"}]`
+
+	var requests []map[string]any
+	server := newScriptedStub(t, []string{content}, &requests)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider:       config.ProviderOpenAICompatible,
+			BaseURL:        server.URL,
+			Model:          "local-model",
+			RepairAttempts: 1,
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Errorf("Expected a review and a repair request, got %d", len(requests))
+	}
+	if len(result.Suggestions) != 2 {
+		t.Fatalf("Expected both suggestions to be kept, got %d", len(result.Suggestions))
+	}
+	if got := result.Suggestions[1].Severity; got != SeveritySuggestion {
+		t.Errorf("Expected the severity inferred from the title, got %q", got)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], `"urgent"`) {
+		t.Errorf("Expected a warning about the invalid severity, got %v", result.Warnings)
+	}
+}
+
+func TestCodeReviewer_Cache(t *testing.T) {
+	var requests []map[string]any
+	server := newScriptedStub(t, []string{stubSuggestions}, &requests)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderOpenAICompatible,
+			BaseURL:  server.URL,
+			Model:    "local-model",
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+	reviewer.SetCache(cache.New(t.TempDir()))
+
+	cfg := config.DefaultConfig()
+	review := func(code string) *ReviewResult {
+		t.Helper()
+		result, err := reviewer.Review(context.Background(), cfg, code, "main.go")
+		if err != nil {
+			t.Fatalf("Review() error = %v", err)
+		}
+		return result
+	}
+
+	first := review("package main")
+	if first.Cached || first.TokensUsed == 0 {
+		t.Errorf("Expected the first review to call the model, got %+v", first)
+	}
+
+	second := review("package main")
+	if !second.Cached || second.TokensUsed != 0 || second.Cost != 0 {
+		t.Errorf("Expected the second review from the cache, got %+v", second)
+	}
+	if len(second.Suggestions) != len(first.Suggestions) {
+		t.Errorf("Cached review has %d suggestions, want %d", len(second.Suggestions), len(first.Suggestions))
+	}
+	if len(requests) != 1 {
+		t.Errorf("Expected 1 request, got %d", len(requests))
+	}
+
+	// A different prompt misses the cache
+	review("package other")
+	if len(requests) != 2 {
+		t.Errorf("Expected a new request for a different prompt, got %d", len(requests))
+	}
+}
@@ -0,0 +1,161 @@
+package agents
+
+import (
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// reviewToolName is the tool the model calls to submit its review when the
+// provider enforces structured output through tool calling.
+const reviewToolName = "submit_review"
+
+// structuredMode is how a provider is asked for schema-conforming output.
+type structuredMode int
+
+const (
+	structuredNone           structuredMode = iota // Plain text, parsed and repaired
+	structuredResponseFormat                       // OpenAI response_format JSON schema
+	structuredTool                                 // Tool call carrying the review
+	structuredJSONMode                             // Generic JSON mode without a schema
+)
+
+// structuredModeFor picks the structured output mechanism for a provider.
+// With "auto" only providers known to support it use native structured
+// output; "on" also enables it for OpenAI-compatible endpoints and Ollama.
+func structuredModeFor(cfg config.LLM) structuredMode {
+	setting := cfg.StructuredOutput
+	if setting == "" {
+		setting = config.StructuredOutputAuto
+	}
+	if setting == config.StructuredOutputOff {
+		return structuredNone
+	}
+
+	switch cfg.Provider {
+	case config.ProviderOpenRouter, config.ProviderOpenAI:
+		return structuredResponseFormat
+	case config.ProviderAnthropic:
+		return structuredTool
+	case config.ProviderOpenAICompatible:
+		if setting == config.StructuredOutputOn {
+			return structuredResponseFormat
+		}
+	case config.ProviderOllama:
+		if setting == config.StructuredOutputOn {
+			return structuredJSONMode
+		}
+	}
+
+	return structuredNone
+}
+
+// suggestionSchema describes a single suggestion. All fields are required
+// because strict schemas don't allow optional properties; empty strings
+// and zero lines stand in for missing values.
+func suggestionSchema() *openai.ResponseFormatJSONSchemaProperty {
+	return &openai.ResponseFormatJSONSchemaProperty{
+		Type: "object",
+		Properties: map[string]*openai.ResponseFormatJSONSchemaProperty{
+			"id": {
+				Type:        "string",
+				Description: `Unique identifier, e.g. "miso-1A".`,
+			},
+			"title": {
+				Type:        "string",
+				Description: "One-line summary including a severity emoji.",
+			},
+			"body": {
+				Type:        "string",
+				Description: "Markdown explanation of the issue and why it matters.",
+			},
+			"original": {
+				Type:        "string",
+				Description: "The exact code to be replaced, or an empty string.",
+			},
+			"suggestion": {
+				Type:        "string",
+				Description: "The new code, or an empty string.",
+			},
+			"severity": {
+				Type:        "string",
+				Description: "How serious the issue is.",
+				Enum:        enumValues(Severities),
+			},
+			"category": {
+				Type:        "string",
+				Description: "The kind of issue.",
+				Enum:        enumValues(Categories),
+			},
+			"start_line": {
+				Type:        "integer",
+				Description: "First line of the issue, or 0 if unknown.",
+			},
+			"end_line": {
+				Type:        "integer",
+				Description: "Last line of the issue, or 0 if unknown.",
+			},
+			"confidence": {
+				Type:        "number",
+				Description: "How sure you are that the issue is real, from 0 to 1.",
+			},
+			"guide": {
+				Type:        "string",
+				Description: "Name of the guide the issue is based on, or an empty string.",
+			},
+		},
+		Required: []string{
+			"id", "title", "body", "original", "suggestion", "severity",
+			"category", "start_line", "end_line", "confidence", "guide",
+		},
+	}
+}
+
+// enumValues converts enum constants to the values of a schema enum.
+func enumValues[T ~string](values []T) []any {
+	enum := make([]any, len(values))
+	for i, value := range values {
+		enum[i] = string(value)
+	}
+	return enum
+}
+
+// reviewSchema is the root schema of a review: an object wrapping the array,
+// since structured output requires an object at the root.
+func reviewSchema() *openai.ResponseFormatJSONSchemaProperty {
+	return &openai.ResponseFormatJSONSchemaProperty{
+		Type: "object",
+		Properties: map[string]*openai.ResponseFormatJSONSchemaProperty{
+			"suggestions": {
+				Type:        "array",
+				Description: "Suggestions sorted from most to least critical.",
+				Items:       suggestionSchema(),
+			},
+		},
+		Required: []string{"suggestions"},
+	}
+}
+
+// reviewResponseFormat is the OpenAI response_format enforcing reviewSchema.
+func reviewResponseFormat() *openai.ResponseFormat {
+	return &openai.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openai.ResponseFormatJSONSchema{
+			Name:   "code_review",
+			Strict: true,
+			Schema: reviewSchema(),
+		},
+	}
+}
+
+// reviewTool is the tool definition carrying reviewSchema as its input.
+func reviewTool() llms.Tool {
+	return llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        reviewToolName,
+			Description: "Submit the code review suggestions.",
+			Parameters:  reviewSchema(),
+		},
+	}
+}
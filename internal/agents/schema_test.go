@@ -0,0 +1,149 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestStructuredModeFor(t *testing.T) {
+	tests := []struct {
+		provider string
+		setting  string
+		want     structuredMode
+	}{
+		{config.ProviderOpenRouter, "", structuredResponseFormat},
+		{config.ProviderOpenAI, config.StructuredOutputAuto, structuredResponseFormat},
+		{config.ProviderAnthropic, config.StructuredOutputAuto, structuredTool},
+		{config.ProviderOpenAICompatible, config.StructuredOutputAuto, structuredNone},
+		{config.ProviderOpenAICompatible, config.StructuredOutputOn, structuredResponseFormat},
+		{config.ProviderOllama, config.StructuredOutputAuto, structuredNone},
+		{config.ProviderOllama, config.StructuredOutputOn, structuredJSONMode},
+		{config.ProviderOpenAI, config.StructuredOutputOff, structuredNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.provider+"/"+tt.setting, func(t *testing.T) {
+				got := structuredModeFor(
+					config.LLM{Provider: tt.provider, StructuredOutput: tt.setting},
+				)
+				if got != tt.want {
+					t.Errorf("structuredModeFor() = %d, want %d", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_ResponseFormat(t *testing.T) {
+	var requests []map[string]any
+	server := newScriptedStub(
+		t, []string{`{"suggestions":[]}`}, &requests,
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider:         config.ProviderOpenAICompatible,
+			BaseURL:          server.URL,
+			Model:            "local-model",
+			StructuredOutput: config.StructuredOutputOn,
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+	if len(result.Suggestions) != 0 {
+		t.Errorf("Expected no suggestions, got %d", len(result.Suggestions))
+	}
+
+	format, _ := requests[0]["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Fatalf("Expected json_schema response_format, got %v", format)
+	}
+	schema, _ := format["json_schema"].(map[string]any)
+	if schema["strict"] != true {
+		t.Errorf("Expected a strict schema, got %v", schema["strict"])
+	}
+}
+
+func TestCodeReviewer_ToolCallOutput(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	var toolsSent []any
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var req map[string]any
+				json.NewDecoder(r.Body).Decode(&req)
+				toolsSent, _ = req["tools"].([]any)
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(
+					map[string]any{
+						"id":          "msg_1",
+						"type":        "message",
+						"role":        "assistant",
+						"stop_reason": "tool_use",
+						"content": []map[string]any{
+							{
+								"type": "tool_use",
+								"id":   "toolu_1",
+								"name": reviewToolName,
+								"input": map[string]any{
+									"suggestions": []map[string]any{
+										{
+											"id":         "miso-1A",
+											"title":      "🟡 Warning: Unchecked error",
+											"body":       "Check the error.",
+											"original":   "",
+											"suggestion": "",
+										},
+									},
+								},
+							},
+						},
+						"usage": map[string]any{
+							"input_tokens":  10,
+							"output_tokens": 5,
+						},
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{Provider: config.ProviderAnthropic, BaseURL: server.URL},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	result, err := reviewer.Review(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if len(toolsSent) != 1 {
+		t.Errorf("Expected the review tool in the request, got %v", toolsSent)
+	}
+	if len(result.Suggestions) != 1 || result.Suggestions[0].ID != "miso-1A" {
+		t.Errorf("Expected suggestion miso-1A from the tool call, got %+v", result.Suggestions)
+	}
+}
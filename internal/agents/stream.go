@@ -0,0 +1,108 @@
+package agents
+
+import (
+	"encoding/json"
+)
+
+// SuggestionFunc receives each suggestion as soon as it has been streamed.
+type SuggestionFunc func(Suggestion)
+
+// Stream receives a review as it is streamed.
+type Stream struct {
+	// Suggestion receives each suggestion as soon as it is complete.
+	Suggestion SuggestionFunc
+	// Restart, if set, is called when the streamed response couldn't be
+	// parsed and the model is asked again. The suggestions streamed so far
+	// are superseded by those of the final result.
+	Restart func()
+}
+
+// suggestionStream incrementally parses a streamed review and reports each
+// suggestion as soon as its JSON object closes. It accepts both a bare array
+// and the structured output envelope; text around the JSON is ignored.
+// Streamed suggestions are a preview: the complete response is still parsed
+// with parseSuggestions once the call finishes.
+type suggestionStream struct {
+	emit SuggestionFunc
+
+	depth      int    // Nesting depth of arrays and objects
+	arrayDepth int    // Depth of the suggestions array, 0 when outside one
+	inString   bool   // Inside a JSON string
+	escaped    bool   // The previous character was a backslash in a string
+	object     []byte // The suggestion object being captured, nil if none
+}
+
+// newSuggestionStream creates a parser reporting suggestions to emit.
+func newSuggestionStream(emit SuggestionFunc) *suggestionStream {
+	return &suggestionStream{emit: emit}
+}
+
+// Write feeds the next chunk of the response to the parser.
+func (s *suggestionStream) Write(chunk []byte) {
+	for _, c := range chunk {
+		if s.object != nil {
+			s.object = append(s.object, c)
+		}
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			s.inString = true
+		case '[':
+			s.depth++
+			// A top-level array, or the array inside the envelope object
+			if s.arrayDepth == 0 && s.depth <= 2 {
+				s.arrayDepth = s.depth
+			}
+		case '{':
+			s.depth++
+			if s.arrayDepth > 0 && s.depth == s.arrayDepth+1 && s.object == nil {
+				s.object = []byte{c}
+			}
+		case '}':
+			if s.object != nil && s.depth == s.arrayDepth+1 {
+				s.flush()
+			}
+			s.close()
+		case ']':
+			if s.depth == s.arrayDepth {
+				s.arrayDepth = 0
+			}
+			s.close()
+		}
+	}
+}
+
+// close leaves the current nesting level, recovering from stray brackets in
+// text around the JSON.
+func (s *suggestionStream) close() {
+	if s.depth > 0 {
+		s.depth--
+	}
+	if s.depth < s.arrayDepth {
+		s.arrayDepth = 0
+		s.object = nil
+	}
+}
+
+// flush decodes the captured object and emits it if it is a suggestion.
+func (s *suggestionStream) flush() {
+	var suggestion Suggestion
+	err := json.Unmarshal(s.object, &suggestion)
+	s.object = nil
+
+	if err == nil && suggestion.Title != "" {
+		s.emit(suggestion)
+	}
+}
@@ -0,0 +1,247 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestSuggestionStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []string
+		wantIDs []string
+	}{
+		{
+			name: "bare array split across chunks",
+			chunks: []string{
+				`[{"id":"miso-1A","ti`, `tle":"t","body":"b"},`,
+				`{"id":"miso-1B","title":"t","body":"b"}]`,
+			},
+			wantIDs: []string{"miso-1A", "miso-1B"},
+		},
+		{
+			name: "structured envelope",
+			chunks: []string{
+				`{"suggestions":[{"id":"miso-1A","title":"t","body":"b",`,
+				`"original":"","suggestion":""}]}`,
+			},
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name: "brackets and braces inside strings",
+			chunks: []string{
+				`[{"id":"miso-1A","title":"Use a[i]","body":"func f() { \"}\" }"}]`,
+			},
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name: "prose with brackets before the array",
+			chunks: []string{
+				"See items [1] and [2].\n```json\n",
+				`[{"id":"miso-1A","title":"t","body":"b"}]`,
+				"\n```",
+			},
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name: "incomplete object is not emitted",
+			chunks: []string{
+				`[{"id":"miso-1A","title":"t","body":"b"},{"id":"miso-1B","ti`,
+			},
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name:    "no JSON",
+			chunks:  []string{"I could not review this file."},
+			wantIDs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var got []string
+				stream := newSuggestionStream(
+					func(s Suggestion) {
+						got = append(got, s.ID)
+					},
+				)
+				for _, chunk := range tt.chunks {
+					stream.Write([]byte(chunk))
+				}
+
+				if len(got) != len(tt.wantIDs) {
+					t.Fatalf("emitted %v, want %v", got, tt.wantIDs)
+				}
+				for i := range got {
+					if got[i] != tt.wantIDs[i] {
+						t.Errorf("suggestion %d ID = %s, want %s", i, got[i], tt.wantIDs[i])
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_ReviewStream(t *testing.T) {
+	chunks := []string{
+		`[{"id":"miso-1A","title":"t","body":"b"},`,
+		`{"id":"miso-1B","title":"t",`,
+		`"body":"b"}]`,
+	}
+
+	var streamed bool
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var req map[string]any
+				json.NewDecoder(r.Body).Decode(&req)
+				streamed, _ = req["stream"].(bool)
+
+				// Answer with server-sent events in the OpenAI chunk format
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, chunk := range chunks {
+					data, _ := json.Marshal(
+						map[string]any{
+							"id":     "chatcmpl-1",
+							"object": "chat.completion.chunk",
+							"choices": []map[string]any{
+								{"index": 0, "delta": map[string]any{"content": chunk}},
+							},
+						},
+					)
+					fmt.Fprintf(w, "data: %s\n\n", data)
+				}
+				fmt.Fprint(
+					w, `data: {"id":"chatcmpl-1","choices":[],"usage":`+
+						`{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`+"\n\n",
+				)
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderOpenAICompatible,
+			BaseURL:  server.URL,
+			Model:    "local-model",
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	var got []string
+	result, err := reviewer.ReviewStream(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+		&Stream{
+			Suggestion: func(s Suggestion) {
+				got = append(got, s.ID)
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("ReviewStream() error = %v", err)
+	}
+
+	if !streamed {
+		t.Error("Expected a streaming request")
+	}
+	if len(got) != 2 || got[0] != "miso-1A" || got[1] != "miso-1B" {
+		t.Errorf("Streamed suggestions = %v, want [miso-1A miso-1B]", got)
+	}
+	if len(result.Suggestions) != 2 {
+		t.Errorf("Expected 2 suggestions in the result, got %d", len(result.Suggestions))
+	}
+	if result.TokensUsed != 15 {
+		t.Errorf("TokensUsed = %d, want 15", result.TokensUsed)
+	}
+}
+
+func TestCodeReviewer_ReviewStream_Restart(t *testing.T) {
+	// The streamed response has an invalid severity; the repair is answered
+	// in full
+	var requests []map[string]any
+	repair := newScriptedStub(t, []string{stubSuggestions}, &requests)
+	defer repair.Close()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				var req map[string]any
+				json.Unmarshal(body, &req)
+				if streamed, _ := req["stream"].(bool); !streamed {
+					r.Body = io.NopCloser(bytes.NewReader(body))
+					repair.Config.Handler.ServeHTTP(w, r)
+					return
+				}
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, chunk := range []string{
+					`[{"id":"miso-1B","title":"t","body":"b"},`,
+					`{"id":"miso-1C","title":"t","body":"b","severity":"bogus"}]`,
+				} {
+					data, _ := json.Marshal(
+						map[string]any{
+							"id":     "chatcmpl-1",
+							"object": "chat.completion.chunk",
+							"choices": []map[string]any{
+								{"index": 0, "delta": map[string]any{"content": chunk}},
+							},
+						},
+					)
+					fmt.Fprintf(w, "data: %s\n\n", data)
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+			},
+		),
+	)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider:       config.ProviderOpenAICompatible,
+			BaseURL:        server.URL,
+			Model:          "local-model",
+			RepairAttempts: 1,
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	var events []string
+	result, err := reviewer.ReviewStream(
+		context.Background(), config.DefaultConfig(), "package main", "main.go",
+		&Stream{
+			Suggestion: func(s Suggestion) {
+				events = append(events, s.ID)
+			},
+			Restart: func() {
+				events = append(events, "restart")
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("ReviewStream() error = %v", err)
+	}
+
+	// Repairs aren't streamed, so the restart is the last event
+	if len(events) != 2 || events[0] != "miso-1B" || events[1] != "restart" {
+		t.Errorf("Stream events = %v, want [miso-1B restart]", events)
+	}
+	if len(result.Suggestions) != 1 || result.Suggestions[0].ID != "miso-1A" {
+		t.Errorf("Expected the repaired suggestion, got %+v", result.Suggestions)
+	}
+}
@@ -0,0 +1,158 @@
+package agents
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity is how serious a finding is.
+type Severity string
+
+// Severities, from most to least severe.
+const (
+	SeverityCritical   Severity = "critical"
+	SeverityWarning    Severity = "warning"
+	SeveritySuggestion Severity = "suggestion"
+)
+
+// Severities lists the valid severities, from most to least severe.
+var Severities = []Severity{SeverityCritical, SeverityWarning, SeveritySuggestion}
+
+// Category is the kind of issue a finding describes.
+type Category string
+
+// Categories of findings.
+const (
+	CategoryBug             Category = "bug"
+	CategorySecurity        Category = "security"
+	CategoryPerformance     Category = "performance"
+	CategoryBreaking        Category = "breaking"
+	CategoryArchitecture    Category = "architecture"
+	CategoryMaintainability Category = "maintainability"
+	CategoryStyle           Category = "style"
+)
+
+// Categories lists the valid categories.
+var Categories = []Category{
+	CategoryBug, CategorySecurity, CategoryPerformance, CategoryBreaking,
+	CategoryArchitecture, CategoryMaintainability, CategoryStyle,
+}
+
+// titleSeverities maps the emoji of the title labels to a severity, for
+// responses that leave the severity out.
+var titleSeverities = []struct {
+	emoji    string
+	severity Severity
+}{
+	{"🔴", SeverityCritical},
+	{"🟡", SeverityWarning},
+	{"⚠️", SeverityWarning},
+	{"❌", SeverityWarning},
+	{"💡", SeveritySuggestion},
+}
+
+// normalize validates the typed fields of a suggestion from the model,
+// lowercasing the enums, inferring a missing severity from the title and
+// defaulting the end line to the start line.
+func (s *Suggestion) normalize() error {
+	s.Severity = Severity(strings.ToLower(strings.TrimSpace(string(s.Severity))))
+	if s.Severity == "" {
+		s.Severity = severityFromTitle(s.Title)
+	}
+	if !slices.Contains(Severities, s.Severity) {
+		return fmt.Errorf(
+			"invalid severity %q, must be one of %s", s.Severity,
+			joinEnum(Severities),
+		)
+	}
+
+	s.Category = Category(strings.ToLower(strings.TrimSpace(string(s.Category))))
+	if s.Category != "" && !slices.Contains(Categories, s.Category) {
+		return fmt.Errorf(
+			"invalid category %q, must be one of %s", s.Category,
+			joinEnum(Categories),
+		)
+	}
+
+	if s.StartLine < 0 || s.EndLine < 0 {
+		return fmt.Errorf("line numbers must not be negative")
+	}
+	if s.EndLine == 0 {
+		s.EndLine = s.StartLine
+	}
+	if s.EndLine < s.StartLine {
+		return fmt.Errorf(
+			"end_line %d is before start_line %d", s.EndLine, s.StartLine,
+		)
+	}
+
+	if s.Confidence < 0 || s.Confidence > 1 {
+		return fmt.Errorf("confidence must be between 0 and 1, got %g", s.Confidence)
+	}
+
+	s.Guide = strings.TrimSpace(s.Guide)
+	return nil
+}
+
+// coerce replaces the invalid typed fields of a suggestion with defaults, so
+// normalize accepts it, and returns a description of each replaced field.
+func (s *Suggestion) coerce() []string {
+	var replaced []string
+
+	severity := Severity(strings.ToLower(strings.TrimSpace(string(s.Severity))))
+	if severity != "" && !slices.Contains(Severities, severity) {
+		replaced = append(replaced, fmt.Sprintf("invalid severity %q", s.Severity))
+		s.Severity = "" // Inferred from the title
+	}
+
+	category := Category(strings.ToLower(strings.TrimSpace(string(s.Category))))
+	if category != "" && !slices.Contains(Categories, category) {
+		replaced = append(replaced, fmt.Sprintf("invalid category %q", s.Category))
+		s.Category = ""
+	}
+
+	if s.StartLine < 0 || s.EndLine < 0 || (s.EndLine > 0 && s.EndLine < s.StartLine) {
+		replaced = append(
+			replaced, fmt.Sprintf("invalid lines %d-%d", s.StartLine, s.EndLine),
+		)
+		s.StartLine, s.EndLine = 0, 0
+	}
+
+	if s.Confidence < 0 || s.Confidence > 1 {
+		replaced = append(replaced, fmt.Sprintf("invalid confidence %g", s.Confidence))
+		s.Confidence = 0
+	}
+
+	return replaced
+}
+
+// normalizeSuggestions validates every suggestion of a response.
+func normalizeSuggestions(suggestions []Suggestion) error {
+	for i := range suggestions {
+		if err := suggestions[i].normalize(); err != nil {
+			return fmt.Errorf("suggestion %s: %w", suggestions[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// severityFromTitle returns the severity of the emoji label of a title,
+// or a warning when the title has none.
+func severityFromTitle(title string) Severity {
+	for _, label := range titleSeverities {
+		if strings.HasPrefix(strings.TrimSpace(title), label.emoji) {
+			return label.severity
+		}
+	}
+	return SeverityWarning
+}
+
+// joinEnum lists enum values for error messages and prompts.
+func joinEnum[T ~string](values []T) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(quoted, ", ")
+}
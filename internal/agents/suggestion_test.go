@@ -0,0 +1,80 @@
+package agents
+
+import (
+	"testing"
+)
+
+func TestSuggestion_Normalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		suggestion Suggestion
+		want       Suggestion
+		wantErr    bool
+	}{
+		{
+			name: "typed fields",
+			suggestion: Suggestion{
+				Severity: " Critical", Category: "Security", StartLine: 3,
+				EndLine: 5, Confidence: 0.8, Guide: " go.md ",
+			},
+			want: Suggestion{
+				Severity: SeverityCritical, Category: CategorySecurity,
+				StartLine: 3, EndLine: 5, Confidence: 0.8, Guide: "go.md",
+			},
+		},
+		{
+			name:       "severity from the title",
+			suggestion: Suggestion{Title: "💡 Suggestion: Rename variable"},
+			want: Suggestion{
+				Title:    "💡 Suggestion: Rename variable",
+				Severity: SeveritySuggestion,
+			},
+		},
+		{
+			name:       "title without a label",
+			suggestion: Suggestion{Title: "Rename variable"},
+			want:       Suggestion{Title: "Rename variable", Severity: SeverityWarning},
+		},
+		{
+			name:       "single line",
+			suggestion: Suggestion{Severity: SeverityWarning, StartLine: 7},
+			want:       Suggestion{Severity: SeverityWarning, StartLine: 7, EndLine: 7},
+		},
+		{
+			name:       "invalid category",
+			suggestion: Suggestion{Severity: SeverityWarning, Category: "typo"},
+			wantErr:    true,
+		},
+		{
+			name:       "negative line",
+			suggestion: Suggestion{Severity: SeverityWarning, StartLine: -1},
+			wantErr:    true,
+		},
+		{
+			name:       "confidence out of range",
+			suggestion: Suggestion{Severity: SeverityWarning, Confidence: 80},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := tt.suggestion
+				err := got.normalize()
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("normalize() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				if got.Severity != tt.want.Severity || got.Category != tt.want.Category ||
+					got.StartLine != tt.want.StartLine || got.EndLine != tt.want.EndLine ||
+					got.Confidence != tt.want.Confidence || got.Guide != tt.want.Guide {
+					t.Errorf("normalize() = %+v, want %+v", got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,238 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/prompts"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// errNoVerdicts is returned when a verification response holds no scores.
+var errNoVerdicts = errors.New("no JSON array of scores found")
+
+// verifyRepairInstruction asks the model to resend scores that failed to parse.
+const verifyRepairInstruction = `Your previous response could not be parsed: %v
+
+Reply again with only the JSON array of score objects described above, without any text or markdown around it.`
+
+// verdict is the verification score of a single suggestion.
+type verdict struct {
+	ID         string   `json:"id"`
+	Confidence *float64 `json:"confidence"` // Required; nil marks something else
+	Reason     string   `json:"reason"`
+}
+
+// verification is the cached outcome of a verification call.
+type verification struct {
+	Verdicts []verdict
+}
+
+// verify scores the suggestions of result in a second call and drops those
+// below the configured confidence threshold. subject is the reviewed code or
+// diff. The usage of the call is added to result.
+func (cr *CodeReviewer) verify(
+	ctx context.Context, cfg *config.Config, subject string, filename string,
+	settings callSettings, result *ReviewResult,
+) error {
+	if !cfg.Verify.Enabled || len(result.Suggestions) == 0 {
+		return nil
+	}
+
+	if cfg.Verify.Model != "" {
+		settings.model = cfg.Verify.Model
+	}
+
+	// Models may repeat suggestion IDs, so the verifier refers to each
+	// suggestion by its position instead
+	scored := make([]Suggestion, len(result.Suggestions))
+	for i, suggestion := range result.Suggestions {
+		suggestion.ID = verdictID(i)
+		scored[i] = suggestion
+	}
+	suggestions, err := json.MarshalIndent(scored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode suggestions: %w", err)
+	}
+	prompt, err := prompts.Verify(subject, filename, string(suggestions))
+	if err != nil {
+		return fmt.Errorf("failed to format verification prompt: %w", err)
+	}
+
+	verdicts, usage, err := cr.callVerifier(ctx, prompt, settings)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+	cr.applyCost(cfg, usage)
+
+	result.TokensUsed += usage.TokensUsed
+	result.InputTokens += usage.InputTokens
+	result.OutputTokens += usage.OutputTokens
+	result.Cost += usage.Cost
+	result.Priced = result.Priced && usage.Priced
+	result.Cached = result.Cached && usage.Cached
+
+	applyVerdicts(result, verdicts, cfg.Verify.Threshold)
+	return nil
+}
+
+// verdictID returns the ID the verifier knows the suggestion at index i by.
+func verdictID(i int) string {
+	return fmt.Sprintf("suggestion-%d", i+1)
+}
+
+// applyVerdicts sets the confidence of each scored suggestion and moves
+// those below threshold to result.Dropped. Suggestions the verifier didn't
+// score are kept as unverified, without the confidence the model claimed.
+func applyVerdicts(result *ReviewResult, verdicts []verdict, threshold float64) {
+	scores := make(map[string]float64, len(verdicts))
+	for _, v := range verdicts {
+		scores[v.ID] = min(max(*v.Confidence, 0), 1)
+	}
+
+	kept := make([]Suggestion, 0, len(result.Suggestions))
+	for i, suggestion := range result.Suggestions {
+		score, ok := scores[verdictID(i)]
+		if !ok {
+			suggestion.Confidence = 0
+			kept = append(kept, suggestion)
+			continue
+		}
+
+		suggestion.Confidence = score
+		if score < threshold {
+			result.Dropped = append(result.Dropped, suggestion)
+			continue
+		}
+		kept = append(kept, suggestion)
+	}
+	result.Suggestions = kept
+}
+
+// callVerifier returns the verdicts of a verification prompt, from the
+// response cache when it holds an identical call. The returned result only
+// carries the usage of the call.
+func (cr *CodeReviewer) callVerifier(
+	ctx context.Context, prompt string, settings callSettings,
+) ([]verdict, *ReviewResult, error) {
+	key, cacheable := cr.cacheKey(prompt, settings)
+	if cr.cache != nil && cacheable {
+		var cached verification
+		if cr.cache.Get(key, &cached) {
+			return cached.Verdicts, &ReviewResult{
+				Model:  settings.model,
+				Cached: true,
+			}, nil
+		}
+	}
+
+	usage := &ReviewResult{Model: settings.model}
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+	}
+	options := []llms.CallOption{
+		llms.WithModel(settings.model),
+		llms.WithTemperature(settings.temperature),
+	}
+	if cr.maxTokens > 0 {
+		options = append(options, llms.WithMaxTokens(cr.maxTokens))
+	}
+	if cr.structured == structuredJSONMode {
+		options = append(options, llms.WithJSONMode())
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := cr.verifier.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("LLM call failed: %w", err)
+		}
+		addUsage(usage, resp)
+
+		content := responseContent(resp)
+		verdicts, err := parseVerdicts(content)
+		if err == nil {
+			if cr.cache != nil && cacheable {
+				// A failed write only costs a cache miss on the next run
+				_ = cr.cache.Put(key, verification{Verdicts: verdicts})
+			}
+			return verdicts, usage, nil
+		}
+
+		if attempt >= cr.repairAttempts {
+			return nil, nil, fmt.Errorf(
+				"failed to parse scores after %d attempts: %w", attempt+1, err,
+			)
+		}
+
+		messages = append(
+			messages,
+			llms.TextParts(llms.ChatMessageTypeAI, content),
+			llms.TextParts(
+				llms.ChatMessageTypeHuman,
+				fmt.Sprintf(verifyRepairInstruction, err),
+			),
+		)
+	}
+}
+
+// parseVerdicts extracts the scores from a verification response: a JSON
+// array, or an object wrapping it, possibly surrounded by prose.
+func parseVerdicts(content string) ([]verdict, error) {
+	trimmed := strings.TrimSpace(content)
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] != '[' && trimmed[i] != '{' {
+			continue
+		}
+
+		dec := json.NewDecoder(strings.NewReader(trimmed[i:]))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			continue
+		}
+		if verdicts, ok := decodeVerdicts(raw); ok {
+			return verdicts, nil
+		}
+	}
+
+	return nil, errNoVerdicts
+}
+
+// decodeVerdicts decodes data as an array of verdicts, or as an object with
+// a single array field holding them.
+func decodeVerdicts(data []byte) ([]verdict, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, false
+	}
+
+	switch data[0] {
+	case '[':
+		var verdicts []verdict
+		if err := json.Unmarshal(data, &verdicts); err != nil {
+			return nil, false
+		}
+		// Arrays of other objects, e.g. the suggestions quoted back, decode
+		// without scores
+		for _, v := range verdicts {
+			if v.Confidence == nil {
+				return nil, false
+			}
+		}
+		return verdicts, true
+	case '{':
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(data, &envelope); err != nil || len(envelope) != 1 {
+			return nil, false
+		}
+		for _, value := range envelope {
+			return decodeVerdicts(value)
+		}
+	}
+
+	return nil, false
+}
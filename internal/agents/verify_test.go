@@ -0,0 +1,170 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestParseVerdicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "bare array",
+			content: `[{"id":"miso-1A","confidence":0.9,"reason":"r"}]`,
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name:    "wrapped in an object",
+			content: `{"scores":[{"id":"miso-1A","confidence":0.2,"reason":"r"}]}`,
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name: "prose quoting the suggestions first",
+			content: "Reviewing " + stubSuggestions + ":\n" +
+				"```json\n[{\"id\":\"miso-1A\",\"confidence\":0,\"reason\":\"r\"}]\n```",
+			wantIDs: []string{"miso-1A"},
+		},
+		{
+			name:    "suggestions without scores",
+			content: stubSuggestions,
+			wantErr: true,
+		},
+		{
+			name:    "no JSON",
+			content: "All suggestions look fine.",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				verdicts, err := parseVerdicts(tt.content)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf(
+						"parseVerdicts() error = %v, wantErr %v", err, tt.wantErr,
+					)
+				}
+				if tt.wantErr {
+					return
+				}
+
+				if len(verdicts) != len(tt.wantIDs) {
+					t.Fatalf(
+						"parseVerdicts() returned %d verdicts, want %d",
+						len(verdicts), len(tt.wantIDs),
+					)
+				}
+				for i, id := range tt.wantIDs {
+					if verdicts[i].ID != id {
+						t.Errorf("verdict %d ID = %s, want %s", i, verdicts[i].ID, id)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestCodeReviewer_Verify(t *testing.T) {
+	firstPass := `[` +
+		`{"id":"miso-1A","title":"Unchecked error","body":"b"},` +
+		`{"id":"miso-1B","title":"Rename variable","body":"b"},` +
+		`{"id":"miso-1C","title":"Unscored","body":"b","confidence":0.9}]`
+	scores := `[` +
+		`{"id":"suggestion-1","confidence":1.4,"reason":"The error is dropped."},` +
+		`{"id":"suggestion-2","confidence":0.1,"reason":"A nitpick."}]`
+
+	var requests []map[string]any
+	server := newScriptedStub(t, []string{firstPass, scores}, &requests)
+	defer server.Close()
+
+	reviewer, err := NewCodeReviewer(
+		config.LLM{
+			Provider: config.ProviderOpenAICompatible,
+			BaseURL:  server.URL,
+			Model:    "local-model",
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewCodeReviewer() error = %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Verify.Enabled = true
+	cfg.Verify.Threshold = 0.5
+	cfg.Verify.Model = "judge-model"
+
+	result, err := reviewer.Review(
+		context.Background(), cfg, "package main", "main.go",
+	)
+	if err != nil {
+		t.Fatalf("Review() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected a review and a verification request, got %d", len(requests))
+	}
+	if requests[1]["model"] != "judge-model" {
+		t.Errorf("Expected the verification model, got %v", requests[1]["model"])
+	}
+	messages, _ := requests[1]["messages"].([]any)
+	prompt, _ := messages[len(messages)-1].(map[string]any)["content"].(string)
+	if !strings.Contains(prompt, "Rename variable") ||
+		!strings.Contains(prompt, `"id": "suggestion-2"`) {
+		t.Errorf("Expected the numbered suggestions in the verification prompt")
+	}
+
+	// Unscored suggestions are kept
+	var kept []string
+	for _, suggestion := range result.Suggestions {
+		kept = append(kept, suggestion.ID)
+	}
+	if strings.Join(kept, ",") != "miso-1A,miso-1C" {
+		t.Errorf("Expected miso-1A and miso-1C to be kept, got %v", kept)
+	}
+	if got := result.Suggestions[0].File; got != "main.go" {
+		t.Errorf("Expected the file set on the suggestions, got %q", got)
+	}
+	if got := result.Suggestions[0].Confidence; got != 1 {
+		t.Errorf("Expected the confidence clamped to 1, got %g", got)
+	}
+	if got := result.Suggestions[1].Confidence; got != 0 {
+		t.Errorf("Expected no confidence for the unscored suggestion, got %g", got)
+	}
+	if len(result.Dropped) != 1 || result.Dropped[0].ID != "miso-1B" {
+		t.Errorf("Expected miso-1B to be dropped, got %+v", result.Dropped)
+	}
+	if result.TokensUsed != 30 {
+		t.Errorf("Expected the usage of both calls, got %d tokens", result.TokensUsed)
+	}
+}
+
+func TestApplyVerdicts_DuplicateIDs(t *testing.T) {
+	result := &ReviewResult{
+		Suggestions: []Suggestion{
+			{ID: "miso-1A", Title: "Unchecked error"},
+			{ID: "miso-1A", Title: "Rename variable"},
+		},
+	}
+	high, low := 0.9, 0.1
+	verdicts := []verdict{
+		{ID: "suggestion-1", Confidence: &high},
+		{ID: "suggestion-2", Confidence: &low},
+	}
+
+	applyVerdicts(result, verdicts, 0.5)
+
+	if len(result.Suggestions) != 1 || result.Suggestions[0].Title != "Unchecked error" {
+		t.Errorf("Expected the first suggestion to be kept, got %+v", result.Suggestions)
+	}
+	if len(result.Dropped) != 1 || result.Dropped[0].Title != "Rename variable" {
+		t.Errorf("Expected the second suggestion to be dropped, got %+v", result.Dropped)
+	}
+}
@@ -0,0 +1,139 @@
+package budget
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+// Adjustment actions taken to stay within the budget.
+const (
+	ActionSkipped   = "skipped"
+	ActionTruncated = "truncated"
+)
+
+// Adjustment records a file that was skipped or truncated and why.
+type Adjustment struct {
+	File   string
+	Action string
+	Reason string
+}
+
+// Reservation holds the estimated usage of a call until it completes.
+type Reservation struct {
+	tokens int
+	cost   float64
+}
+
+// Budget tracks token and cost usage against the limits of a run.
+// It is safe for concurrent use.
+type Budget struct {
+	limits config.Budget
+
+	mu          sync.Mutex
+	usedTokens  int
+	usedCost    float64
+	adjustments []Adjustment
+}
+
+// New creates a budget with the given limits.
+func New(limits config.Budget) *Budget {
+	return &Budget{limits: limits}
+}
+
+// Limits returns the configured limits.
+func (b *Budget) Limits() config.Budget {
+	return b.limits
+}
+
+// Enabled reports whether any limit is set.
+func (b *Budget) Enabled() bool {
+	return b.limits.MaxTokens > 0 || b.limits.MaxCost > 0 ||
+		b.limits.MaxFileTokens > 0
+}
+
+// Reserve claims the estimated tokens and cost of a call. It returns an
+// error describing the exceeded limit, and records the file as skipped,
+// when the call would not fit in the remaining budget.
+func (b *Budget) Reserve(file string, tokens int, cost float64) (
+	Reservation, error,
+) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var err error
+	switch {
+	case b.limits.MaxTokens > 0 && b.usedTokens+tokens > b.limits.MaxTokens:
+		err = fmt.Errorf(
+			"needs an estimated %d tokens but only %d of the %d token budget remain",
+			tokens, b.limits.MaxTokens-b.usedTokens, b.limits.MaxTokens,
+		)
+	case b.limits.MaxCost > 0 && b.usedCost+cost > b.limits.MaxCost:
+		err = fmt.Errorf(
+			"needs an estimated $%.4f but only $%.4f of the $%.4f cost budget remain",
+			cost, b.limits.MaxCost-b.usedCost, b.limits.MaxCost,
+		)
+	}
+
+	if err != nil {
+		b.adjustments = append(
+			b.adjustments,
+			Adjustment{File: file, Action: ActionSkipped, Reason: err.Error()},
+		)
+		return Reservation{}, err
+	}
+
+	b.usedTokens += tokens
+	b.usedCost += cost
+	return Reservation{tokens: tokens, cost: cost}, nil
+}
+
+// Commit replaces a reservation with the actual usage of the call.
+func (b *Budget) Commit(r Reservation, tokens int, cost float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.usedTokens += tokens - r.tokens
+	b.usedCost += cost - r.cost
+}
+
+// Release returns a reservation whose call failed or was cancelled.
+func (b *Budget) Release(r Reservation) {
+	b.Commit(r, 0, 0)
+}
+
+// Record adds an adjustment made to a file, such as a truncation.
+func (b *Budget) Record(file, action, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.adjustments = append(
+		b.adjustments, Adjustment{File: file, Action: action, Reason: reason},
+	)
+}
+
+// Adjustments returns the files that were skipped or truncated, sorted by
+// file so the report doesn't depend on the order concurrent reviews ran in.
+func (b *Budget) Adjustments() []Adjustment {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	adjustments := append([]Adjustment(nil), b.adjustments...)
+	slices.SortStableFunc(
+		adjustments, func(a, b Adjustment) int {
+			return strings.Compare(a.File, b.File)
+		},
+	)
+	return adjustments
+}
+
+// Used returns the tokens and cost consumed so far.
+func (b *Budget) Used() (int, float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.usedTokens, b.usedCost
+}
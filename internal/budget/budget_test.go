@@ -0,0 +1,154 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestBudgetReserve(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      config.Budget
+		tokens      []int
+		costs       []float64
+		wantSkipped []bool
+	}{
+		{
+			name:        "no limits",
+			limits:      config.Budget{},
+			tokens:      []int{1000, 1000},
+			costs:       []float64{1, 1},
+			wantSkipped: []bool{false, false},
+		},
+		{
+			name:        "token budget",
+			limits:      config.Budget{MaxTokens: 2500},
+			tokens:      []int{1000, 1000, 1000},
+			costs:       []float64{0, 0, 0},
+			wantSkipped: []bool{false, false, true},
+		},
+		{
+			name:        "cost budget",
+			limits:      config.Budget{MaxCost: 0.05},
+			tokens:      []int{100, 100, 100},
+			costs:       []float64{0.03, 0.03, 0.01},
+			wantSkipped: []bool{false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				b := New(tt.limits)
+				for i := range tt.tokens {
+					_, err := b.Reserve("file.go", tt.tokens[i], tt.costs[i])
+					if (err != nil) != tt.wantSkipped[i] {
+						t.Errorf(
+							"Reserve() call %d error = %v, wantSkipped %v", i,
+							err, tt.wantSkipped[i],
+						)
+					}
+				}
+
+				skipped := 0
+				for _, want := range tt.wantSkipped {
+					if want {
+						skipped++
+					}
+				}
+				if got := len(b.Adjustments()); got != skipped {
+					t.Errorf("Adjustments() = %d entries, want %d", got, skipped)
+				}
+			},
+		)
+	}
+}
+
+func TestBudgetCommit(t *testing.T) {
+	b := New(config.Budget{MaxTokens: 1000})
+
+	r, err := b.Reserve("a.go", 800, 0.01)
+	if err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	// The call used fewer tokens than estimated, freeing budget
+	b.Commit(r, 300, 0.002)
+
+	tokens, cost := b.Used()
+	if tokens != 300 || cost != 0.002 {
+		t.Errorf("Used() = %d, %v, want 300, 0.002", tokens, cost)
+	}
+
+	r, err = b.Reserve("b.go", 700, 0)
+	if err != nil {
+		t.Fatalf("Reserve() after commit error = %v", err)
+	}
+	b.Release(r)
+
+	if tokens, _ := b.Used(); tokens != 300 {
+		t.Errorf("Used() after release = %d, want 300", tokens)
+	}
+}
+
+func TestBudgetEnabled(t *testing.T) {
+	if New(config.Budget{}).Enabled() {
+		t.Error("Expected a budget without limits to be disabled")
+	}
+	if !New(config.Budget{MaxFileTokens: 100}).Enabled() {
+		t.Error("Expected a budget with a per-file limit to be enabled")
+	}
+}
+
+func TestApproximateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 2},
+		{"abcdef", 2},
+		{"héllo wörld", 4},
+	}
+
+	for _, tt := range tests {
+		if got := approximateTokens(tt.text); got != tt.want {
+			t.Errorf("approximateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTimeoutLoader(t *testing.T) {
+	loader := &timeoutLoader{base: blockingLoader{}, timeout: time.Millisecond}
+	if _, err := loader.LoadTiktokenBpe("cl100k_base.tiktoken"); err == nil {
+		t.Error("LoadTiktokenBpe() error = nil, want a timeout")
+	}
+}
+
+// blockingLoader never finishes loading, like a download without a network.
+type blockingLoader struct{}
+
+func (blockingLoader) LoadTiktokenBpe(string) (map[string]int, error) {
+	select {}
+}
+
+func TestBudgetAdjustmentsSorted(t *testing.T) {
+	b := New(config.Budget{})
+	b.Record("b.go", ActionTruncated, "reason")
+	b.Record("a.go", ActionSkipped, "reason")
+	b.Record("b.go", ActionSkipped, "reason")
+
+	got := b.Adjustments()
+	want := []Adjustment{
+		{File: "a.go", Action: ActionSkipped, Reason: "reason"},
+		{File: "b.go", Action: ActionTruncated, Reason: "reason"},
+		{File: "b.go", Action: ActionSkipped, Reason: "reason"},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Adjustments()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package budget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// encodingName is the tokenizer used for estimates. Providers tokenize
+// differently, so counts are an approximation for non-OpenAI models.
+const encodingName = "cl100k_base"
+
+// charsPerToken approximates token counts when the tokenizer is unavailable,
+// for example when its vocabulary can't be downloaded in an offline CI job.
+// Code averages fewer characters per token than prose, so the approximation
+// uses 3 rather than the usual 4 to err on the high side.
+const charsPerToken = 3
+
+// loadTimeout bounds the one-time download of the tokenizer vocabulary.
+// tiktoken caches it under TIKTOKEN_CACHE_DIR once downloaded.
+const loadTimeout = 10 * time.Second
+
+var (
+	encoderOnce sync.Once
+	encoder     *tiktoken.Tiktoken
+)
+
+// CountTokens estimates the number of tokens in text.
+func CountTokens(text string) int {
+	encoderOnce.Do(
+		func() {
+			// Fall back to the approximation if the encoding can't be loaded
+			tiktoken.SetBpeLoader(
+				&timeoutLoader{
+					base:    tiktoken.NewDefaultBpeLoader(),
+					timeout: loadTimeout,
+				},
+			)
+			encoder, _ = tiktoken.GetEncoding(encodingName)
+		},
+	)
+
+	if encoder == nil {
+		return approximateTokens(text)
+	}
+	return len(encoder.Encode(text, nil, nil))
+}
+
+// approximateTokens estimates tokens from the number of characters.
+func approximateTokens(text string) int {
+	return (len([]rune(text)) + charsPerToken - 1) / charsPerToken
+}
+
+// timeoutLoader gives up on loading a vocabulary after a timeout, since the
+// default loader downloads it without one.
+type timeoutLoader struct {
+	base    tiktoken.BpeLoader
+	timeout time.Duration
+}
+
+// LoadTiktokenBpe loads the vocabulary or fails once the timeout elapses.
+func (l *timeoutLoader) LoadTiktokenBpe(file string) (map[string]int, error) {
+	type result struct {
+		ranks map[string]int
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		ranks, err := l.base.LoadTiktokenBpe(file)
+		done <- result{ranks, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.ranks, r.err
+	case <-time.After(l.timeout):
+		return nil, fmt.Errorf("loading %s timed out after %s", file, l.timeout)
+	}
+}
@@ -0,0 +1,104 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/git"
+)
+
+// ErrTooLarge is returned when not even the smallest truncation of a file
+// fits the per-file token limit.
+var ErrTooLarge = errors.New("does not fit the per-file token limit")
+
+// FitDiff keeps the leading hunks of a diff whose prompt fits in maxTokens.
+// count returns the prompt tokens of a candidate diff. The diff is returned
+// unchanged if it already fits.
+func FitDiff(
+	diffData *git.DiffData, maxTokens int,
+	count func(*git.DiffData) (int, error),
+) (*git.DiffData, error) {
+	withHunks := func(n int) *git.DiffData {
+		fitted := *diffData
+		fitted.Hunks = diffData.Hunks[:n]
+		return &fitted
+	}
+
+	n, ok, err := largestFit(
+		len(diffData.Hunks), func(n int) (bool, error) {
+			tokens, err := count(withHunks(n))
+			return tokens <= maxTokens, err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTooLarge
+	}
+
+	return withHunks(n), nil
+}
+
+// FitLines keeps the leading lines of code whose prompt fits in maxTokens,
+// ending truncated code with a note so the model knows the file continues.
+// The code is returned unchanged if it already fits.
+func FitLines(
+	code string, maxTokens int, count func(string) (int, error),
+) (string, error) {
+	lines := strings.Split(code, "\n")
+	withLines := func(n int) string {
+		if n == len(lines) {
+			return code
+		}
+		return strings.Join(lines[:n], "\n") + fmt.Sprintf(
+			"\n[... %d more lines truncated to fit the token budget]",
+			len(lines)-n,
+		)
+	}
+
+	n, ok, err := largestFit(
+		len(lines), func(n int) (bool, error) {
+			tokens, err := count(withLines(n))
+			return tokens <= maxTokens, err
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrTooLarge
+	}
+
+	return withLines(n), nil
+}
+
+// largestFit returns the largest n in [1, total] for which fits is true,
+// assuming fits is monotonic, and false if even n = 1 doesn't fit. It uses a
+// binary search so large files only need a few estimates.
+func largestFit(total int, fits func(n int) (bool, error)) (int, bool, error) {
+	ok, err := fits(total)
+	if err != nil {
+		return 0, false, err
+	}
+	if ok {
+		return total, true, nil
+	}
+
+	lo, hi := 0, total // fits(lo) holds unless lo is 0; fits(hi) fails
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, lo > 0, nil
+}
@@ -0,0 +1,104 @@
+package budget
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/git"
+)
+
+// countChars stands in for a prompt estimate: one token per character.
+func countChars(s string) (int, error) {
+	return len(s), nil
+}
+
+func TestFitLines(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 4; i++ {
+		lines = append(lines, "line"+string(rune('0'+i))+strings.Repeat(".", 25))
+	}
+	code := strings.Join(lines, "\n")
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		wantLines int
+		wantErr   error
+	}{
+		{"fits", 200, 4, nil},
+		{"truncated", 120, 2, nil},
+		{"too large", 10, 0, ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := FitLines(code, tt.maxTokens, countChars)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FitLines() error = %v, want %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+
+				if len(got) > tt.maxTokens {
+					t.Errorf("FitLines() returned %d chars, over %d", len(got), tt.maxTokens)
+				}
+				for i := 1; i <= 4; i++ {
+					line := "line" + string(rune('0'+i))
+					if strings.Contains(got, line) != (i <= tt.wantLines) {
+						t.Errorf("FitLines() = %q, want the first %d lines", got, tt.wantLines)
+						break
+					}
+				}
+				if tt.wantLines < 4 && !strings.Contains(got, "truncated") {
+					t.Errorf("FitLines() = %q, want a truncation note", got)
+				}
+			},
+		)
+	}
+}
+
+func TestFitDiff(t *testing.T) {
+	diffData := &git.DiffData{
+		FilePath: "main.go",
+		Hunks:    make([]git.DiffHunk, 10),
+	}
+	// Each hunk costs 100 tokens on top of a 50 token prompt
+	count := func(d *git.DiffData) (int, error) {
+		return 50 + 100*len(d.Hunks), nil
+	}
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		wantHunks int
+		wantErr   error
+	}{
+		{"fits", 2000, 10, nil},
+		{"truncated", 480, 4, nil},
+		{"too large", 100, 0, ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := FitDiff(diffData, tt.maxTokens, count)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FitDiff() error = %v, want %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+
+				if len(got.Hunks) != tt.wantHunks {
+					t.Errorf("FitDiff() kept %d hunks, want %d", len(got.Hunks), tt.wantHunks)
+				}
+				if len(diffData.Hunks) != 10 {
+					t.Errorf("FitDiff() modified the original diff")
+				}
+			},
+		)
+	}
+}
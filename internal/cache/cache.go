@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// entryExt is the file extension of cache entries.
+const entryExt = ".json"
+
+// Cache is a content-addressed store of JSON values on disk.
+// Entries live in dir/<first two hex digits of the key>/<key>.json.
+type Cache struct {
+	dir string
+}
+
+// Stats describes the contents of a cache.
+type Stats struct {
+	Dir     string
+	Entries int
+	Bytes   int64
+}
+
+// DefaultDir returns the cache directory under the user cache dir,
+// e.g. ~/.cache/miso/responses on Linux.
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user cache dir: %w", err)
+	}
+	return filepath.Join(base, "miso", "responses"), nil
+}
+
+// New creates a cache stored in dir. The directory is created on first write.
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Key hashes the given parts into a cache key. Parts are length-prefixed so
+// different splits of the same bytes produce different keys.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the file holding the entry for key.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+entryExt)
+}
+
+// Get decodes the entry for key into v. It reports false if there is no
+// entry; unreadable entries are treated as missing.
+func (c *Cache) Get(key string, v any) bool {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// Has reports whether there is an entry for key.
+func (c *Cache) Has(key string) bool {
+	_, err := os.Stat(c.path(key))
+	return err == nil
+}
+
+// Put stores v as the entry for key. The entry is written to a temporary
+// file and renamed, so concurrent readers never see a partial entry.
+func (c *Cache) Put(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	if err := WriteFile(c.path(key), data); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes data to path through a temporary file that is renamed
+// into place, creating the directory if needed. Concurrent readers see the
+// old file or the new one, never a partial write.
+func WriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats counts the entries of the cache and their size.
+func (c *Cache) Stats() (Stats, error) {
+	stats := Stats{Dir: c.dir}
+	err := c.walk(
+		func(path string, info fs.FileInfo) error {
+			stats.Entries++
+			stats.Bytes += info.Size()
+			return nil
+		},
+	)
+	return stats, err
+}
+
+// Clear removes all entries and returns how many there were.
+func (c *Cache) Clear() (int, error) {
+	removed := 0
+	err := c.walk(
+		func(path string, _ fs.FileInfo) error {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed++
+			return nil
+		},
+	)
+	if err != nil {
+		return removed, fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return removed, nil
+}
+
+// walk calls fn for each entry of the cache. A missing cache is empty.
+func (c *Cache) walk(fn func(path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(
+		c.dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, entryExt) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return fn(path, info)
+		},
+	)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	Name  string
+	Count int
+}
+
+func TestCacheGetPut(t *testing.T) {
+	c := New(t.TempDir())
+	key := Key("prompt", "model")
+
+	var got entry
+	if c.Get(key, &got) {
+		t.Fatal("Expected a miss on an empty cache")
+	}
+
+	want := entry{Name: "review", Count: 3}
+	if err := c.Put(key, want); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if !c.Has(key) {
+		t.Error("Has() = false after Put()")
+	}
+	if !c.Get(key, &got) {
+		t.Fatal("Expected a hit after Put()")
+	}
+	if got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		same bool
+	}{
+		{"identical parts", []string{"a", "b"}, []string{"a", "b"}, true},
+		{"different parts", []string{"a", "b"}, []string{"a", "c"}, false},
+		{"same bytes split differently", []string{"ab", "c"}, []string{"a", "bc"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := Key(tt.a...) == Key(tt.b...); got != tt.same {
+					t.Errorf("Key(%v) == Key(%v) is %v, want %v", tt.a, tt.b, got, tt.same)
+				}
+			},
+		)
+	}
+}
+
+func TestCacheStatsClear(t *testing.T) {
+	c := New(t.TempDir())
+
+	// A cache that was never written to is empty
+	if stats, err := New(c.Dir() + "/missing").Stats(); err != nil || stats.Entries != 0 {
+		t.Errorf("Stats() on a missing dir = %+v, %v, want no entries", stats, err)
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := c.Put(Key(name), entry{Name: name}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Entries != 3 || stats.Bytes == 0 {
+		t.Errorf("Stats() = %+v, want 3 entries", stats)
+	}
+
+	removed, err := c.Clear()
+	if err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("Clear() removed %d entries, want 3", removed)
+	}
+
+	if stats, _ := c.Stats(); stats.Entries != 0 {
+		t.Errorf("Stats() after Clear() = %+v, want no entries", stats)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "file.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("content = %q, want %q", data, content)
+		}
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the file in the directory, got %d entries", len(entries))
+	}
+}
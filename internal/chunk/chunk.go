@@ -0,0 +1,146 @@
+// Package chunk splits large files into overlapping chunks at declaration
+// boundaries, so each can be reviewed on its own, and merges the reviews of
+// the chunks back into one.
+package chunk
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// Chunk is a range of lines of a file, reviewed on its own.
+type Chunk struct {
+	Index int    // Position of the chunk in the file, from 0
+	Start int    // First line, from 1, including the overlap with the previous chunk
+	End   int    // Last line
+	Code  string // The lines from Start to End
+}
+
+// Remap maps the lines of a suggestion on the chunk back to the file, and
+// makes its ID unique across the chunks of the file.
+func (c Chunk) Remap(suggestion agents.Suggestion) agents.Suggestion {
+	if suggestion.StartLine > 0 {
+		suggestion.StartLine += c.Start - 1
+	}
+	if suggestion.EndLine > 0 {
+		suggestion.EndLine += c.Start - 1
+	}
+	if c.Index > 0 {
+		suggestion.ID = fmt.Sprintf("%s-c%d", suggestion.ID, c.Index+1)
+	}
+	return suggestion
+}
+
+// Split splits code into chunks of at most maxLines lines, cutting between
+// top-level declarations where possible: parsed ones for Go files, and
+// unindented lines after a blank line for other languages. Each chunk but
+// the first also repeats the last overlap lines of the previous one. Code of
+// at most maxLines lines, or any code when maxLines is 0, is a single chunk.
+func Split(filename string, code string, maxLines int, overlap int) []Chunk {
+	lines := strings.Split(code, "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return []Chunk{{Start: 1, End: len(lines), Code: code}}
+	}
+
+	var boundaries []int
+	if filepath.Ext(filename) == ".go" {
+		boundaries = goBoundaries(code)
+	}
+	if boundaries == nil {
+		boundaries = heuristicBoundaries(lines)
+	}
+
+	var chunks []Chunk
+	for i, r := range pack(boundaries, len(lines), maxLines) {
+		start := r[0]
+		if i > 0 {
+			start = max(start-overlap, 1)
+		}
+		chunks = append(
+			chunks, Chunk{
+				Index: i,
+				Start: start,
+				End:   r[1],
+				Code:  strings.Join(lines[start-1:r[1]], "\n"),
+			},
+		)
+	}
+	return chunks
+}
+
+// pack groups the lines from 1 to total into ranges of at most maxLines
+// lines, each ending right before the last boundary that fits. Ranges
+// without a boundary that fits are cut at maxLines.
+func pack(boundaries []int, total int, maxLines int) [][2]int {
+	var ranges [][2]int
+	for start := 1; start <= total; {
+		end := start + maxLines - 1
+		if end >= total {
+			ranges = append(ranges, [2]int{start, total})
+			break
+		}
+
+		next := end + 1
+		for i := len(boundaries) - 1; i >= 0; i-- {
+			if b := boundaries[i]; b > start && b <= end+1 {
+				next = b
+				break
+			}
+		}
+
+		ranges = append(ranges, [2]int{start, next - 1})
+		start = next
+	}
+	return ranges
+}
+
+// goBoundaries returns the first line of each top-level declaration of Go
+// code, including its doc comment, or nil when the code doesn't parse.
+func goBoundaries(code string) []int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	var boundaries []int
+	for _, decl := range file.Decls {
+		pos := decl.Pos()
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		if doc != nil {
+			pos = doc.Pos()
+		}
+		boundaries = append(boundaries, fset.Position(pos).Line)
+	}
+	return boundaries
+}
+
+// heuristicBoundaries returns the lines that likely start a top-level
+// declaration in any language: unindented lines after a blank line, other
+// than closing brackets.
+func heuristicBoundaries(lines []string) []int {
+	var boundaries []int
+	for i, line := range lines {
+		if i == 0 || strings.TrimSpace(lines[i-1]) != "" || line == "" {
+			continue
+		}
+		if unicode.IsSpace(rune(line[0])) || strings.ContainsRune("})]", rune(line[0])) {
+			continue
+		}
+		boundaries = append(boundaries, i+1)
+	}
+	return boundaries
+}
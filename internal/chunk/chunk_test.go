@@ -0,0 +1,115 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// goFile returns Go code with n functions after the package clause, each
+// preceded by a blank line; function i starts on line 3+5*i with its doc
+// comment.
+func goFile(n int) string {
+	var code strings.Builder
+	code.WriteString("package main")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&code, "\n\n// f%d does nothing.\nfunc f%d() {\n\treturn\n}", i, i)
+	}
+	return code.String()
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name       string
+		filename   string
+		code       string
+		maxLines   int
+		overlap    int
+		wantRanges [][2]int
+	}{
+		{
+			name:       "short file",
+			filename:   "main.go",
+			code:       goFile(2),
+			maxLines:   50,
+			wantRanges: [][2]int{{1, 11}},
+		},
+		{
+			name:       "chunking disabled",
+			filename:   "main.go",
+			code:       goFile(20),
+			maxLines:   0,
+			wantRanges: [][2]int{{1, 101}},
+		},
+		{
+			name:     "go declarations",
+			filename: "main.go",
+			code:     goFile(4),
+			maxLines: 10,
+			// Blank lines end the previous chunk, doc comments start the next
+			wantRanges: [][2]int{{1, 7}, {8, 17}, {18, 21}},
+		},
+		{
+			name:       "overlap",
+			filename:   "main.go",
+			code:       goFile(4),
+			maxLines:   12,
+			overlap:    2,
+			wantRanges: [][2]int{{1, 12}, {11, 21}},
+		},
+		{
+			name:     "heuristic for other languages",
+			filename: "app.py",
+			code: "import os\n\ndef a():\n    pass\n\n    return\n\n" +
+				"class B:\n    pass\n\ndef c():\n    pass",
+			maxLines:   6,
+			wantRanges: [][2]int{{1, 2}, {3, 7}, {8, 12}},
+		},
+		{
+			name:       "declaration longer than the chunks",
+			filename:   "main.txt",
+			code:       strings.Repeat("x\n", 9) + "x",
+			maxLines:   4,
+			wantRanges: [][2]int{{1, 4}, {5, 8}, {9, 10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				chunks := Split(tt.filename, tt.code, tt.maxLines, tt.overlap)
+
+				var ranges [][2]int
+				lines := strings.Split(tt.code, "\n")
+				for i, c := range chunks {
+					ranges = append(ranges, [2]int{c.Start, c.End})
+					if c.Index != i {
+						t.Errorf("chunk %d has index %d", i, c.Index)
+					}
+					if want := strings.Join(lines[c.Start-1:c.End], "\n"); c.Code != want {
+						t.Errorf("chunk %d code = %q, want %q", i, c.Code, want)
+					}
+				}
+				if fmt.Sprint(ranges) != fmt.Sprint(tt.wantRanges) {
+					t.Errorf("Split() ranges = %v, want %v", ranges, tt.wantRanges)
+				}
+			},
+		)
+	}
+}
+
+func TestChunk_Remap(t *testing.T) {
+	c := Chunk{Index: 2, Start: 101, End: 200}
+
+	got := c.Remap(agents.Suggestion{ID: "miso-1A", StartLine: 3, EndLine: 5})
+	if got.ID != "miso-1A-c3" || got.StartLine != 103 || got.EndLine != 105 {
+		t.Errorf("Remap() = %+v, want miso-1A-c3 on lines 103-105", got)
+	}
+
+	got = c.Remap(agents.Suggestion{ID: "miso-1B"})
+	if got.StartLine != 0 || got.EndLine != 0 {
+		t.Errorf("Remap() moved a suggestion without lines to %d-%d", got.StartLine, got.EndLine)
+	}
+}
@@ -0,0 +1,94 @@
+package chunk
+
+import (
+	"slices"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// Merge combines the reviews of the chunks of a file into one, with the
+// lines of the suggestions mapped back to the file. A finding reported on
+// the overlapping lines of two chunks is kept once. Results may be nil for
+// chunks that weren't reviewed; Merge returns nil if none were.
+func Merge(chunks []Chunk, results []*agents.ReviewResult) *agents.ReviewResult {
+	if len(chunks) == 1 {
+		return results[0]
+	}
+
+	var merged *agents.ReviewResult
+	var from []int // Chunk of each merged suggestion
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &agents.ReviewResult{
+				Model:       result.Model,
+				Suggestions: []agents.Suggestion{},
+				Priced:      true,
+				Cached:      true,
+			}
+		}
+
+		merged.TokensUsed += result.TokensUsed
+		merged.InputTokens += result.InputTokens
+		merged.OutputTokens += result.OutputTokens
+		merged.CachedTokens += result.CachedTokens
+		merged.Cost += result.Cost
+		merged.ToolCalls += result.ToolCalls
+		merged.Priced = merged.Priced && result.Priced
+		merged.Cached = merged.Cached && result.Cached
+		merged.Truncated = merged.Truncated || result.Truncated
+		merged.Warnings = append(merged.Warnings, result.Warnings...)
+
+		for _, suggestion := range result.Suggestions {
+			suggestion = chunks[i].Remap(suggestion)
+			duplicate := false
+			for j, kept := range merged.Suggestions {
+				if from[j] != i && sameFinding(kept, suggestion) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				merged.Suggestions = append(merged.Suggestions, suggestion)
+				from = append(from, i)
+			}
+		}
+		for _, suggestion := range result.Dropped {
+			merged.Dropped = append(merged.Dropped, chunks[i].Remap(suggestion))
+		}
+	}
+	if merged == nil {
+		return nil
+	}
+
+	// Each chunk sorts its own suggestions by severity; keep the file order
+	// among suggestions of the same severity
+	slices.SortStableFunc(
+		merged.Suggestions, func(a, b agents.Suggestion) int {
+			return severityRank(a.Severity) - severityRank(b.Severity)
+		},
+	)
+	return merged
+}
+
+// sameFinding reports whether two suggestions of different chunks describe
+// the same finding: similar suggestions on overlapping lines.
+func sameFinding(a, b agents.Suggestion) bool {
+	if a.StartLine == 0 || b.StartLine == 0 {
+		return false
+	}
+	if a.StartLine > b.EndLine || b.StartLine > a.EndLine {
+		return false
+	}
+	return agents.Similar(a, b)
+}
+
+// severityRank orders severities from most to least severe.
+func severityRank(severity agents.Severity) int {
+	if i := slices.Index(agents.Severities, severity); i != -1 {
+		return i
+	}
+	return len(agents.Severities)
+}
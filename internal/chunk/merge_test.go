@@ -0,0 +1,91 @@
+package chunk
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+func TestMerge(t *testing.T) {
+	chunks := []Chunk{
+		{Index: 0, Start: 1, End: 100},
+		{Index: 1, Start: 91, End: 200},
+		{Index: 2, Start: 191, End: 250},
+	}
+	closeErr := agents.Suggestion{
+		ID:       "miso-1A",
+		Title:    "🟡 Warning: Unchecked error from Close",
+		Severity: agents.SeverityWarning,
+	}
+
+	first := closeErr
+	first.StartLine, first.EndLine = 95, 95
+	// The same finding, reported by the second chunk on the overlapping lines
+	again := closeErr
+	again.ID, again.StartLine, again.EndLine = "miso-1B", 5, 5
+	// A similar finding elsewhere in the file is kept
+	elsewhere := closeErr
+	elsewhere.ID, elsewhere.StartLine, elsewhere.EndLine = "miso-1C", 60, 60
+
+	results := []*agents.ReviewResult{
+		{
+			Suggestions: []agents.Suggestion{first},
+			TokensUsed:  10,
+			Cost:        0.01,
+			Priced:      true,
+		},
+		{
+			Suggestions: []agents.Suggestion{
+				{
+					ID:        "miso-1A",
+					Title:     "🔴 Critical: SQL injection",
+					Severity:  agents.SeverityCritical,
+					StartLine: 40,
+					EndLine:   42,
+				},
+				again,
+				elsewhere,
+			},
+			TokensUsed: 20,
+			Cost:       0.02,
+			Priced:     true,
+		},
+		nil, // Skipped by the budget
+	}
+
+	merged := Merge(chunks, results)
+	if merged == nil {
+		t.Fatal("Merge() = nil, want a result")
+	}
+
+	var got []string
+	for _, suggestion := range merged.Suggestions {
+		got = append(
+			got, fmt.Sprintf(
+				"%s@%d-%d", suggestion.ID, suggestion.StartLine, suggestion.EndLine,
+			),
+		)
+	}
+	want := "miso-1A-c2@130-132,miso-1A@95-95,miso-1C-c2@150-150"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Merge() suggestions = %v, want %s", got, want)
+	}
+
+	if merged.TokensUsed != 30 || merged.Cost != 0.03 || !merged.Priced {
+		t.Errorf("Expected the usage summed over the chunks, got %+v", merged)
+	}
+}
+
+func TestMerge_Passthrough(t *testing.T) {
+	result := &agents.ReviewResult{Model: "test"}
+	if Merge([]Chunk{{Start: 1, End: 10}}, []*agents.ReviewResult{result}) != result {
+		t.Error("Expected the result of a single chunk as is")
+	}
+
+	chunks := []Chunk{{Index: 0, Start: 1, End: 10}, {Index: 1, Start: 8, End: 20}}
+	if Merge(chunks, []*agents.ReviewResult{nil, nil}) != nil {
+		t.Error("Expected no result when no chunk was reviewed")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"gopkg.in/yaml.v3"
 )
@@ -59,6 +60,7 @@ func (p *Parser) LoadFile(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	resolveTemplates(&config.Prompts, filepath.Dir(path))
 
 	// Validate configuration
 	if err := p.validate(config); err != nil {
@@ -83,6 +85,16 @@ func (p *Parser) LoadFromString(yamlContent string) (*Config, error) {
 	return config, nil
 }
 
+// resolveTemplates makes the relative template paths of prompts relative to
+// dir, the directory of the config file, rather than the working directory.
+func resolveTemplates(prompts *Prompts, dir string) {
+	for _, path := range []*string{&prompts.Review, &prompts.Diff} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
 // validate checks if the configuration is valid
 func (p *Parser) validate(config *Config) error {
 	// Validate content defaults
@@ -96,6 +108,61 @@ func (p *Parser) validate(config *Config) error {
 		return fmt.Errorf("invalid default strategy: %s", config.ContentDefaults.Strategy)
 	}
 
+	if err := validateLLM(config.LLM); err != nil {
+		return err
+	}
+
+	for model, price := range config.Pricing {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("pricing %s: prices must not be negative", model)
+		}
+	}
+
+	if config.Guides.MaxTokens < 0 {
+		return fmt.Errorf("guides: max_tokens must not be negative")
+	}
+
+	if config.Budget.MaxTokens < 0 || config.Budget.MaxCost < 0 ||
+		config.Budget.MaxFileTokens < 0 {
+		return fmt.Errorf("budget: limits must not be negative")
+	}
+
+	if config.Chunking.MaxLines < 0 || config.Chunking.Overlap < 0 {
+		return fmt.Errorf("chunking: max_lines and overlap must not be negative")
+	}
+	if config.Chunking.MaxLines > 0 && config.Chunking.Overlap >= config.Chunking.MaxLines {
+		return fmt.Errorf(
+			"chunking: overlap %d must be less than max_lines %d",
+			config.Chunking.Overlap, config.Chunking.MaxLines,
+		)
+	}
+
+	if config.Agent.MaxSteps < 0 {
+		return fmt.Errorf("agent: max_steps must not be negative")
+	}
+
+	if config.Verify.Threshold < 0 || config.Verify.Threshold > 1 {
+		return fmt.Errorf(
+			"verify: threshold must be between 0 and 1, got %g",
+			config.Verify.Threshold,
+		)
+	}
+
+	if err := validateEnsemble(
+		config.Ensemble.Models, config.Ensemble.MinAgreement,
+	); err != nil {
+		return fmt.Errorf("ensemble: %w", err)
+	}
+
+	for i, rule := range config.Rules {
+		if err := validateRule(rule); err != nil {
+			if rule.Name == "" {
+				return fmt.Errorf("rule %d: %w", i, err)
+			}
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+	}
+
 	// Validate patterns
 	for i, pattern := range config.Patterns {
 		if pattern.Name == "" {
@@ -117,11 +184,130 @@ func (p *Parser) validate(config *Config) error {
 		if len(pattern.Context) == 0 && len(pattern.DiffContext) == 0 {
 			return fmt.Errorf("pattern %s: must have at least one context or diff_context guide", pattern.Name)
 		}
+
+		if pattern.Temperature != nil && (*pattern.Temperature < 0 || *pattern.Temperature > 2) {
+			return fmt.Errorf("pattern %s: temperature must be between 0 and 2, got %g", pattern.Name, *pattern.Temperature)
+		}
+
+		if err := validateEnsemble(pattern.Ensemble, pattern.MinAgreement); err != nil {
+			return fmt.Errorf("pattern %s: %w", pattern.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateEnsemble checks the models and agreement of an ensemble. Without
+// models, the agreement applies to the ensemble of the config.
+func validateEnsemble(models []string, minAgreement int) error {
+	for _, model := range models {
+		if model == "" {
+			return fmt.Errorf("ensemble models must not be empty")
+		}
+	}
+
+	if minAgreement < 0 {
+		return fmt.Errorf("min_agreement must not be negative")
+	}
+	if len(models) > 0 && minAgreement > len(models) {
+		return fmt.Errorf(
+			"min_agreement %d exceeds the %d ensemble models",
+			minAgreement, len(models),
+		)
 	}
 
 	return nil
 }
 
+// validateRule checks that a rule has a name, a single check and valid
+// regexes and severity.
+func validateRule(rule Rule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if (rule.Pattern == "") == (rule.Literal == "") {
+		return fmt.Errorf("must have exactly one of pattern or literal")
+	}
+
+	if _, err := regexp.Compile(rule.Filename); err != nil {
+		return fmt.Errorf("invalid filename regex: %w", err)
+	}
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern regex: %w", err)
+	}
+
+	switch rule.Severity {
+	case "", SeverityCritical, SeverityWarning, SeveritySuggestion:
+	default:
+		return fmt.Errorf("invalid severity: %s", rule.Severity)
+	}
+
+	return nil
+}
+
+// validateLLM checks the provider settings
+func validateLLM(llm LLM) error {
+	validProviders := map[string]bool{
+		ProviderOpenRouter:       true,
+		ProviderOpenAI:           true,
+		ProviderAnthropic:        true,
+		ProviderOllama:           true,
+		ProviderOpenAICompatible: true,
+	}
+
+	if !validProviders[llm.Provider] {
+		return fmt.Errorf("llm: invalid provider: %s", llm.Provider)
+	}
+
+	if llm.Provider == ProviderOpenAICompatible && llm.BaseURL == "" {
+		return fmt.Errorf("llm: provider %s requires base_url", llm.Provider)
+	}
+
+	if llm.Temperature < 0 || llm.Temperature > 2 {
+		return fmt.Errorf("llm: temperature must be between 0 and 2, got %g", llm.Temperature)
+	}
+
+	if llm.MaxTokens < 0 {
+		return fmt.Errorf("llm: max_tokens must not be negative, got %d", llm.MaxTokens)
+	}
+
+	if llm.Timeout < 0 {
+		return fmt.Errorf("llm: timeout must not be negative, got %s", llm.Timeout)
+	}
+
+	if llm.MaxRetries < 0 {
+		return fmt.Errorf("llm: max_retries must not be negative, got %d", llm.MaxRetries)
+	}
+
+	if llm.RetryDelay < 0 {
+		return fmt.Errorf("llm: retry_delay must not be negative, got %s", llm.RetryDelay)
+	}
+
+	validStructuredOutput := map[string]bool{
+		"":                   true,
+		StructuredOutputAuto: true,
+		StructuredOutputOn:   true,
+		StructuredOutputOff:  true,
+	}
+
+	if !validStructuredOutput[llm.StructuredOutput] {
+		return fmt.Errorf("llm: invalid structured_output: %s", llm.StructuredOutput)
+	}
+
+	if llm.RepairAttempts < 0 {
+		return fmt.Errorf("llm: repair_attempts must not be negative, got %d", llm.RepairAttempts)
+	}
+
+	return nil
+}
+
+// Validate checks a configuration that was built or modified in code,
+// for example after applying command-line overrides.
+func (p *Parser) Validate(config *Config) error {
+	return p.validate(config)
+}
+
 // FindConfigFile searches for a configuration file in the current directory and parent directories.
 // Returns the path to the first configuration file found, or an error if none found.
 func (p *Parser) FindConfigFile() (string, error) {
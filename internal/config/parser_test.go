@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadFromString(t *testing.T) {
@@ -71,6 +72,286 @@ patterns:
     content_lines: [100, 100]
     context:
       - go.md
+`,
+			wantErr: true,
+		},
+		{
+			name: "valid llm section",
+			yaml: `
+llm:
+  provider: "ollama"
+  base_url: "http://localhost:11434"
+  model: "llama3.1"
+  temperature: 0.1
+  max_tokens: 2048
+  timeout: "90s"
+  max_retries: 5
+  retry_delay: "500ms"
+`,
+			wantErr: false,
+		},
+		{
+			name: "prompt templates",
+			yaml: `
+prompts:
+  review: prompts/review.tmpl
+  diff: prompts/diff.tmpl
+`,
+			wantErr: false,
+		},
+		{
+			name: "guide budget",
+			yaml: `
+guides:
+  max_tokens: 4000
+  priority:
+    security.md: 10
+    style.md: -1
+`,
+			wantErr: false,
+		},
+		{
+			name: "negative guide budget",
+			yaml: `
+guides:
+  max_tokens: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "pricing override",
+			yaml: `
+pricing:
+  my-model:
+    input: 1.5
+    output: 6
+`,
+			wantErr: false,
+		},
+		{
+			name: "negative pricing",
+			yaml: `
+pricing:
+  my-model:
+    input: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "budget limits",
+			yaml: `
+budget:
+  max_tokens: 200000
+  max_cost: 0.5
+  max_file_tokens: 8000
+`,
+			wantErr: false,
+		},
+		{
+			name: "negative budget",
+			yaml: `
+budget:
+  max_cost: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "chunking",
+			yaml: `
+chunking:
+  max_lines: 300
+  overlap: 30
+`,
+			wantErr: false,
+		},
+		{
+			name: "chunk overlap as long as the chunks",
+			yaml: `
+chunking:
+  max_lines: 30
+  overlap: 30
+`,
+			wantErr: true,
+		},
+		{
+			name: "agent tools",
+			yaml: `
+agent:
+  enabled: true
+  max_steps: 5
+`,
+			wantErr: false,
+		},
+		{
+			name: "negative agent steps",
+			yaml: `
+agent:
+  max_steps: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "verification",
+			yaml: `
+verify:
+  enabled: true
+  threshold: 0.7
+  model: openai/gpt-4o-mini
+`,
+			wantErr: false,
+		},
+		{
+			name: "verification threshold out of range",
+			yaml: `
+verify:
+  threshold: 70
+`,
+			wantErr: true,
+		},
+		{
+			name: "ensemble",
+			yaml: `
+ensemble:
+  models: [anthropic/claude-sonnet-4, openai/gpt-4.1]
+  min_agreement: 2
+patterns:
+  - name: payments
+    filename: "^payments/"
+    context: [payments.md]
+    ensemble: [anthropic/claude-sonnet-4, openai/gpt-4.1, google/gemini-2.5-pro]
+    min_agreement: 2
+`,
+			wantErr: false,
+		},
+		{
+			name: "ensemble agreement exceeds models",
+			yaml: `
+ensemble:
+  models: [anthropic/claude-sonnet-4, openai/gpt-4.1]
+  min_agreement: 3
+`,
+			wantErr: true,
+		},
+		{
+			name: "pattern ensemble agreement exceeds models",
+			yaml: `
+patterns:
+  - name: payments
+    filename: "^payments/"
+    context: [payments.md]
+    ensemble: [openai/gpt-4.1]
+    min_agreement: 2
+`,
+			wantErr: true,
+		},
+		{
+			name: "rules",
+			yaml: `
+rules:
+  - name: no-fmt-println
+    filename: "\\.go$"
+    pattern: "fmt\\.Println\\("
+    added_only: true
+    title: Debug print
+    message: Use the logger instead of fmt.Println.
+  - name: no-todo
+    literal: "TODO"
+    severity: suggestion
+`,
+			wantErr: false,
+		},
+		{
+			name: "rule with both pattern and literal",
+			yaml: `
+rules:
+  - name: no-todo
+    pattern: "TODO"
+    literal: "TODO"
+`,
+			wantErr: true,
+		},
+		{
+			name: "rule with invalid regex",
+			yaml: `
+rules:
+  - name: broken
+    pattern: "fmt\\.Println("
+`,
+			wantErr: true,
+		},
+		{
+			name: "rule with invalid severity",
+			yaml: `
+rules:
+  - name: no-todo
+    literal: "TODO"
+    severity: blocker
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid structured output",
+			yaml: `
+llm:
+  structured_output: "sometimes"
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative llm retries",
+			yaml: `
+llm:
+  max_retries: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid llm provider",
+			yaml: `
+llm:
+  provider: "unknown"
+`,
+			wantErr: true,
+		},
+		{
+			name: "openai-compatible without base_url",
+			yaml: `
+llm:
+  provider: "openai-compatible"
+  model: "local-model"
+`,
+			wantErr: true,
+		},
+		{
+			name: "pattern with model override",
+			yaml: `
+patterns:
+  - name: "security"
+    content: "password|token"
+    model: "anthropic/claude-3.5-sonnet"
+    temperature: 0
+    context:
+      - security.md
+`,
+			wantErr: false,
+		},
+		{
+			name: "pattern temperature out of range",
+			yaml: `
+patterns:
+  - name: "tests"
+    filename: "_test\\.go$"
+    temperature: -1
+    context:
+      - testing.md
+`,
+			wantErr: true,
+		},
+		{
+			name: "llm temperature out of range",
+			yaml: `
+llm:
+  temperature: 3
 `,
 			wantErr: true,
 		},
@@ -141,6 +422,56 @@ patterns:
 	}
 }
 
+func TestLoadFile_PromptTemplates(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "miso.yml")
+	configContent := `
+prompts:
+  review: prompts/review.tmpl
+  diff: /etc/miso/diff.tmpl
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := NewParser().LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	// Relative paths are resolved against the directory of the config file
+	if want := filepath.Join(dir, "prompts", "review.tmpl"); config.Prompts.Review != want {
+		t.Errorf("Prompts.Review = %q, want %q", config.Prompts.Review, want)
+	}
+	if config.Prompts.Diff != "/etc/miso/diff.tmpl" {
+		t.Errorf("Expected the absolute path kept, got %q", config.Prompts.Diff)
+	}
+}
+
+func TestLoadLLMRetrySettings(t *testing.T) {
+	parser := NewParser()
+	config, err := parser.LoadFromString(`
+llm:
+  timeout: "90s"
+  max_retries: 0
+`)
+	if err != nil {
+		t.Fatalf("LoadFromString() error = %v", err)
+	}
+
+	if config.LLM.Timeout != 90*time.Second {
+		t.Errorf("Expected timeout 90s, got %s", config.LLM.Timeout)
+	}
+
+	if config.LLM.MaxRetries != 0 {
+		t.Errorf("Expected retries to be disabled, got %d", config.LLM.MaxRetries)
+	}
+
+	if config.LLM.RetryDelay != time.Second {
+		t.Errorf("Expected default retry delay 1s, got %s", config.LLM.RetryDelay)
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	config := DefaultConfig()
 
@@ -156,4 +487,17 @@ func TestDefaultConfig(t *testing.T) {
 			"Expected default lines 50, got %d", config.ContentDefaults.Lines,
 		)
 	}
+
+	if config.LLM.Provider != ProviderOpenRouter {
+		t.Errorf(
+			"Expected default provider %s, got %s", ProviderOpenRouter,
+			config.LLM.Provider,
+		)
+	}
+
+	if config.LLM.Temperature != 0.3 {
+		t.Errorf(
+			"Expected default temperature 0.3, got %g", config.LLM.Temperature,
+		)
+	}
 }
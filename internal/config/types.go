@@ -1,10 +1,135 @@
 package config
 
+import "time"
+
 // Config represents the complete miso configuration structure.
 // It defines how files are matched and which review guides are applied.
 type Config struct {
-	ContentDefaults ContentDefaults `yaml:"content_defaults"`
-	Patterns        []Pattern       `yaml:"patterns"`
+	ContentDefaults ContentDefaults       `yaml:"content_defaults"`
+	LLM             LLM                   `yaml:"llm"`
+	Prompts         Prompts               `yaml:"prompts"`
+	Guides          Guides                `yaml:"guides"`
+	Pricing         map[string]ModelPrice `yaml:"pricing"` // Per-model price overrides
+	Budget          Budget                `yaml:"budget"`
+	Chunking        Chunking              `yaml:"chunking"`
+	Agent           Agent                 `yaml:"agent"`
+	Verify          Verify                `yaml:"verify"`
+	Ensemble        Ensemble              `yaml:"ensemble"`
+	Rules           []Rule                `yaml:"rules"`
+	Patterns        []Pattern             `yaml:"patterns"`
+}
+
+// Prompts holds the paths of custom prompt templates, Go templates that
+// replace the built-in prompts. Empty paths use the built-in prompts.
+type Prompts struct {
+	Review string `yaml:"review"` // Template of file reviews
+	Diff   string `yaml:"diff"`   // Template of diff and pull request reviews
+}
+
+// Guides limits the guide context of prompts. When the matched guides
+// exceed MaxTokens, the lowest-priority guides are cut to their headings
+// first, then dropped.
+type Guides struct {
+	MaxTokens int            `yaml:"max_tokens"` // Tokens of guide context per prompt; 0 disables the limit
+	Priority  map[string]int `yaml:"priority"`   // Priority of each guide by name, higher kept first (default: 0)
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`  // USD per million input tokens
+	Output float64 `yaml:"output"` // USD per million output tokens
+}
+
+// Budget limits the tokens and cost of a review run. Zero disables a limit.
+// Usage is estimated before each call; files that don't fit are truncated or skipped.
+type Budget struct {
+	MaxTokens     int     `yaml:"max_tokens"`      // Total tokens (input and output) for the run
+	MaxCost       float64 `yaml:"max_cost"`        // Total cost in USD for the run
+	MaxFileTokens int     `yaml:"max_file_tokens"` // Prompt tokens for a single file
+}
+
+// Chunking splits files longer than MaxLines into chunks reviewed
+// separately, cutting at declaration boundaries where possible.
+type Chunking struct {
+	MaxLines int `yaml:"max_lines"` // Longest file reviewed in one prompt; 0 disables chunking
+	Overlap  int `yaml:"overlap"`   // Lines of the previous chunk repeated as context
+}
+
+// Agent lets the model call read-only repository tools during a review,
+// e.g. to check the callers of a function whose contract changed.
+type Agent struct {
+	Enabled  bool `yaml:"enabled"`
+	MaxSteps int  `yaml:"max_steps"` // Tool-calling rounds before the model must answer
+}
+
+// Verify runs a second LLM call that scores each suggestion for correctness
+// and value, dropping those scored below the threshold.
+type Verify struct {
+	Enabled   bool    `yaml:"enabled"`
+	Threshold float64 `yaml:"threshold"` // Minimum confidence to keep a suggestion, from 0 to 1
+	Model     string  `yaml:"model"`     // Model of the verification call (default: the review model)
+}
+
+// Ensemble reviews each file with several models and merges their findings,
+// reporting those enough models agree on. Patterns can set their own
+// ensemble, e.g. for high-stakes paths.
+type Ensemble struct {
+	Models       []string `yaml:"models"`        // Models reviewing every file; empty disables the ensemble
+	MinAgreement int      `yaml:"min_agreement"` // Models that must report a finding (default: 1)
+}
+
+// Rule severities, from most to least severe.
+const (
+	SeverityCritical   = "critical"
+	SeverityWarning    = "warning"
+	SeveritySuggestion = "suggestion"
+)
+
+// Rule is a deterministic check run on every matching file without calling
+// the LLM. Each line matching its regex or literal is reported.
+type Rule struct {
+	Name      string `yaml:"name"`
+	Filename  string `yaml:"filename"`   // Regex for the files to check (default: all files)
+	Pattern   string `yaml:"pattern"`    // Regex matched against each line
+	Literal   string `yaml:"literal"`    // Text searched in each line, instead of a pattern
+	AddedOnly bool   `yaml:"added_only"` // In diff reviews, check only added lines
+	Title     string `yaml:"title"`      // Summary of the finding (default: the name)
+	Message   string `yaml:"message"`    // Explanation shown with each finding
+	Severity  string `yaml:"severity"`   // critical, warning (default), suggestion
+}
+
+// Supported LLM providers.
+const (
+	ProviderOpenRouter       = "openrouter"
+	ProviderOpenAI           = "openai"
+	ProviderAnthropic        = "anthropic"
+	ProviderOllama           = "ollama"
+	ProviderOpenAICompatible = "openai-compatible"
+)
+
+// Structured output settings for LLM.StructuredOutput.
+const (
+	StructuredOutputAuto = "auto" // Native structured output where the provider is known to support it
+	StructuredOutputOn   = "on"   // Also request it from openai-compatible and ollama endpoints
+	StructuredOutputOff  = "off"  // Always parse plain text responses
+)
+
+// LLM configures the language model provider used for reviews.
+// Empty fields fall back to the provider's defaults.
+type LLM struct {
+	Provider    string  `yaml:"provider"`    // openrouter, openai, anthropic, ollama, openai-compatible
+	BaseURL     string  `yaml:"base_url"`    // Override the provider API endpoint
+	Model       string  `yaml:"model"`       // Model identifier sent to the provider
+	Temperature float64 `yaml:"temperature"` // Sampling temperature
+	MaxTokens   int     `yaml:"max_tokens"`  // Response token limit (0 uses the provider default)
+	APIKeyEnv   string  `yaml:"api_key_env"` // Environment variable holding the API key
+
+	Timeout    time.Duration `yaml:"timeout"`     // Timeout for each request attempt (0 disables)
+	MaxRetries int           `yaml:"max_retries"` // Retries for rate limits, 5xx and network errors
+	RetryDelay time.Duration `yaml:"retry_delay"` // Initial backoff delay, doubled on each retry
+
+	StructuredOutput string `yaml:"structured_output"` // auto, on, off
+	RepairAttempts   int    `yaml:"repair_attempts"`   // Follow-up calls to fix unparsable responses
 }
 
 // ContentDefaults defines global defaults for content scanning strategies.
@@ -25,6 +150,10 @@ type Pattern struct {
 	Context         []string `yaml:"context"`          // Guide files to use
 	DiffContext     []string `yaml:"diff_context"`     // Guide files for diff reviews
 	Stop            bool     `yaml:"stop"`             // Stop evaluating further patterns
+	Model           string   `yaml:"model"`            // Model override for matched files
+	Temperature     *float64 `yaml:"temperature"`      // Temperature override for matched files
+	Ensemble        []string `yaml:"ensemble"`         // Ensemble models for matched files
+	MinAgreement    int      `yaml:"min_agreement"`    // Ensemble agreement override for matched files
 }
 
 // DefaultConfig returns a configuration with sensible defaults.
@@ -35,6 +164,26 @@ func DefaultConfig() *Config {
 			Strategy: "first_lines",
 			Lines:    50,
 		},
+		LLM: LLM{
+			Provider:    ProviderOpenRouter,
+			Temperature: 0.3,
+			Timeout:     2 * time.Minute,
+			MaxRetries:  3,
+			RetryDelay:  time.Second,
+
+			StructuredOutput: StructuredOutputAuto,
+			RepairAttempts:   2,
+		},
+		Chunking: Chunking{
+			MaxLines: 500,
+			Overlap:  20,
+		},
+		Agent: Agent{
+			MaxSteps: 8,
+		},
+		Verify: Verify{
+			Threshold: 0.5,
+		},
 		Patterns: []Pattern{},
 	}
 }
@@ -39,6 +39,28 @@ func NewGitClient() (*GitClient, error) {
 	return &GitClient{repo: repo}, nil
 }
 
+// OpenRepository creates a GitClient for the repository holding dir, which
+// may be any directory of its working tree.
+func OpenRepository(dir string) (*GitClient, error) {
+	repo, err := git.PlainOpenWithOptions(
+		dir, &git.PlainOpenOptions{DetectDotGit: true},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository in %s: %w", dir, err)
+	}
+	return &GitClient{repo: repo}, nil
+}
+
+// Root returns the top-level directory of the working tree, which the file
+// paths of diffs are relative to.
+func (g *GitClient) Root() (string, error) {
+	worktree, err := g.repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to open worktree: %w", err)
+	}
+	return worktree.Filesystem.Root(), nil
+}
+
 // GetChangedFiles returns a list of files that changed between two Git references.
 // References can be commit hashes, branch names, or symbolic refs like HEAD.
 func (g *GitClient) GetChangedFiles(baseRef, headRef string) ([]string, error) {
@@ -369,3 +369,38 @@ func TestGitClient_resolveCommit(t *testing.T) {
 		})
 	}
 }
+
+func TestGitClient_Root(t *testing.T) {
+	client, cleanup := setupGitClient(t)
+	defer cleanup()
+
+	root, err := client.Root()
+	if err != nil {
+		t.Fatalf("Root() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "internal", "git", "git.go")); err != nil {
+		t.Errorf("Expected %s to be the repository root: %v", root, err)
+	}
+}
+
+func TestOpenRepository(t *testing.T) {
+	client, cleanup := setupGitClient(t)
+	defer cleanup()
+	want, err := client.Root()
+	if err != nil {
+		t.Fatalf("Root() error = %v", err)
+	}
+
+	// A subdirectory opens the repository it belongs to
+	sub, err := OpenRepository(filepath.Join(want, "internal", "git"))
+	if err != nil {
+		t.Fatalf("OpenRepository() error = %v", err)
+	}
+	if got, err := sub.Root(); err != nil || got != want {
+		t.Errorf("Root() = %q, %v, want %q", got, err, want)
+	}
+
+	if _, err := OpenRepository(t.TempDir()); err == nil {
+		t.Error("Expected an error outside of a repository")
+	}
+}
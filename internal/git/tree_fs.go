@@ -0,0 +1,202 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TreeFS returns a read-only file system of the files at a Git reference.
+// Submodules are left out; symlinks read as files holding their target.
+// The file system is safe for concurrent use, but it shares the repository
+// with g, so concurrent use of both needs separate clients.
+func (g *GitClient) TreeFS(ref string) (fs.FS, error) {
+	commit, err := g.resolveCommit(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree for %s: %w", ref, err)
+	}
+
+	return &treeFS{tree: tree, modTime: commit.Committer.When}, nil
+}
+
+// CommitHash resolves a reference to the full hash of its commit.
+func (g *GitClient) CommitHash(ref string) (string, error) {
+	commit, err := g.resolveCommit(ref)
+	if err != nil {
+		return "", err
+	}
+	return commit.Hash.String(), nil
+}
+
+// treeFS implements fs.FS over a Git tree.
+type treeFS struct {
+	mu      sync.Mutex // Serializes object reads, which go-git doesn't synchronize
+	tree    *object.Tree
+	modTime time.Time // Commit time, used for every entry
+}
+
+// Open opens the named file or directory of the tree.
+func (t *treeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return t.openDir(name, t.tree), nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, err := t.tree.FindEntry(name)
+	if err != nil || entry.Mode == filemode.Submodule {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if entry.Mode == filemode.Dir {
+		sub, err := t.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return t.openDir(name, sub), nil
+	}
+
+	file, err := t.tree.TreeEntryFile(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &treeFile{
+		info:   t.fileInfo(path.Base(name), *entry, file.Size),
+		Reader: strings.NewReader(contents),
+	}, nil
+}
+
+// openDir opens a directory of the tree.
+func (t *treeFS) openDir(name string, tree *object.Tree) *treeDir {
+	return &treeDir{
+		info: t.fileInfo(
+			path.Base(name), object.TreeEntry{Mode: filemode.Dir}, 0,
+		),
+		fsys: t,
+		tree: tree,
+	}
+}
+
+// fileInfo describes a tree entry.
+func (t *treeFS) fileInfo(
+	name string, entry object.TreeEntry, size int64,
+) fileInfo {
+	mode := fs.FileMode(0o444)
+	switch entry.Mode {
+	case filemode.Dir:
+		mode = fs.ModeDir | 0o555
+	case filemode.Executable:
+		mode = 0o555
+	}
+
+	return fileInfo{name: name, size: size, mode: mode, modTime: t.modTime}
+}
+
+// treeFile is an open file of a tree.
+type treeFile struct {
+	info fileInfo
+	*strings.Reader
+}
+
+func (f *treeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *treeFile) Close() error               { return nil }
+
+// treeDir is an open directory of a tree.
+type treeDir struct {
+	info    fileInfo
+	fsys    *treeFS
+	tree    *object.Tree
+	entries []fs.DirEntry // Loaded on the first ReadDir
+	offset  int
+}
+
+func (d *treeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *treeDir) Close() error               { return nil }
+
+func (d *treeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir returns the next n entries of the directory, sorted by name.
+func (d *treeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.fsys.mu.Lock()
+		defer d.fsys.mu.Unlock()
+
+		d.entries = []fs.DirEntry{}
+		for _, entry := range d.tree.Entries {
+			if entry.Mode == filemode.Submodule {
+				continue
+			}
+
+			var size int64
+			if entry.Mode != filemode.Dir {
+				file, err := d.tree.TreeEntryFile(&entry)
+				if err != nil {
+					return nil, err
+				}
+				size = file.Size
+			}
+			d.entries = append(
+				d.entries,
+				fs.FileInfoToDirEntry(d.fsys.fileInfo(entry.Name, entry, size)),
+			)
+		}
+		slices.SortFunc(
+			d.entries, func(a, b fs.DirEntry) int {
+				return strings.Compare(a.Name(), b.Name())
+			},
+		)
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
+
+// fileInfo implements fs.FileInfo for tree entries.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() any           { return nil }
@@ -0,0 +1,46 @@
+package git
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGitClient_TreeFS(t *testing.T) {
+	client, cleanup := setupGitClient(t)
+	defer cleanup()
+
+	fsys, err := client.TreeFS("HEAD")
+	if err != nil {
+		t.Fatalf("TreeFS() error = %v", err)
+	}
+
+	if err := fstest.TestFS(fsys, "go.mod", "internal/git/git.go"); err != nil {
+		t.Errorf("TreeFS() is not a valid fs.FS: %v", err)
+	}
+
+	data, err := fs.ReadFile(fsys, "go.mod")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if len(data) == 0 {
+		t.Error("Expected go.mod to have content")
+	}
+
+	if _, err := fs.Stat(fsys, "does/not/exist.go"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestGitClient_CommitHash(t *testing.T) {
+	client, cleanup := setupGitClient(t)
+	defer cleanup()
+
+	hash, err := client.CommitHash("HEAD")
+	if err != nil {
+		t.Fatalf("CommitHash() error = %v", err)
+	}
+	if len(hash) != 40 {
+		t.Errorf("CommitHash() = %q, want a full hash", hash)
+	}
+}
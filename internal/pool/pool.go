@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Run calls work for each item with at most jobs calls running at once and
+// passes each result to emit in the order of items, as soon as it and all
+// earlier results are available. emit runs on the calling goroutine, so it
+// can print and aggregate results without locking.
+//
+// If emit returns an error, the context passed to work is cancelled, items
+// not yet started are skipped, and Run returns the error once the running
+// calls have finished. With a single job each item is emitted before the
+// next one starts.
+func Run[T, R any](
+	ctx context.Context, items []T, jobs int,
+	work func(ctx context.Context, item T) R,
+	emit func(item T, result R) error,
+) error {
+	if jobs <= 1 {
+		for _, item := range items {
+			if err := emit(item, work(ctx, item)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// One buffered channel per item keeps results in order
+	results := make([]chan R, len(items))
+	for i := range results {
+		results[i] = make(chan R, 1)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] <- work(ctx, items[i])
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indexes)
+		for i := range items {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var err error
+	for i, item := range items {
+		if err = emit(item, <-results[i]); err != nil {
+			break
+		}
+	}
+
+	cancel()
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []int
+		jobs  int
+	}{
+		{"sequential", []int{1, 2, 3, 4}, 1},
+		{"concurrent", []int{1, 2, 3, 4, 5, 6, 7, 8}, 3},
+		{"more jobs than items", []int{1, 2}, 8},
+		{"no items", nil, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var running, maxRunning atomic.Int32
+				work := func(_ context.Context, item int) int {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						m := maxRunning.Load()
+						if n <= m || maxRunning.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					// Later items finish first to exercise the ordering
+					time.Sleep(time.Duration(10-item) * time.Millisecond)
+					return item * 10
+				}
+
+				var got []int
+				err := Run(
+					context.Background(), tt.items, tt.jobs, work,
+					func(item int, result int) error {
+						if result != item*10 {
+							t.Errorf("result for %d = %d, want %d", item, result, item*10)
+						}
+						got = append(got, item)
+						return nil
+					},
+				)
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+
+				if len(got) != len(tt.items) {
+					t.Fatalf("emitted %v, want %v", got, tt.items)
+				}
+				for i := range got {
+					if got[i] != tt.items[i] {
+						t.Errorf("emitted %v, want %v", got, tt.items)
+						break
+					}
+				}
+				if int(maxRunning.Load()) > max(tt.jobs, 1) {
+					t.Errorf("%d calls ran at once, want at most %d", maxRunning.Load(), tt.jobs)
+				}
+			},
+		)
+	}
+}
+
+func TestRun_EmitError(t *testing.T) {
+	stop := errors.New("stop")
+	var started atomic.Int32
+
+	err := Run(
+		context.Background(), []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 2,
+		func(ctx context.Context, item int) int {
+			started.Add(1)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Millisecond):
+			}
+			return item
+		},
+		func(item int, _ int) error {
+			if item == 2 {
+				return stop
+			}
+			return nil
+		},
+	)
+
+	if !errors.Is(err, stop) {
+		t.Fatalf("Run() error = %v, want %v", err, stop)
+	}
+	if started.Load() >= 10 {
+		t.Errorf("Expected remaining items to be skipped, %d started", started.Load())
+	}
+}
@@ -2,44 +2,17 @@ package prompts
 
 import (
 	"fmt"
-	"strings"
+	"text/template"
 
 	"github.com/j0lvera/miso/internal/config"
 	"github.com/j0lvera/miso/internal/resolver"
-	"github.com/tmc/langchaingo/prompts"
 )
 
-func CodeReview(cfg *config.Config, code string, filename string) (
-	string, error,
-) {
-	// Use resolver
-	res := resolver.NewResolver(cfg)
-	guides, err := res.GetGuides(filename)
-	if err != nil {
-		return "", fmt.Errorf("failed to get guides: %w", err)
-	}
-
-	// Load guide content
-	guideContent, err := res.LoadGuideContent(guides)
-	if err != nil {
-		return "", fmt.Errorf("failed to load guide content: %w", err)
-	}
-
-	// Combine all guide content
-	var combinedGuides strings.Builder
-	if len(guideContent) > 0 {
-		combinedGuides.WriteString("\n\n**Architecture Guides:**\n")
-		for guideName, content := range guideContent {
-			combinedGuides.WriteString(
-				fmt.Sprintf(
-					"\n=== %s ===\n%s\n", guideName, content,
-				),
-			)
-		}
-	}
-
-	template := prompts.NewPromptTemplate(
-		`You are an expert code reviewer. Perform a two-pass review on the provided code.
+// codeReviewTemplate is the built-in template of code review prompts. Its
+// prefix is the same for every file reviewed with the same guides.
+var codeReviewTemplate = template.Must(
+	template.New("review").Parse(
+		`{{define "prefix"}}You are an expert code reviewer. Perform a two-pass review on the provided code.
 
 **FIRST PASS - General Code Health**
 Identify general issues based on the following criteria:
@@ -51,7 +24,18 @@ Identify general issues based on the following criteria:
 **SECOND PASS - Architecture Compliance**
 Review the code against the provided Architecture Guides. If no guides are provided, skip this pass.
 
-**Output Format:**
+{{.OutputFormat}}{{.Guides}}{{end}}Code to review, each line prefixed with its line number and "|":
+'''
+{{.Code}}
+'''
+
+File: {{.Filename}}`,
+	),
+)
+
+// codeOutputFormat describes the response of code reviews, which the
+// templates must include as is.
+const codeOutputFormat = `**Output Format:**
 Return your review as a JSON array of suggestion objects.
 - Provide only actionable suggestions for improvement. Do not comment on code that is already good.
 - Sort the suggestions in the final JSON array from most critical to least critical.
@@ -60,8 +44,13 @@ Each object must have the following fields:
 - "id": A unique identifier for the suggestion (e.g., "miso-1A", "miso-1B").
 - "title": A concise, one-line summary of the issue, including a severity emoji (e.g., "🔴 Critical", "🟡 Warning", "💡 Suggestion", "❌ Violation", "⚠️ Deviation").
 - "body": A detailed explanation of the issue in markdown format. This should explain what's wrong and why it matters.
-- "original": (Optional) The exact code to be replaced.
+- "original": (Optional) The exact code to be replaced, without line numbers.
 - "suggestion": (Optional) The new code.
+- "severity": One of "critical", "warning" or "suggestion", matching the emoji of the title.
+- "category": One of "bug", "security", "performance", "breaking", "architecture", "maintainability" or "style".
+- "start_line" and "end_line": The first and last line numbers of the issue, from the numbers before each line, or 0 if the issue has no specific lines.
+- "confidence": How sure you are that the issue is real and worth fixing, from 0 to 1.
+- "guide": The name of the Architecture Guide the issue is based on, as in its === heading, or "" for general issues.
 
 The "body", "original", and "suggestion" fields must be valid JSON strings, meaning all newlines inside them must be escaped as \\n.
 
@@ -70,30 +59,51 @@ The "body", "original", and "suggestion" fields must be valid JSON strings, mean
   {
     "id": "miso-1A",
     "title": "🔴 Critical: Lack of Error Handling",
-    "body": "The function `+"`doSomething`"+` can return an error that is not being checked. This could lead to unexpected behavior.",
+    "body": "The function ` + "`doSomething`" + ` can return an error that is not being checked. This could lead to unexpected behavior.",
     "original": "result := doSomething()",
-    "suggestion": "result, err := doSomething()\\nif err != nil {\\n  return err\\n}"
+    "suggestion": "result, err := doSomething()\\nif err != nil {\\n  return err\\n}",
+    "severity": "critical",
+    "category": "bug",
+    "start_line": 42,
+    "end_line": 42,
+    "confidence": 0.9,
+    "guide": ""
   }
 ]
 
 If you find no issues, return an empty JSON array: [].
-Do not add any introductory text or markdown formatting around the JSON array.
+Do not add any introductory text or markdown formatting around the JSON array.`
 
-Code to review:
-'''
-{{.code}}
-'''
+// CodeReview builds the prompt reviewing code, using the guides of the
+// patterns matching filename.
+func CodeReview(cfg *config.Config, code string, filename string) (
+	Prompt, error,
+) {
+	// Use resolver
+	res := resolver.NewResolver(cfg)
+	guides, err := res.GetGuides(filename)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to get guides: %w", err)
+	}
 
-File: {{.filename}}{{.guide}}`,
-		[]string{"code", "filename", "guide"},
-	)
+	// Load guide content within the guide budget
+	guideSet, err := res.AssembleGuides(guides)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to load guide content: %w", err)
+	}
+
+	tmpl, err := loadTemplate(cfg.Prompts.Review, codeReviewTemplate)
+	if err != nil {
+		return Prompt{}, err
+	}
 
-	// Format the template with the provided values
-	return template.Format(
-		map[string]any{
-			"code":     code,
-			"filename": filename,
-			"guide":    combinedGuides.String(),
+	return render(
+		tmpl, TemplateData{
+			Code:         numberLines(code),
+			Filename:     filename,
+			Language:     Language(filename),
+			Guides:       formatGuides(guideSet.Guides),
+			OutputFormat: codeOutputFormat,
 		},
 	)
 }
@@ -2,65 +2,18 @@ package prompts
 
 import (
 	"fmt"
-	"strings"
+	"text/template"
 
 	"github.com/j0lvera/miso/internal/config"
 	"github.com/j0lvera/miso/internal/git"
 	"github.com/j0lvera/miso/internal/resolver"
-	"github.com/tmc/langchaingo/prompts"
 )
 
-func DiffReview(
-	cfg *config.Config, diffData *git.DiffData, filename string,
-) (string, error) {
-	// Use resolver to get diff-specific guides
-	res := resolver.NewResolver(cfg)
-	guides, err := res.GetDiffGuides(filename)
-	if err != nil {
-		return "", fmt.Errorf("failed to get diff guides: %w", err)
-	}
-
-	// Fallback to regular guides if no diff-specific guides
-	if len(guides) == 0 {
-		guides, err = res.GetGuides(filename)
-		if err != nil {
-			return "", fmt.Errorf("failed to get guides: %w", err)
-		}
-	}
-
-	// Load guide content
-	guideContent, err := res.LoadGuideContent(guides)
-	if err != nil {
-		return "", fmt.Errorf("failed to load guide content: %w", err)
-	}
-
-	// Combine all guide content
-	var combinedGuides strings.Builder
-	if len(guideContent) > 0 {
-		combinedGuides.WriteString("\n\n**Architecture Guides:**\n")
-		for guideName, content := range guideContent {
-			combinedGuides.WriteString(
-				fmt.Sprintf(
-					"\n=== %s ===\n%s\n", guideName, content,
-				),
-			)
-		}
-	}
-
-	// Format the diff for review
-	formattedDiff := diffData.FormatForReview()
-
-	// Analyze the changes
-	addedLines := diffData.GetAddedLines()
-	removedLines := diffData.GetRemovedLines()
-
-	changesSummary := fmt.Sprintf(
-		"Changes Summary:\n- Added lines: %d\n- Removed lines: %d\n- Total hunks: %d",
-		len(addedLines), len(removedLines), len(diffData.Hunks),
-	)
-
-	template := prompts.NewPromptTemplate(
-		`You are an expert code reviewer analyzing specific changes in a pull request. Focus on reviewing ONLY the changes shown in the diff, not the entire file.
+// diffReviewTemplate is the built-in template of diff review prompts. Its
+// prefix is the same for every file reviewed with the same guides.
+var diffReviewTemplate = template.Must(
+	template.New("diff").Parse(
+		`{{define "prefix"}}You are an expert code reviewer analyzing specific changes in a pull request. Focus on reviewing ONLY the changes shown in the diff, not the entire file.
 
 **CHANGE ANALYSIS FOCUS:**
 1. **Breaking Changes**: Does removing code break existing functionality?
@@ -72,12 +25,24 @@ func DiffReview(
 **REVIEW GUIDELINES:**
 - Focus on the specific lines being added (+) and removed (-)
 - Consider the context around changes (unchanged lines)
+- Added and unchanged lines are prefixed with their line number in the new file and "|"; removed lines have no number
 - Flag potential breaking changes from removals
 - Ensure new code follows established patterns
 - Check for proper error handling in new code
 - Verify imports and dependencies are appropriate
 
-**Output Format:**
+{{.OutputFormat}}{{.Guides}}{{end}}**DIFF TO REVIEW:**
+{{.ChangesSummary}}
+
+{{.Diff}}
+
+File: {{.Filename}}`,
+	),
+)
+
+// diffOutputFormat describes the response of diff reviews, which the
+// templates must include as is.
+const diffOutputFormat = `**Output Format:**
 Return your review as a JSON array of suggestion objects.
 - Provide only actionable suggestions for improvement. Do not comment on code that is already good.
 - Sort the suggestions in the final JSON array from most critical to least critical.
@@ -86,8 +51,13 @@ Each object must have the following fields:
 - "id": A unique identifier for the suggestion (e.g., "miso-1A", "miso-1B").
 - "title": A concise, one-line summary of the issue, including a severity emoji (e.g., "🔴 Breaking", "🟡 Risky", "🔴 Critical", "🟡 Warning", "💡 Suggestion", "❌ Inconsistent", "⚠️ Minor Issue").
 - "body": A detailed explanation of the issue in markdown format. This should explain what's wrong and why it matters.
-- "original": (Optional) The exact code to be replaced.
+- "original": (Optional) The exact code to be replaced, without line numbers.
 - "suggestion": (Optional) The new code.
+- "severity": One of "critical", "warning" or "suggestion", matching the emoji of the title.
+- "category": One of "bug", "security", "performance", "breaking", "architecture", "maintainability" or "style".
+- "start_line" and "end_line": The first and last line numbers of the issue, from the numbers before each line, or 0 if the issue has no specific lines.
+- "confidence": How sure you are that the issue is real and worth fixing, from 0 to 1.
+- "guide": The name of the Architecture Guide the issue is based on, as in its === heading, or "" for general issues.
 
 The "body", "original", and "suggestion" fields must be valid JSON strings, meaning all newlines inside them must be escaped as \\n.
 
@@ -96,31 +66,75 @@ The "body", "original", and "suggestion" fields must be valid JSON strings, mean
   {
     "id": "miso-1A",
     "title": "🔴 Breaking: Function signature changed",
-    "body": "The signature of `+"`calculateTotal`"+` was changed, which will break existing callers.",
+    "body": "The signature of ` + "`calculateTotal`" + ` was changed, which will break existing callers.",
     "original": "-func calculateTotal(price int, quantity int)",
-    "suggestion": "+func calculateTotal(price float64, quantity int)"
+    "suggestion": "+func calculateTotal(price float64, quantity int)",
+    "severity": "critical",
+    "category": "breaking",
+    "start_line": 12,
+    "end_line": 12,
+    "confidence": 0.8,
+    "guide": "api.md"
   }
 ]
 
 If you find no issues, return an empty JSON array: [].
-Do not add any introductory text or markdown formatting around the JSON array.
+Do not add any introductory text or markdown formatting around the JSON array.`
+
+// DiffReview builds the prompt reviewing the changes of a diff, using the
+// diff guides of the patterns matching filename, or else their guides. pr
+// describes the pull request under review, if any.
+func DiffReview(
+	cfg *config.Config, diffData *git.DiffData, filename string,
+	pr *PullRequest,
+) (Prompt, error) {
+	// Use resolver to get diff-specific guides
+	res := resolver.NewResolver(cfg)
+	guides, err := res.GetDiffGuides(filename)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to get diff guides: %w", err)
+	}
+
+	// Fallback to regular guides if no diff-specific guides
+	if len(guides) == 0 {
+		guides, err = res.GetGuides(filename)
+		if err != nil {
+			return Prompt{}, fmt.Errorf("failed to get guides: %w", err)
+		}
+	}
+
+	// Load guide content within the guide budget
+	guideSet, err := res.AssembleGuides(guides)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed to load guide content: %w", err)
+	}
 
-**DIFF TO REVIEW:**
-{{.changes_summary}}
+	// Format the diff for review
+	formattedDiff := numberDiff(diffData)
 
-{{.formatted_diff}}
+	// Analyze the changes
+	addedLines := diffData.GetAddedLines()
+	removedLines := diffData.GetRemovedLines()
 
-File: {{.filename}}{{.guide}}`,
-		[]string{"changes_summary", "formatted_diff", "filename", "guide"},
+	changesSummary := fmt.Sprintf(
+		"Changes Summary:\n- Added lines: %d\n- Removed lines: %d\n- Total hunks: %d",
+		len(addedLines), len(removedLines), len(diffData.Hunks),
 	)
 
-	// Format the template with the provided values
-	return template.Format(
-		map[string]any{
-			"changes_summary": changesSummary,
-			"formatted_diff":  formattedDiff,
-			"filename":        filename,
-			"guide":           combinedGuides.String(),
+	tmpl, err := loadTemplate(cfg.Prompts.Diff, diffReviewTemplate)
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	return render(
+		tmpl, TemplateData{
+			Diff:           formattedDiff,
+			ChangesSummary: changesSummary,
+			Filename:       filename,
+			Language:       Language(filename),
+			Guides:         formatGuides(guideSet.Guides),
+			PR:             pr,
+			OutputFormat:   diffOutputFormat,
 		},
 	)
 }
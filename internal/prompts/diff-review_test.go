@@ -161,7 +161,7 @@ func TestDiffReview(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(
 			tt.name, func(t *testing.T) {
-				got, err := DiffReview(cfg, tt.diffData, tt.filename)
+				prompt, err := DiffReview(cfg, tt.diffData, tt.filename, nil)
 
 				if (err != nil) != tt.wantErr {
 					t.Errorf(
@@ -173,6 +173,7 @@ func TestDiffReview(t *testing.T) {
 				if err != nil {
 					return // Skip content checks if we expected an error
 				}
+				got := prompt.String()
 
 				// Check that all expected strings are present
 				for _, expected := range tt.contains {
@@ -217,10 +218,11 @@ func TestDiffReview_GuideIntegration(t *testing.T) {
 		},
 	}
 
-	result, err := DiffReview(cfg, diffData, "test.page.tsx")
+	prompt, err := DiffReview(cfg, diffData, "test.page.tsx", nil)
 	if err != nil {
 		t.Fatalf("DiffReview() failed: %v", err)
 	}
+	result := prompt.String()
 
 	// Should contain the architecture guides section when guides are found
 	// Note: This test may not always have guides depending on file patterns
@@ -249,10 +251,11 @@ func TestDiffReview_FallbackToRegularGuides(t *testing.T) {
 		},
 	}
 
-	result, err := DiffReview(cfg, diffData, "unknown.xyz")
+	prompt, err := DiffReview(cfg, diffData, "unknown.xyz", nil)
 	if err != nil {
 		t.Fatalf("DiffReview() failed: %v", err)
 	}
+	result := prompt.String()
 
 	// Should still generate a valid prompt even without specific guides
 	if !strings.Contains(result, "You are an expert code reviewer") {
@@ -283,10 +286,11 @@ func TestDiffReview_ChangesSummary(t *testing.T) {
 		},
 	}
 
-	result, err := DiffReview(cfg, diffData, "test.go")
+	prompt, err := DiffReview(cfg, diffData, "test.go", nil)
 	if err != nil {
 		t.Fatalf("DiffReview() failed: %v", err)
 	}
+	result := prompt.String()
 
 	// Should correctly count added and removed lines
 	if !strings.Contains(result, "Added lines: 3") {
@@ -0,0 +1,70 @@
+package prompts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/git"
+)
+
+// numberLines prefixes each line of code with its line number, so the model
+// can report where its findings are.
+func numberLines(code string) string {
+	lines := strings.Split(code, "\n")
+	width := len(strconv.Itoa(len(lines)))
+
+	var numbered strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&numbered, "%*d | %s\n", width, i+1, line)
+	}
+	return numbered.String()
+}
+
+// numberDiff formats a diff like git.DiffData.FormatForReview, prefixing
+// added and context lines with their line number in the new file. Removed
+// lines have no number, since they are gone after the change.
+func numberDiff(d *git.DiffData) string {
+	width := 1
+	for _, hunk := range d.Hunks {
+		width = max(width, len(strconv.Itoa(hunk.NewStart+hunk.NewCount)))
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "File: %s\n", d.FilePath)
+
+	switch {
+	case d.IsNew:
+		result.WriteString("Status: New file\n")
+	case d.IsDeleted:
+		result.WriteString("Status: Deleted file\n")
+	case d.IsRenamed:
+		fmt.Fprintf(&result, "Status: Renamed from %s\n", d.OldFilePath)
+	}
+
+	result.WriteString("\nChanges:\n")
+
+	for _, hunk := range d.Hunks {
+		fmt.Fprintf(
+			&result, "\n@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldCount,
+			hunk.NewStart, hunk.NewCount,
+		)
+		if hunk.Header != "" {
+			result.WriteString(" " + hunk.Header)
+		}
+		result.WriteString("\n")
+
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case git.DiffLineAdded:
+				fmt.Fprintf(&result, "%*d |+%s\n", width, line.NewNum, line.Content)
+			case git.DiffLineRemoved:
+				fmt.Fprintf(&result, "%*s |-%s\n", width, "", line.Content)
+			case git.DiffLineContext:
+				fmt.Fprintf(&result, "%*d | %s\n", width, line.NewNum, line.Content)
+			}
+		}
+	}
+
+	return result.String()
+}
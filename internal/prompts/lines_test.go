@@ -0,0 +1,45 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/git"
+)
+
+func TestNumberLines(t *testing.T) {
+	code := strings.Repeat("x\n", 9) + "last"
+
+	got := numberLines(code)
+	for _, want := range []string{" 1 | x\n", " 9 | x\n", "10 | last\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("numberLines() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestNumberDiff(t *testing.T) {
+	diff := &git.DiffData{
+		FilePath: "main.go",
+		Hunks: []git.DiffHunk{
+			{
+				OldStart: 9, OldCount: 2, NewStart: 9, NewCount: 2,
+				Lines: []git.DiffLine{
+					{Type: git.DiffLineContext, Content: "func main() {", OldNum: 9, NewNum: 9},
+					{Type: git.DiffLineRemoved, Content: "\told()", OldNum: 10},
+					{Type: git.DiffLineAdded, Content: "\tnew()", NewNum: 10},
+				},
+			},
+		},
+	}
+
+	got := numberDiff(diff)
+	for _, want := range []string{
+		"File: main.go\n", "@@ -9,2 +9,2 @@\n", " 9 | func main() {\n",
+		"   |-\told()\n", "10 |+\tnew()\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("numberDiff() = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package prompts
+
+import (
+	"strings"
+
+	"github.com/j0lvera/miso/internal/resolver"
+)
+
+// Prompt is a review prompt split into a prefix that is the same for every
+// file reviewed with the same guides, and a suffix with the file itself.
+// Sending the prefix first lets providers cache it across files.
+type Prompt struct {
+	Prefix string // Instructions and guides
+	Suffix string // The code or diff under review
+}
+
+// String returns the whole prompt, as sent to providers without separate
+// system messages.
+func (p Prompt) String() string {
+	if p.Prefix == "" {
+		return p.Suffix
+	}
+	return p.Prefix + "\n\n" + p.Suffix
+}
+
+// formatGuides renders the guides in match order, which is the same on
+// every call for the same guides.
+func formatGuides(guides []resolver.Guide) string {
+	if len(guides) == 0 {
+		return ""
+	}
+
+	var combinedGuides strings.Builder
+	combinedGuides.WriteString("\n\n**Architecture Guides:**\n")
+	for _, guide := range guides {
+		combinedGuides.WriteString(guide.Format())
+	}
+	return combinedGuides.String()
+}
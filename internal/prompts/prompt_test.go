@@ -0,0 +1,52 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/resolver"
+)
+
+func TestCodeReview_Prefix(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	first, err := CodeReview(cfg, "package main", "main.go")
+	if err != nil {
+		t.Fatalf("CodeReview() error = %v", err)
+	}
+	second, err := CodeReview(cfg, "package util", "util.go")
+	if err != nil {
+		t.Fatalf("CodeReview() error = %v", err)
+	}
+
+	// Files reviewed with the same guides share the prefix
+	if first.Prefix != second.Prefix {
+		t.Error("Expected the same prefix for both files")
+	}
+	if strings.Contains(first.Prefix, "package main") ||
+		!strings.Contains(first.Suffix, "package main") {
+		t.Error("Expected the code in the suffix only")
+	}
+	if !strings.HasPrefix(first.String(), first.Prefix) ||
+		!strings.HasSuffix(first.String(), first.Suffix) {
+		t.Error("Expected String() to join the prefix and the suffix")
+	}
+}
+
+func TestFormatGuides(t *testing.T) {
+	got := formatGuides(
+		[]resolver.Guide{
+			{Name: "style.md", Content: "b"},
+			{Name: "api.md", Content: "a"},
+		},
+	)
+	want := "\n\n**Architecture Guides:**\n\n=== style.md ===\nb\n\n=== api.md ===\na\n"
+	if got != want {
+		t.Errorf("formatGuides() = %q, want %q", got, want)
+	}
+
+	if got := formatGuides(nil); got != "" {
+		t.Errorf("formatGuides(nil) = %q, want empty", got)
+	}
+}
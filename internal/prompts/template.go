@@ -0,0 +1,215 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/j0lvera/miso/internal/config"
+)
+
+// prefixTemplate is the template a prompt template may define for the part
+// of the prompt that is the same for every file, sent first so providers
+// can cache it.
+const prefixTemplate = "prefix"
+
+// TemplateData holds the variables of prompt templates.
+type TemplateData struct {
+	Code           string       // The reviewed code, each line prefixed with its number; empty for diffs
+	Diff           string       // The reviewed diff, with the new line numbers; empty for files
+	ChangesSummary string       // Counts of added and removed lines and hunks of the diff
+	Filename       string       // Path of the reviewed file
+	Language       string       // Language of the file, from its extension; empty if unknown
+	Guides         string       // The guides matching the file, under a heading; empty without guides
+	PR             *PullRequest // The pull request under review; nil outside github review-pr
+	OutputFormat   string       // The response format miso parses; templates must include it
+}
+
+// PullRequest describes the pull request under review.
+type PullRequest struct {
+	Number int
+	Title  string
+	Body   string
+	Author string
+	Base   string // Base branch
+	Head   string // Head branch
+}
+
+// templates caches the parsed template files by path, since a prompt is
+// rendered again for each step of fitting it to the budget.
+var templates sync.Map
+
+// loadTemplate parses the template file at path, or returns builtin when
+// path is empty. Each file is parsed once.
+func loadTemplate(path string, builtin *template.Template) (
+	*template.Template, error,
+) {
+	if path == "" {
+		return builtin, nil
+	}
+	if tmpl, ok := templates.Load(path); ok {
+		return tmpl.(*template.Template), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	templates.Store(path, tmpl)
+	return tmpl, nil
+}
+
+// render executes a prompt template. The prefix template, when defined,
+// renders the prefix of the prompt and the template itself the suffix.
+func render(tmpl *template.Template, data TemplateData) (Prompt, error) {
+	var prompt Prompt
+
+	if prefix := tmpl.Lookup(prefixTemplate); prefix != nil {
+		var rendered strings.Builder
+		if err := prefix.Execute(&rendered, data); err != nil {
+			return prompt, fmt.Errorf("failed to render prompt template: %w", err)
+		}
+		prompt.Prefix = rendered.String()
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return prompt, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	prompt.Suffix = rendered.String()
+
+	return prompt, nil
+}
+
+// Sample values the templates are validated with.
+const (
+	sampleCode = "func sample() error {\n\treturn nil\n}"
+	sampleDiff = "@@ -1,1 +1,1 @@\n-\treturn err\n+\treturn nil"
+)
+
+// ValidateTemplates checks that the custom prompt templates of cfg parse,
+// render with and without a pull request, and keep the output contract:
+// the response format miso parses and the code or diff under review must be
+// part of the prompt, or responses would fail to parse.
+func ValidateTemplates(cfg *config.Config) error {
+	var errs []error
+
+	if cfg.Prompts.Review != "" {
+		data := TemplateData{
+			Code:         numberLines(sampleCode),
+			Filename:     "sample.go",
+			Language:     "Go",
+			OutputFormat: codeOutputFormat,
+		}
+		if err := checkTemplate(cfg.Prompts.Review, data, data.Code, "Code"); err != nil {
+			errs = append(errs, fmt.Errorf("prompts.review: %w", err))
+		}
+	}
+
+	if cfg.Prompts.Diff != "" {
+		data := TemplateData{
+			Diff:           sampleDiff,
+			ChangesSummary: "Changes Summary:\n- Added lines: 1\n- Removed lines: 1\n- Total hunks: 1",
+			Filename:       "sample.go",
+			Language:       "Go",
+			OutputFormat:   diffOutputFormat,
+		}
+		if err := checkTemplate(cfg.Prompts.Diff, data, data.Diff, "Diff"); err != nil {
+			errs = append(errs, fmt.Errorf("prompts.diff: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkTemplate renders the template at path with data, once without and
+// once with a pull request, and checks that the prompts include the output
+// format and subject, the value of the variable named field.
+func checkTemplate(
+	path string, data TemplateData, subject string, field string,
+) error {
+	tmpl, err := loadTemplate(path, nil)
+	if err != nil {
+		return err
+	}
+
+	samplePR := &PullRequest{
+		Number: 1,
+		Title:  "Sample pull request",
+		Author: "octocat",
+		Base:   "main",
+		Head:   "feature",
+	}
+	for _, pr := range []*PullRequest{nil, samplePR} {
+		data.PR = pr
+		prompt, err := render(tmpl, data)
+		if err != nil {
+			return err
+		}
+
+		if !strings.Contains(prompt.String(), data.OutputFormat) {
+			return fmt.Errorf(
+				"%s must include {{.OutputFormat}}, the response format miso parses",
+				path,
+			)
+		}
+		if !strings.Contains(prompt.String(), subject) {
+			return fmt.Errorf(
+				"%s must include {{.%s}}, the %s under review", path, field,
+				strings.ToLower(field),
+			)
+		}
+	}
+	return nil
+}
+
+// languages maps file extensions to the name of their language.
+var languages = map[string]string{
+	".c":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cs":     "C#",
+	".css":    "CSS",
+	".go":     "Go",
+	".h":      "C",
+	".hpp":    "C++",
+	".html":   "HTML",
+	".java":   "Java",
+	".js":     "JavaScript",
+	".json":   "JSON",
+	".jsx":    "JavaScript",
+	".kt":     "Kotlin",
+	".md":     "Markdown",
+	".php":    "PHP",
+	".proto":  "Protocol Buffers",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".rs":     "Rust",
+	".scala":  "Scala",
+	".scss":   "SCSS",
+	".sh":     "Shell",
+	".sql":    "SQL",
+	".svelte": "Svelte",
+	".swift":  "Swift",
+	".tf":     "Terraform",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".vue":    "Vue",
+	".yaml":   "YAML",
+	".yml":    "YAML",
+}
+
+// Language returns the language of a file from its extension, or an empty
+// string when it isn't known.
+func Language(filename string) string {
+	return languages[strings.ToLower(filepath.Ext(filename))]
+}
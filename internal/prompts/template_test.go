@@ -0,0 +1,183 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/git"
+)
+
+// writeTemplate writes a prompt template to a temporary file.
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	return path
+}
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		review  string
+		diff    string
+		wantErr string
+	}{
+		{
+			name:   "valid templates",
+			review: `{{define "prefix"}}Review {{.Language}} code.{{.Guides}}{{.OutputFormat}}{{end}}{{.Code}}`,
+			diff:   `{{with .PR}}PR #{{.Number}}: {{.Title}}{{end}}{{.OutputFormat}}{{.ChangesSummary}}{{.Diff}}`,
+		},
+		{
+			name:    "missing output format",
+			review:  `Review this: {{.Code}}`,
+			wantErr: "must include {{.OutputFormat}}",
+		},
+		{
+			name:    "missing diff",
+			diff:    `{{.OutputFormat}} {{.Filename}}`,
+			wantErr: "must include {{.Diff}}",
+		},
+		{
+			name:    "pull request without a guard",
+			diff:    `PR {{.PR.Title}}{{.OutputFormat}}{{.Diff}}`,
+			wantErr: "nil pointer",
+		},
+		{
+			name:    "unknown variable",
+			review:  `{{.Source}}{{.OutputFormat}}`,
+			wantErr: "can't evaluate field Source",
+		},
+		{
+			name:    "syntax error",
+			review:  `{{.Code}`,
+			wantErr: "failed to parse prompt template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cfg := config.DefaultConfig()
+				if tt.review != "" {
+					cfg.Prompts.Review = writeTemplate(t, tt.review)
+				}
+				if tt.diff != "" {
+					cfg.Prompts.Diff = writeTemplate(t, tt.diff)
+				}
+
+				err := ValidateTemplates(cfg)
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("ValidateTemplates() error = %v", err)
+					}
+					return
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf(
+						"ValidateTemplates() error = %v, want %q", err, tt.wantErr,
+					)
+				}
+			},
+		)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Prompts.Review = filepath.Join(t.TempDir(), "missing.tmpl")
+	if err := ValidateTemplates(cfg); err == nil {
+		t.Error("Expected an error for a missing template file")
+	}
+}
+
+func TestCodeReview_CustomTemplate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Prompts.Review = writeTemplate(
+		t,
+		`{{define "prefix"}}Review {{.Language}} code.
+{{.OutputFormat}}{{end}}{{.Filename}}:
+{{.Code}}`,
+	)
+
+	prompt, err := CodeReview(cfg, "package main", "cmd/main.go")
+	if err != nil {
+		t.Fatalf("CodeReview() error = %v", err)
+	}
+
+	if !strings.HasPrefix(prompt.Prefix, "Review Go code.\n**Output Format:**") {
+		t.Errorf("Unexpected prefix: %q", prompt.Prefix)
+	}
+	if want := "cmd/main.go:\n1 | package main\n"; prompt.Suffix != want {
+		t.Errorf("Suffix = %q, want %q", prompt.Suffix, want)
+	}
+}
+
+func TestDiffReview_PullRequest(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Prompts.Diff = writeTemplate(
+		t,
+		`{{with .PR}}#{{.Number}} {{.Title}} by {{.Author}} ({{.Head}} into {{.Base}})
+{{end}}{{.OutputFormat}}{{.Diff}}`,
+	)
+	diffData := &git.DiffData{FilePath: "main.go"}
+	pr := &PullRequest{
+		Number: 42,
+		Title:  "Add retries",
+		Author: "octocat",
+		Base:   "main",
+		Head:   "retries",
+	}
+
+	prompt, err := DiffReview(cfg, diffData, "main.go", pr)
+	if err != nil {
+		t.Fatalf("DiffReview() error = %v", err)
+	}
+	if want := "#42 Add retries by octocat (retries into main)\n"; !strings.HasPrefix(prompt.String(), want) {
+		t.Errorf("Expected the pull request in the prompt, got %q", prompt.String())
+	}
+
+	prompt, err = DiffReview(cfg, diffData, "main.go", nil)
+	if err != nil {
+		t.Fatalf("DiffReview() error = %v", err)
+	}
+	if prompt.Prefix != "" || !strings.HasPrefix(prompt.Suffix, "**Output Format:**") {
+		t.Errorf("Expected the whole prompt in the suffix, got %+v", prompt)
+	}
+}
+
+func TestLoadTemplate_Cached(t *testing.T) {
+	path := writeTemplate(t, "first {{.OutputFormat}}")
+	first, err := loadTemplate(path, nil)
+	if err != nil {
+		t.Fatalf("loadTemplate() error = %v", err)
+	}
+
+	// The file is parsed once, so later changes aren't read back
+	if err := os.WriteFile(path, []byte("second {{.OutputFormat}}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	second, err := loadTemplate(path, nil)
+	if err != nil {
+		t.Fatalf("loadTemplate() error = %v", err)
+	}
+	if first != second {
+		t.Error("Expected the parsed template to be reused")
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tests := map[string]string{
+		"main.go":           "Go",
+		"src/App.TSX":       "TypeScript",
+		"scripts/deploy.sh": "Shell",
+		"Makefile":          "",
+	}
+	for filename, want := range tests {
+		if got := Language(filename); got != want {
+			t.Errorf("Language(%q) = %q, want %q", filename, got, want)
+		}
+	}
+}
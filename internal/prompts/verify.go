@@ -0,0 +1,57 @@
+package prompts
+
+import (
+	"github.com/tmc/langchaingo/prompts"
+)
+
+// Verify formats the prompt of the verification pass, which asks the model
+// to score the suggestions of a first review of subject. subject is the
+// reviewed code or diff, and suggestions the JSON of the suggestions.
+func Verify(subject string, filename string, suggestions string) (
+	string, error,
+) {
+	template := prompts.NewPromptTemplate(
+		`You are a senior engineer double-checking the suggestions of an automated code review before they reach the author. Many automated suggestions are wrong or not worth the author's time; your job is to catch them.
+
+For each suggestion below, judge:
+- **Correctness:** Is the issue real? Check it against the code. Suggestions that misread the code, rely on context that isn't shown, or refer to code that doesn't exist are wrong.
+- **Value:** Is it worth acting on? Style nitpicks, speculative concerns and restatements of what the code obviously does have little value.
+
+**Output Format:**
+Return a JSON array with one object per suggestion, with the following fields:
+- "id": The id of the suggestion.
+- "confidence": A number from 0 to 1: how confident you are that the suggestion is both correct and worth acting on.
+- "reason": One sentence explaining the score.
+
+**Example JSON Output:**
+[
+  {
+    "id": "suggestion-1",
+    "confidence": 0.9,
+    "reason": "The error returned by Close is ignored on the write path, so failed writes go unnoticed."
+  }
+]
+
+Do not add any introductory text or markdown formatting around the JSON array.
+
+Code under review:
+'''
+{{.subject}}
+'''
+
+File: {{.filename}}
+
+Suggestions to verify:
+{{.suggestions}}`,
+		[]string{"subject", "filename", "suggestions"},
+	)
+
+	// Format the template with the provided values
+	return template.Format(
+		map[string]any{
+			"subject":     subject,
+			"filename":    filename,
+			"suggestions": suggestions,
+		},
+	)
+}
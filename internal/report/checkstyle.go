@@ -0,0 +1,88 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// checkstyleVersion is the Checkstyle version whose report format is written.
+const checkstyleVersion = "8.0"
+
+// checkstyleSeverities maps severities to Checkstyle severities.
+var checkstyleSeverities = map[agents.Severity]string{
+	agents.SeverityCritical:   "error",
+	agents.SeverityWarning:    "warning",
+	agents.SeveritySuggestion: "info",
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// checkstyleEmitter writes the run as a Checkstyle XML report, with an
+// error for each finding and for each file that failed to be reviewed.
+type checkstyleEmitter struct {
+	w      io.Writer
+	report checkstyleReport
+}
+
+func (e *checkstyleEmitter) File(file File) error {
+	entry := checkstyleFile{Name: file.Path}
+	if file.Error != "" {
+		entry.Errors = append(
+			entry.Errors, checkstyleError{
+				Severity: "error",
+				Message:  "Review failed: " + file.Error,
+				Source:   toolName + ".review",
+			},
+		)
+	}
+	for _, suggestion := range file.Suggestions {
+		entry.Errors = append(
+			entry.Errors, checkstyleError{
+				Line:     suggestion.StartLine,
+				Severity: level(checkstyleSeverities, suggestion.Severity),
+				Message:  message(suggestion),
+				Source:   toolName + "." + findingRule(suggestion).id,
+			},
+		)
+	}
+
+	e.report.Files = append(e.report.Files, entry)
+	return nil
+}
+
+func (e *checkstyleEmitter) Close(Summary) error {
+	e.report.Version = checkstyleVersion
+	return writeXML(e.w, e.report)
+}
+
+// writeXML writes v as an indented XML document.
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
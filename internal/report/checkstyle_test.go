@@ -0,0 +1,44 @@
+package report
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestCheckstyle(t *testing.T) {
+	output := emit(t, FormatCheckstyle, sampleFiles()...)
+	if !strings.HasPrefix(string(output), xml.Header) {
+		t.Errorf("Expected an XML header, got %q", output)
+	}
+
+	var report checkstyleReport
+	if err := xml.Unmarshal(output, &report); err != nil {
+		t.Fatalf("Invalid Checkstyle XML: %v", err)
+	}
+	if len(report.Files) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(report.Files))
+	}
+
+	errors := report.Files[0].Errors
+	if len(errors) != 2 {
+		t.Fatalf("Expected 2 errors, got %+v", errors)
+	}
+	if errors[0].Line != 12 || errors[0].Severity != "error" ||
+		errors[0].Source != "miso.guide/database.md" ||
+		!strings.HasPrefix(errors[0].Message, "SQL injection\n\nUse a parameterized query.") {
+		t.Errorf("Unexpected error: %+v", errors[0])
+	}
+	if errors[1].Line != 0 || errors[1].Severity != "info" ||
+		errors[1].Source != "miso.general" {
+		t.Errorf("Unexpected error: %+v", errors[1])
+	}
+
+	if len(report.Files[1].Errors) != 0 {
+		t.Errorf("Expected no errors for a clean file, got %+v", report.Files[1].Errors)
+	}
+	if failed := report.Files[2].Errors; len(failed) != 1 ||
+		failed[0].Message != "Review failed: timeout" {
+		t.Errorf("Expected the review failure, got %+v", failed)
+	}
+}
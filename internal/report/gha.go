@@ -0,0 +1,162 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// ghaCommands maps severities to GitHub Actions workflow commands.
+var ghaCommands = map[agents.Severity]string{
+	agents.SeverityCritical:   "error",
+	agents.SeverityWarning:    "warning",
+	agents.SeveritySuggestion: "notice",
+}
+
+// ghaFile is a reviewed file as listed in the job summary.
+type ghaFile struct {
+	path   string
+	counts map[string]int // Findings by workflow command
+	err    string
+}
+
+// ghaEmitter writes a workflow command for each finding, which GitHub
+// Actions shows as an annotation on the changed line, and appends a summary
+// of the run to the job summary.
+type ghaEmitter struct {
+	w           io.Writer
+	stepSummary io.Writer
+	files       []ghaFile
+}
+
+func (e *ghaEmitter) File(file File) error {
+	entry := ghaFile{path: file.Path, counts: make(map[string]int)}
+
+	if file.Error != "" {
+		entry.err = file.Error
+		if err := writeCommand(
+			e.w, "error", map[string]string{
+				"file":  file.Path,
+				"title": "miso review failed",
+			},
+			file.Error,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, suggestion := range file.Suggestions {
+		command := level(ghaCommands, suggestion.Severity)
+		entry.counts[command]++
+
+		properties := map[string]string{
+			"file":  file.Path,
+			"title": suggestion.Title,
+		}
+		if suggestion.StartLine > 0 {
+			properties["line"] = fmt.Sprint(suggestion.StartLine)
+			if suggestion.EndLine > suggestion.StartLine {
+				properties["endLine"] = fmt.Sprint(suggestion.EndLine)
+			}
+		}
+
+		text := details(suggestion)
+		if text == "" {
+			text = suggestion.Title
+		}
+		if err := writeCommand(e.w, command, properties, text); err != nil {
+			return err
+		}
+	}
+
+	if entry.err != "" || len(file.Suggestions) > 0 {
+		e.files = append(e.files, entry)
+	}
+	return nil
+}
+
+func (e *ghaEmitter) Close(summary Summary) error {
+	if e.stepSummary == nil {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("## 🍲 miso review\n\n")
+	if len(e.files) == 0 {
+		fmt.Fprintf(&b, "✅ No issues found in %d files.\n", summary.Files)
+	} else {
+		b.WriteString("| File | Critical | Warnings | Suggestions |\n")
+		b.WriteString("| --- | ---: | ---: | ---: |\n")
+		for _, file := range e.files {
+			if file.err != "" {
+				fmt.Fprintf(&b, "| `%s` | ❌ %s | | |\n", file.path, tableCell(file.err))
+				continue
+			}
+			fmt.Fprintf(
+				&b, "| `%s` | %d | %d | %d |\n", file.path,
+				file.counts["error"], file.counts["warning"], file.counts["notice"],
+			)
+		}
+		fmt.Fprintf(
+			&b, "\n%d findings in %d files reviewed", summary.Suggestions,
+			summary.Files,
+		)
+		if summary.Failed > 0 {
+			fmt.Fprintf(&b, ", %d failed", summary.Failed)
+		}
+		b.WriteString(".\n")
+	}
+
+	if len(summary.Adjustments) > 0 {
+		b.WriteString("\n**Budget adjustments**\n\n")
+		for _, a := range summary.Adjustments {
+			fmt.Fprintf(&b, "- `%s` %s: %s\n", a.File, a.Action, a.Reason)
+		}
+	}
+	if summary.Usage.Total > 0 {
+		fmt.Fprintf(
+			&b, "\n<sub>%d tokens · $%.4f</sub>\n", summary.Usage.Total,
+			summary.Cost,
+		)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(e.stepSummary, b.String())
+	return err
+}
+
+// writeCommand writes a workflow command with its properties and message.
+func writeCommand(
+	w io.Writer, command string, properties map[string]string, message string,
+) error {
+	var props []string
+	for _, key := range []string{"file", "line", "endLine", "title"} {
+		if value, ok := properties[key]; ok {
+			props = append(props, key+"="+escapeProperty(value))
+		}
+	}
+	_, err := fmt.Fprintf(
+		w, "::%s %s::%s\n", command, strings.Join(props, ","),
+		escapeData(message),
+	)
+	return err
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer(
+		"%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C",
+	).Replace(s)
+}
+
+// tableCell keeps text on a single Markdown table cell.
+func tableCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
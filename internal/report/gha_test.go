@@ -0,0 +1,64 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGHA(t *testing.T) {
+	var out, stepSummary bytes.Buffer
+	emitter, err := New(FormatGHA, &out, Options{StepSummary: &stepSummary})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var summary Summary
+	for _, file := range sampleFiles() {
+		if err := emitter.File(file); err != nil {
+			t.Fatalf("File() error = %v", err)
+		}
+		summary.Add(file)
+	}
+	summary.Adjustments = []Adjustment{
+		{File: "big.go", Action: "skipped", Reason: "over budget"},
+	}
+	if err := emitter.Close(summary); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []string{
+		"::error file=internal/db/query.go,line=12,endLine=13,title=SQL injection::" +
+			"Use a parameterized query.%0A%0ASuggested change:%0Arows, err := db.Query(\"SELECT ?\", id)",
+		"::notice file=internal/db/query.go,title=Unclear name::Unclear name",
+		"::error file=api.go,title=miso review failed::timeout",
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("Commands = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Command %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	for _, part := range []string{
+		"| `internal/db/query.go` | 1 | 0 | 1 |",
+		"| `api.go` | ❌ timeout | | |",
+		"2 findings in 3 files reviewed, 1 failed.",
+		"- `big.go` skipped: over budget",
+	} {
+		if !strings.Contains(stepSummary.String(), part) {
+			t.Errorf("Job summary is missing %q:\n%s", part, stepSummary.String())
+		}
+	}
+}
+
+func TestEscapeProperty(t *testing.T) {
+	got := escapeProperty("Warning: 100%, really\nsure")
+	want := "Warning%3A 100%25%2C really%0Asure"
+	if got != want {
+		t.Errorf("escapeProperty() = %q, want %q", got, want)
+	}
+}
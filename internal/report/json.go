@@ -0,0 +1,57 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonEmitter writes the whole run as a single JSON document.
+type jsonEmitter struct {
+	w     io.Writer
+	files []File
+}
+
+func (e *jsonEmitter) File(file File) error {
+	e.files = append(e.files, file)
+	return nil
+}
+
+func (e *jsonEmitter) Close(summary Summary) error {
+	files := e.files
+	if files == nil {
+		files = []File{}
+	}
+
+	encoder := json.NewEncoder(e.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(
+		struct {
+			Files   []File  `json:"files"`
+			Summary Summary `json:"summary"`
+		}{files, summary},
+	)
+}
+
+// jsonlEmitter writes one JSON object per line: a "file" line as each file
+// is reviewed, then a "summary" line.
+type jsonlEmitter struct {
+	w io.Writer
+}
+
+func (e *jsonlEmitter) File(file File) error {
+	return json.NewEncoder(e.w).Encode(
+		struct {
+			Type string `json:"type"`
+			File
+		}{"file", file},
+	)
+}
+
+func (e *jsonlEmitter) Close(summary Summary) error {
+	return json.NewEncoder(e.w).Encode(
+		struct {
+			Type string `json:"type"`
+			Summary
+		}{"summary", summary},
+	)
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+type junitReport struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitEmitter writes the run as a JUnit XML report: a test suite for each
+// file, with a failed test case for each finding, or a passing one for a
+// file without findings.
+type junitEmitter struct {
+	w      io.Writer
+	report junitReport
+}
+
+func (e *junitEmitter) File(file File) error {
+	suite := junitSuite{
+		Name: file.Path,
+		Time: seconds(file.DurationMS),
+	}
+
+	switch {
+	case file.Error != "":
+		suite.Cases = append(
+			suite.Cases, junitCase{
+				Name:      "review",
+				ClassName: file.Path,
+				File:      file.Path,
+				Error: &junitProblem{
+					Message: "Review failed",
+					Type:    "error",
+					Text:    file.Error,
+				},
+			},
+		)
+		suite.Errors++
+	case file.Skipped && len(file.Suggestions) == 0:
+		suite.Cases = append(
+			suite.Cases, junitCase{
+				Name:      "review",
+				ClassName: file.Path,
+				File:      file.Path,
+				Skipped:   &junitSkipped{Message: "Skipped by the budget"},
+			},
+		)
+		suite.Skipped++
+	case len(file.Suggestions) == 0:
+		suite.Cases = append(
+			suite.Cases, junitCase{
+				Name:      "review",
+				ClassName: file.Path,
+				File:      file.Path,
+			},
+		)
+	}
+
+	for _, suggestion := range file.Suggestions {
+		suite.Cases = append(
+			suite.Cases, junitCase{
+				Name:      caseName(suggestion),
+				ClassName: file.Path,
+				File:      file.Path,
+				Line:      suggestion.StartLine,
+				Failure: &junitProblem{
+					Message: suggestion.Title,
+					Type:    severityName(suggestion.Severity),
+					Text:    message(suggestion),
+				},
+			},
+		)
+		suite.Failures++
+	}
+	suite.Tests = len(suite.Cases)
+
+	e.report.Tests += suite.Tests
+	e.report.Failures += suite.Failures
+	e.report.Errors += suite.Errors
+	e.report.Suites = append(e.report.Suites, suite)
+	return nil
+}
+
+func (e *junitEmitter) Close(summary Summary) error {
+	e.report.Name = toolName
+	e.report.Time = seconds(summary.DurationMS)
+	return writeXML(e.w, e.report)
+}
+
+// caseName names the test case of a finding after its line and title.
+func caseName(suggestion agents.Suggestion) string {
+	if suggestion.StartLine == 0 {
+		return suggestion.Title
+	}
+	return fmt.Sprintf("line %d: %s", suggestion.StartLine, suggestion.Title)
+}
+
+// severityName returns a severity, or warning when it is unknown.
+func severityName(severity agents.Severity) string {
+	if severity == "" {
+		return string(agents.SeverityWarning)
+	}
+	return string(severity)
+}
+
+// seconds formats milliseconds as the seconds of a JUnit time attribute.
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
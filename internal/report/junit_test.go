@@ -0,0 +1,46 @@
+package report
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestJUnit(t *testing.T) {
+	var report junitReport
+	if err := xml.Unmarshal(emit(t, FormatJUnit, sampleFiles()...), &report); err != nil {
+		t.Fatalf("Invalid JUnit XML: %v", err)
+	}
+
+	if report.Name != "miso" || report.Tests != 4 || report.Failures != 2 ||
+		report.Errors != 1 {
+		t.Errorf(
+			"Totals = %d tests, %d failures, %d errors",
+			report.Tests, report.Failures, report.Errors,
+		)
+	}
+	if len(report.Suites) != 3 {
+		t.Fatalf("Expected a suite per file, got %d", len(report.Suites))
+	}
+
+	findings := report.Suites[0]
+	if findings.Name != "internal/db/query.go" || findings.Time != "1.250" ||
+		len(findings.Cases) != 2 {
+		t.Fatalf("Unexpected suite: %+v", findings)
+	}
+	failed := findings.Cases[0]
+	if failed.Name != "line 12: SQL injection" || failed.Line != 12 ||
+		failed.Failure == nil || failed.Failure.Type != "critical" {
+		t.Errorf("Unexpected test case: %+v", failed)
+	}
+
+	clean := report.Suites[1].Cases
+	if len(clean) != 1 || clean[0].Failure != nil || clean[0].Error != nil {
+		t.Errorf("Expected a passing test case, got %+v", clean)
+	}
+
+	errored := report.Suites[2].Cases
+	if len(errored) != 1 || errored[0].Error == nil ||
+		errored[0].Error.Text != "timeout" {
+		t.Errorf("Expected an error test case, got %+v", errored)
+	}
+}
@@ -0,0 +1,125 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// rdjsonSeverities maps severities to reviewdog severities.
+var rdjsonSeverities = map[agents.Severity]string{
+	agents.SeverityCritical:   "ERROR",
+	agents.SeverityWarning:    "WARNING",
+	agents.SeveritySuggestion: "INFO",
+}
+
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+type rdjsonSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type rdjsonDiagnostic struct {
+	Message     string             `json:"message"`
+	Location    rdjsonLocation     `json:"location"`
+	Severity    string             `json:"severity"`
+	Code        *rdjsonCode        `json:"code,omitempty"`
+	Suggestions []rdjsonSuggestion `json:"suggestions,omitempty"`
+}
+
+type rdjsonLocation struct {
+	Path  string       `json:"path"`
+	Range *rdjsonRange `json:"range,omitempty"`
+}
+
+type rdjsonRange struct {
+	Start rdjsonPosition  `json:"start"`
+	End   *rdjsonPosition `json:"end,omitempty"`
+}
+
+type rdjsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+type rdjsonSuggestion struct {
+	Range rdjsonRange `json:"range"`
+	Text  string      `json:"text"`
+}
+
+// rdjsonEmitter writes the run in reviewdog's Diagnostic JSON format, for
+// reviewdog -f=rdjson.
+type rdjsonEmitter struct {
+	w      io.Writer
+	result rdjsonResult
+}
+
+func newRDJSONEmitter(w io.Writer) *rdjsonEmitter {
+	return &rdjsonEmitter{
+		w: w,
+		result: rdjsonResult{
+			Source:      rdjsonSource{Name: toolName, URL: toolURI},
+			Diagnostics: []rdjsonDiagnostic{},
+		},
+	}
+}
+
+func (e *rdjsonEmitter) File(file File) error {
+	if file.Error != "" {
+		e.result.Diagnostics = append(
+			e.result.Diagnostics, rdjsonDiagnostic{
+				Message:  "Review failed: " + file.Error,
+				Location: rdjsonLocation{Path: file.Path},
+				Severity: rdjsonSeverities[agents.SeverityCritical],
+			},
+		)
+	}
+
+	for _, suggestion := range file.Suggestions {
+		diagnostic := rdjsonDiagnostic{
+			Message:  message(suggestion),
+			Location: rdjsonLocation{Path: file.Path},
+			Severity: level(rdjsonSeverities, suggestion.Severity),
+			Code:     &rdjsonCode{Value: findingRule(suggestion).id},
+		}
+
+		if suggestion.StartLine > 0 {
+			end := max(suggestion.EndLine, suggestion.StartLine)
+			diagnostic.Location.Range = &rdjsonRange{
+				Start: rdjsonPosition{Line: suggestion.StartLine},
+				End:   &rdjsonPosition{Line: end},
+			}
+
+			// The suggested code replaces the quoted lines as a whole
+			if suggestion.Original != "" && suggestion.Suggestion != "" {
+				text := strings.ReplaceAll(suggestion.Suggestion, "\\n", "\n")
+				diagnostic.Suggestions = []rdjsonSuggestion{
+					{
+						Range: rdjsonRange{
+							Start: rdjsonPosition{Line: suggestion.StartLine, Column: 1},
+							End:   &rdjsonPosition{Line: end + 1, Column: 1},
+						},
+						Text: strings.TrimSuffix(text, "\n") + "\n",
+					},
+				}
+			}
+		}
+
+		e.result.Diagnostics = append(e.result.Diagnostics, diagnostic)
+	}
+	return nil
+}
+
+func (e *rdjsonEmitter) Close(Summary) error {
+	return json.NewEncoder(e.w).Encode(e.result)
+}
@@ -0,0 +1,43 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRDJSON(t *testing.T) {
+	var result rdjsonResult
+	if err := json.Unmarshal(emit(t, FormatRDJSON, sampleFiles()...), &result); err != nil {
+		t.Fatalf("Invalid rdjson: %v", err)
+	}
+
+	if result.Source.Name != "miso" || len(result.Diagnostics) != 3 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	injection := result.Diagnostics[0]
+	if injection.Severity != "ERROR" || injection.Code.Value != "guide/database.md" ||
+		injection.Location.Path != "internal/db/query.go" {
+		t.Errorf("Unexpected diagnostic: %+v", injection)
+	}
+	if r := injection.Location.Range; r == nil || r.Start.Line != 12 ||
+		r.End == nil || r.End.Line != 13 {
+		t.Errorf("Unexpected range: %+v", r)
+	}
+	if len(injection.Suggestions) != 1 {
+		t.Fatalf("Expected a suggestion, got %+v", injection.Suggestions)
+	}
+	fix := injection.Suggestions[0]
+	if fix.Range.Start.Line != 12 || fix.Range.End.Line != 14 ||
+		fix.Text != "rows, err := db.Query(\"SELECT ?\", id)\n" {
+		t.Errorf("Unexpected suggestion: %+v", fix)
+	}
+
+	if unclear := result.Diagnostics[1]; unclear.Severity != "INFO" ||
+		unclear.Location.Range != nil || unclear.Suggestions != nil {
+		t.Errorf("Unexpected diagnostic: %+v", unclear)
+	}
+	if failed := result.Diagnostics[2]; failed.Message != "Review failed: timeout" {
+		t.Errorf("Expected the review failure, got %+v", failed)
+	}
+}
@@ -0,0 +1,248 @@
+// Package report writes review results in machine-readable formats for
+// other tools to consume.
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// Output formats. Text is the human-readable report printed by the
+// commands themselves.
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatJSONL      = "jsonl"
+	FormatSARIF      = "sarif"
+	FormatCheckstyle = "checkstyle"
+	FormatJUnit      = "junit"
+	FormatRDJSON     = "rdjson"
+	FormatGHA        = "gha"
+)
+
+// File is the review of a single file.
+type File struct {
+	Path        string              `json:"file"`
+	Guides      []string            `json:"guides,omitempty"`
+	Model       string              `json:"model,omitempty"`
+	Suggestions []agents.Suggestion `json:"suggestions"`
+	Dropped     []agents.Suggestion `json:"dropped,omitempty"` // Scored below the verification threshold
+	Usage       Usage               `json:"usage"`
+	Cost        float64             `json:"cost"`             // USD, computed from the pricing table
+	Priced      bool                `json:"priced"`           // The model has a price, so Cost is meaningful
+	Cached      bool                `json:"cached,omitempty"` // Served from the response cache
+	ToolCalls   int                 `json:"tool_calls,omitempty"`
+	Truncated   bool                `json:"truncated,omitempty"`
+	Warnings    []string            `json:"warnings,omitempty"` // Invalid fields replaced by defaults
+	Skipped     bool                `json:"skipped,omitempty"`  // Skipped by the budget
+	DurationMS  int64               `json:"duration_ms"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// Usage counts the tokens of a review.
+type Usage struct {
+	Total  int `json:"total"`
+	Input  int `json:"input"`
+	Output int `json:"output"`
+	Cached int `json:"cached,omitempty"` // Input tokens read from the provider's prompt cache
+}
+
+// Summary totals a review run.
+type Summary struct {
+	Files          int          `json:"files"`
+	Failed         int          `json:"failed"`
+	Suggestions    int          `json:"suggestions"`
+	Usage          Usage        `json:"usage"`
+	Cost           float64      `json:"cost"`
+	UnpricedModels []string     `json:"unpriced_models,omitempty"` // Models excluded from Cost
+	CachedFiles    int          `json:"cached_files,omitempty"`
+	ToolCalls      int          `json:"tool_calls,omitempty"`
+	Dropped        int          `json:"dropped,omitempty"`
+	Adjustments    []Adjustment `json:"budget_adjustments,omitempty"`
+	DurationMS     int64        `json:"duration_ms"`
+}
+
+// Add adds a file to the totals.
+func (s *Summary) Add(file File) {
+	s.Files++
+	if file.Error != "" {
+		s.Failed++
+	}
+	s.Suggestions += len(file.Suggestions)
+	s.Usage.Total += file.Usage.Total
+	s.Usage.Input += file.Usage.Input
+	s.Usage.Output += file.Usage.Output
+	s.Usage.Cached += file.Usage.Cached
+	s.Cost += file.Cost
+	if !file.Priced && file.Usage.Total > 0 &&
+		!slices.Contains(s.UnpricedModels, file.Model) {
+		s.UnpricedModels = append(s.UnpricedModels, file.Model)
+	}
+	if file.Cached {
+		s.CachedFiles++
+	}
+	s.ToolCalls += file.ToolCalls
+	s.Dropped += len(file.Dropped)
+}
+
+// Adjustment is a file skipped or truncated to stay within the budget.
+type Adjustment struct {
+	File   string `json:"file"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// NewFile returns the report of the review of path. result is nil when the
+// review failed with err or the budget skipped the file.
+func NewFile(
+	path string, result *agents.ReviewResult, err error, duration time.Duration,
+) File {
+	file := File{
+		Path:        path,
+		Suggestions: []agents.Suggestion{},
+		DurationMS:  duration.Milliseconds(),
+	}
+	if err != nil {
+		file.Error = err.Error()
+	}
+	if result == nil {
+		file.Skipped = err == nil
+		return file
+	}
+
+	file.Model = result.Model
+	if result.Suggestions != nil {
+		file.Suggestions = result.Suggestions
+	}
+	file.Dropped = result.Dropped
+	file.Usage = Usage{
+		Total:  result.TokensUsed,
+		Input:  result.InputTokens,
+		Output: result.OutputTokens,
+		Cached: result.CachedTokens,
+	}
+	file.Cost = result.Cost
+	file.Priced = result.Priced
+	file.Cached = result.Cached
+	file.ToolCalls = result.ToolCalls
+	file.Truncated = result.Truncated
+	file.Warnings = result.Warnings
+	return file
+}
+
+// Emitter writes the files of a run as they are reviewed, in order, and the
+// summary once the run is over. Formats that are a single document buffer
+// the files until Close.
+type Emitter interface {
+	File(file File) error
+	Close(summary Summary) error
+}
+
+// Machine reports whether format is a machine-readable format, which owns
+// stdout; other output then goes to stderr.
+func Machine(format string) bool {
+	return format != "" && format != FormatText
+}
+
+// Options configures emitters.
+type Options struct {
+	Version     string    // Version of miso, for formats that name the tool
+	StepSummary io.Writer // GitHub Actions job summary the gha format appends to, if any
+}
+
+// New returns the emitter of format writing to w.
+func New(format string, w io.Writer, opts Options) (Emitter, error) {
+	switch format {
+	case FormatSARIF:
+		return newSARIFEmitter(w, opts.Version), nil
+	case FormatGHA:
+		return &ghaEmitter{w: w, stepSummary: opts.StepSummary}, nil
+	case FormatCheckstyle:
+		return &checkstyleEmitter{w: w}, nil
+	case FormatJUnit:
+		return &junitEmitter{w: w}, nil
+	case FormatRDJSON:
+		return newRDJSONEmitter(w), nil
+	case FormatJSON:
+		return &jsonEmitter{w: w}, nil
+	case FormatJSONL:
+		return &jsonlEmitter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unsupported output format: %s", format)
+}
+
+// rule is the guide, category or deterministic rule a finding is based on.
+type rule struct {
+	id          string
+	description string
+}
+
+// findingRule returns the rule of a finding: its guide, else the
+// deterministic rule that reported it, else its category.
+func findingRule(suggestion agents.Suggestion) rule {
+	switch {
+	case suggestion.Guide != "":
+		return rule{
+			id:          "guide/" + suggestion.Guide,
+			description: "Findings based on the " + suggestion.Guide + " guide",
+		}
+	case strings.HasPrefix(suggestion.ID, "rule-"):
+		name := ruleName(suggestion.ID)
+		return rule{
+			id:          "rule/" + name,
+			description: "Matches of the " + name + " rule",
+		}
+	case suggestion.Category != "":
+		return rule{
+			id:          "category/" + string(suggestion.Category),
+			description: fmt.Sprintf("%s findings", suggestion.Category),
+		}
+	}
+	return rule{id: "general", description: "General review findings"}
+}
+
+// ruleName returns the name of the deterministic rule of a finding from its
+// ID, rule-<name>-<line>.
+func ruleName(id string) string {
+	name := strings.TrimPrefix(id, "rule-")
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// level returns the level of a severity in a format, from its levels, or
+// the level of warnings when the severity is unknown.
+func level(levels map[agents.Severity]string, severity agents.Severity) string {
+	if level, ok := levels[severity]; ok {
+		return level
+	}
+	return levels[agents.SeverityWarning]
+}
+
+// message returns the text of a finding with its suggested change, if any.
+func message(suggestion agents.Suggestion) string {
+	if details := details(suggestion); details != "" {
+		return suggestion.Title + "\n\n" + details
+	}
+	return suggestion.Title
+}
+
+// details returns the body of a finding followed by its suggested change,
+// if any.
+func details(suggestion agents.Suggestion) string {
+	text := strings.ReplaceAll(suggestion.Body, "\\n", "\n")
+	if suggestion.Suggestion != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += "Suggested change:\n" +
+			strings.ReplaceAll(suggestion.Suggestion, "\\n", "\n")
+	}
+	return text
+}
@@ -0,0 +1,214 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+func TestNewFile(t *testing.T) {
+	result := &agents.ReviewResult{
+		Suggestions:  []agents.Suggestion{{ID: "miso-1A", Title: "Fix it"}},
+		Model:        "gpt-4o",
+		TokensUsed:   120,
+		InputTokens:  100,
+		OutputTokens: 20,
+		Cost:         0.01,
+		Priced:       true,
+	}
+
+	tests := []struct {
+		name   string
+		result *agents.ReviewResult
+		err    error
+		want   File
+	}{
+		{
+			name:   "reviewed",
+			result: result,
+			want: File{
+				Path:        "main.go",
+				Model:       "gpt-4o",
+				Suggestions: result.Suggestions,
+				Usage:       Usage{Total: 120, Input: 100, Output: 20},
+				Cost:        0.01,
+				Priced:      true,
+				DurationMS:  1500,
+			},
+		},
+		{
+			name: "failed",
+			err:  errors.New("timeout"),
+			want: File{
+				Path:        "main.go",
+				Suggestions: []agents.Suggestion{},
+				DurationMS:  1500,
+				Error:       "timeout",
+			},
+		},
+		{
+			name: "skipped",
+			want: File{
+				Path:        "main.go",
+				Suggestions: []agents.Suggestion{},
+				DurationMS:  1500,
+				Skipped:     true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := NewFile("main.go", tt.result, tt.err, 1500*time.Millisecond)
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				if string(gotJSON) != string(wantJSON) {
+					t.Errorf("NewFile() = %s, want %s", gotJSON, wantJSON)
+				}
+			},
+		)
+	}
+}
+
+func TestEmitters(t *testing.T) {
+	files := []File{
+		{
+			Path:        "a.go",
+			Model:       "gpt-4o",
+			Suggestions: []agents.Suggestion{{ID: "miso-1A"}, {ID: "miso-2B"}},
+			Usage:       Usage{Total: 30, Input: 20, Output: 10, Cached: 5},
+			Cost:        0.5,
+			Priced:      true,
+		},
+		{
+			Path:        "b.go",
+			Model:       "local",
+			Suggestions: []agents.Suggestion{},
+			Usage:       Usage{Total: 10, Input: 8, Output: 2},
+			Cached:      true,
+		},
+		{Path: "c.go", Suggestions: []agents.Suggestion{}, Error: "timeout"},
+	}
+
+	run := func(format string) string {
+		return string(emit(t, format, files...))
+	}
+
+	wantSummary := Summary{
+		Files:          3,
+		Failed:         1,
+		Suggestions:    2,
+		Usage:          Usage{Total: 40, Input: 28, Output: 12, Cached: 5},
+		Cost:           0.5,
+		UnpricedModels: []string{"local"},
+		CachedFiles:    1,
+	}
+
+	var document struct {
+		Files   []File  `json:"files"`
+		Summary Summary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(run(FormatJSON)), &document); err != nil {
+		t.Fatalf("Invalid JSON report: %v", err)
+	}
+	if len(document.Files) != 3 || document.Files[2].Error != "timeout" {
+		t.Errorf("Unexpected files: %+v", document.Files)
+	}
+	checkSummary(t, document.Summary, wantSummary)
+
+	lines := strings.Split(strings.TrimSpace(run(FormatJSONL)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 JSONL lines, got %d", len(lines))
+	}
+	for i, line := range lines[:len(files)] {
+		var entry struct {
+			Type string `json:"type"`
+			File
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSONL line %d: %v", i, err)
+		}
+		if entry.Type != "file" || entry.Path != files[i].Path {
+			t.Errorf("Line %d = %s, want file %s", i, line, files[i].Path)
+		}
+	}
+	var last struct {
+		Type string `json:"type"`
+		Summary
+	}
+	if err := json.Unmarshal([]byte(lines[len(files)]), &last); err != nil {
+		t.Fatalf("Invalid JSONL summary: %v", err)
+	}
+	if last.Type != "summary" {
+		t.Errorf("Expected the summary last, got %s", lines[len(files)])
+	}
+	checkSummary(t, last.Summary, wantSummary)
+
+	if _, err := New("yaml", &bytes.Buffer{}, Options{}); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+// checkSummary compares summaries through their JSON encoding.
+func checkSummary(t *testing.T, got, want Summary) {
+	t.Helper()
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Summary = %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+// emit writes files in format and returns the output.
+func emit(t *testing.T, format string, files ...File) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	emitter, err := New(format, &out, Options{Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("New(%q) error = %v", format, err)
+	}
+
+	var summary Summary
+	for _, file := range files {
+		if err := emitter.File(file); err != nil {
+			t.Fatalf("File() error = %v", err)
+		}
+		summary.Add(file)
+	}
+	if err := emitter.Close(summary); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return out.Bytes()
+}
+
+// sampleFiles returns the files of a run with findings, a clean file and a
+// failed one.
+func sampleFiles() []File {
+	return []File{
+		{
+			Path: "internal/db/query.go",
+			Suggestions: []agents.Suggestion{
+				{
+					Title:      "SQL injection",
+					Body:       "Use a parameterized query.",
+					Severity:   agents.SeverityCritical,
+					Guide:      "database.md",
+					StartLine:  12,
+					EndLine:    13,
+					Original:   "rows, err := db.Query(\"SELECT \" + id)",
+					Suggestion: "rows, err := db.Query(\"SELECT ?\", id)",
+				},
+				{Title: "Unclear name", Severity: agents.SeveritySuggestion},
+			},
+			DurationMS: 1250,
+		},
+		{Path: "main.go", Suggestions: []agents.Suggestion{}},
+		{Path: "api.go", Suggestions: []agents.Suggestion{}, Error: "timeout"},
+	}
+}
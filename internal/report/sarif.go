@@ -0,0 +1,270 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "miso"
+	toolURI      = "https://github.com/j0lvera/miso"
+
+	// fingerprintKey names the partial fingerprint of miso findings. Its
+	// version changes whenever the fingerprint is computed differently.
+	fingerprintKey = "misoFinding/v1"
+)
+
+// sarifLevels maps severities to SARIF levels.
+var sarifLevels = map[agents.Severity]string{
+	agents.SeverityCritical:   "error",
+	agents.SeverityWarning:    "warning",
+	agents.SeveritySuggestion: "note",
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	Properties       *sarifRuleProps `json:"properties,omitempty"`
+}
+
+type sarifRuleProps struct {
+	Tags []string `json:"tags"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful bool                `json:"executionSuccessful"`
+	Notifications       []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// sarifEmitter writes the run as a SARIF log with a rule for each guide,
+// category or deterministic rule the findings are based on.
+type sarifEmitter struct {
+	w        io.Writer
+	run      sarifRun
+	rules    map[string]int            // Index of each rule in the driver
+	seen     map[string]map[string]int // Occurrences of each fingerprint by file
+	failures []sarifNotification       // Files that failed to be reviewed
+}
+
+func newSARIFEmitter(w io.Writer, version string) *sarifEmitter {
+	return &sarifEmitter{
+		w: w,
+		run: sarifRun{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           toolName,
+					Version:        version,
+					InformationURI: toolURI,
+					Rules:          []sarifRule{},
+				},
+			},
+			Results: []sarifResult{},
+		},
+		rules: make(map[string]int),
+		seen:  make(map[string]map[string]int),
+	}
+}
+
+func (e *sarifEmitter) File(file File) error {
+	uri := filepath.ToSlash(file.Path)
+
+	if file.Error != "" {
+		e.failures = append(
+			e.failures, sarifNotification{
+				Level:     "error",
+				Message:   sarifMessage{Text: "Review failed: " + file.Error},
+				Locations: []sarifLocation{location(uri, 0, 0)},
+			},
+		)
+	}
+
+	for _, suggestion := range file.Suggestions {
+		ruleID := e.rule(suggestion)
+
+		result := sarifResult{
+			RuleID:    ruleID,
+			RuleIndex: e.rules[ruleID],
+			Level:     level(sarifLevels, suggestion.Severity),
+			Message:   sarifMessage{Text: message(suggestion)},
+			Locations: []sarifLocation{
+				location(uri, suggestion.StartLine, suggestion.EndLine),
+			},
+			PartialFingerprints: map[string]string{
+				fingerprintKey: e.fingerprint(uri, ruleID, suggestion),
+			},
+		}
+
+		properties := make(map[string]any)
+		if suggestion.Severity != "" {
+			properties["severity"] = suggestion.Severity
+		}
+		if suggestion.Category != "" {
+			properties["category"] = suggestion.Category
+		}
+		if suggestion.Confidence > 0 {
+			properties["confidence"] = suggestion.Confidence
+		}
+		if len(properties) > 0 {
+			result.Properties = properties
+		}
+
+		e.run.Results = append(e.run.Results, result)
+	}
+	return nil
+}
+
+func (e *sarifEmitter) Close(summary Summary) error {
+	e.run.Invocations = []sarifInvocation{
+		{
+			ExecutionSuccessful: summary.Failed == 0,
+			Notifications:       e.failures,
+		},
+	}
+
+	encoder := json.NewEncoder(e.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(
+		sarifLog{
+			Schema:  sarifSchema,
+			Version: sarifVersion,
+			Runs:    []sarifRun{e.run},
+		},
+	)
+}
+
+// rule returns the ID of the rule of a suggestion, adding the rule to the
+// driver the first time it is seen.
+func (e *sarifEmitter) rule(suggestion agents.Suggestion) string {
+	rule := findingRule(suggestion)
+	if _, ok := e.rules[rule.id]; !ok {
+		sarif := sarifRule{
+			ID:               rule.id,
+			ShortDescription: sarifMessage{Text: rule.description},
+		}
+		if suggestion.Category != "" {
+			sarif.Properties = &sarifRuleProps{
+				Tags: []string{string(suggestion.Category)},
+			}
+		}
+		e.rules[rule.id] = len(e.run.Tool.Driver.Rules)
+		e.run.Tool.Driver.Rules = append(e.run.Tool.Driver.Rules, sarif)
+	}
+	return rule.id
+}
+
+// fingerprint identifies a finding across runs by its file, rule and the
+// code it is about, or its title when it quotes no code. Line numbers are
+// left out so findings keep their identity when code moves; repeated
+// findings in a file are told apart by their order.
+func (e *sarifEmitter) fingerprint(
+	uri, ruleID string, suggestion agents.Suggestion,
+) string {
+	subject := suggestion.Original
+	if strings.TrimSpace(subject) == "" {
+		subject = suggestion.Title
+	}
+	key := strings.Join(
+		[]string{uri, ruleID, strings.Join(strings.Fields(subject), " ")}, "\x00",
+	)
+
+	if e.seen[uri] == nil {
+		e.seen[uri] = make(map[string]int)
+	}
+	occurrence := e.seen[uri][key]
+	e.seen[uri][key]++
+
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(sum[:16]), occurrence+1)
+}
+
+// location returns the location of lines start to end of a file, or of the
+// whole file when start is zero.
+func location(uri string, start, end int) sarifLocation {
+	loc := sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{
+				URI:       uri,
+				URIBaseID: "%SRCROOT%",
+			},
+		},
+	}
+	if start > 0 {
+		region := &sarifRegion{StartLine: start}
+		if end > start {
+			region.EndLine = end
+		}
+		loc.PhysicalLocation.Region = region
+	}
+	return loc
+}
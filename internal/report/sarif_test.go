@@ -0,0 +1,126 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// emitSARIF writes files as a SARIF log and decodes it.
+func emitSARIF(t *testing.T, files ...File) sarifLog {
+	t.Helper()
+	var log sarifLog
+	if err := json.Unmarshal(emit(t, FormatSARIF, files...), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+	return log
+}
+
+func TestSARIF(t *testing.T) {
+	log := emitSARIF(
+		t,
+		File{
+			Path: "internal/db/query.go",
+			Suggestions: []agents.Suggestion{
+				{
+					Title:     "SQL injection",
+					Severity:  agents.SeverityCritical,
+					Category:  agents.CategorySecurity,
+					Guide:     "database.md",
+					StartLine: 12,
+					EndLine:   14,
+					Original:  "db.Query(\"SELECT \" + id)",
+				},
+				{
+					Title:     "Slow loop",
+					Severity:  agents.SeverityWarning,
+					Category:  agents.CategoryPerformance,
+					StartLine: 30,
+				},
+				{ID: "rule-no-todo-3", Title: "TODO", Severity: agents.SeveritySuggestion},
+				{Title: "Unclear name"},
+			},
+		},
+		File{Path: "main.go", Error: "timeout"},
+	)
+
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected log: %+v", log)
+	}
+	run := log.Runs[0]
+
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	wantRules := []string{
+		"guide/database.md", "category/performance", "rule/no-todo", "general",
+	}
+	if len(ruleIDs) != len(wantRules) {
+		t.Fatalf("Rules = %v, want %v", ruleIDs, wantRules)
+	}
+	for i, want := range wantRules {
+		if ruleIDs[i] != want {
+			t.Errorf("Rules = %v, want %v", ruleIDs, wantRules)
+			break
+		}
+	}
+
+	wantLevels := []string{"error", "warning", "note", "warning"}
+	for i, result := range run.Results {
+		if result.Level != wantLevels[i] {
+			t.Errorf("Result %d level = %s, want %s", i, result.Level, wantLevels[i])
+		}
+		if result.RuleID != wantRules[i] || result.RuleIndex != i {
+			t.Errorf("Result %d rule = %s (%d)", i, result.RuleID, result.RuleIndex)
+		}
+	}
+
+	location := run.Results[0].Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "internal/db/query.go" ||
+		location.Region == nil || location.Region.StartLine != 12 ||
+		location.Region.EndLine != 14 {
+		t.Errorf("Unexpected location: %+v", location)
+	}
+	if run.Results[3].Locations[0].PhysicalLocation.Region != nil {
+		t.Error("Expected a file location for a finding without lines")
+	}
+
+	invocation := run.Invocations[0]
+	if invocation.ExecutionSuccessful || len(invocation.Notifications) != 1 {
+		t.Errorf("Expected the failed file in the invocation, got %+v", invocation)
+	}
+}
+
+func TestSARIF_Fingerprints(t *testing.T) {
+	finding := agents.Suggestion{
+		Title:     "SQL injection",
+		Guide:     "database.md",
+		StartLine: 12,
+		Original:  "db.Query(\"SELECT \" + id)",
+	}
+	fingerprint := func(suggestions ...agents.Suggestion) []string {
+		log := emitSARIF(t, File{Path: "query.go", Suggestions: suggestions})
+		var fingerprints []string
+		for _, result := range log.Runs[0].Results {
+			fingerprints = append(fingerprints, result.PartialFingerprints[fingerprintKey])
+		}
+		return fingerprints
+	}
+
+	first := fingerprint(finding)
+
+	// The same finding moved by an edit above it
+	moved := finding
+	moved.StartLine = 20
+	moved.Original = "db.Query(\"SELECT \"   + id)"
+	if again := fingerprint(moved); again[0] != first[0] {
+		t.Errorf("Fingerprint changed across runs: %s, %s", first[0], again[0])
+	}
+
+	twice := fingerprint(finding, finding)
+	if twice[0] != first[0] || twice[1] == twice[0] {
+		t.Errorf("Expected distinct fingerprints for repeated findings, got %v", twice)
+	}
+}
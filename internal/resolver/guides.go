@@ -0,0 +1,122 @@
+package resolver
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/budget"
+)
+
+// summaryNote follows the headings of a guide cut to fit the guide budget.
+const summaryNote = "(Only the headings of this guide fit the guide budget.)"
+
+// Guide is a review guide loaded from disk.
+type Guide struct {
+	Name       string
+	Content    string
+	Priority   int  // From guides.priority, higher kept first
+	Summarized bool // Content was cut to the headings of the guide
+}
+
+// Format renders the guide as it appears in prompts, under a header with
+// its name.
+func (g Guide) Format() string {
+	return fmt.Sprintf("\n=== %s ===\n%s\n", g.Name, g.Content)
+}
+
+// GuideSet is the guide context of a prompt after applying the guide budget.
+type GuideSet struct {
+	Guides  []Guide // Guides in the prompt, in match order
+	Dropped []Guide // Guides left out, in match order
+}
+
+// Summarized returns the names of the guides cut to their headings.
+func (s GuideSet) Summarized() []string {
+	var names []string
+	for _, g := range s.Guides {
+		if g.Summarized {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
+
+// AssembleGuides loads the guides and fits them to guides.max_tokens.
+// Guides are kept in order of priority, then match order: each is included
+// whole if it fits, else as an outline of its headings, else dropped. The
+// header of each guide counts toward the budget.
+func (r *Resolver) AssembleGuides(names []string) (GuideSet, error) {
+	guides, err := r.LoadGuideContent(names)
+	if err != nil {
+		return GuideSet{}, err
+	}
+
+	maxTokens := r.config.Guides.MaxTokens
+	if maxTokens <= 0 {
+		return GuideSet{Guides: guides}, nil
+	}
+
+	byPriority := make([]int, len(guides))
+	for i := range byPriority {
+		byPriority[i] = i
+	}
+	slices.SortStableFunc(
+		byPriority, func(a, b int) int {
+			return cmp.Compare(guides[b].Priority, guides[a].Priority)
+		},
+	)
+
+	kept := make([]bool, len(guides))
+	remaining := maxTokens
+	for _, i := range byPriority {
+		if tokens := budget.CountTokens(guides[i].Format()); tokens <= remaining {
+			kept[i] = true
+			remaining -= tokens
+			continue
+		}
+
+		summary := guides[i]
+		summary.Content = outline(guides[i].Content)
+		if summary.Content == "" {
+			continue
+		}
+		summary.Summarized = true
+		if tokens := budget.CountTokens(summary.Format()); tokens <= remaining {
+			guides[i] = summary
+			kept[i] = true
+			remaining -= tokens
+		}
+	}
+
+	var set GuideSet
+	for i, g := range guides {
+		if kept[i] {
+			set.Guides = append(set.Guides, g)
+		} else {
+			set.Dropped = append(set.Dropped, g)
+		}
+	}
+	return set, nil
+}
+
+// outline returns the Markdown headings of a guide followed by a note, or
+// an empty string when it has none. Comments in code blocks aren't headings.
+func outline(content string) string {
+	var headings []string
+	inCode := false
+	for line := range strings.Lines(content) {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode && strings.HasPrefix(line, "#") {
+			headings = append(headings, strings.TrimRight(line, "\r\n"))
+		}
+	}
+	if len(headings) == 0 {
+		return ""
+	}
+	return strings.Join(headings, "\n") + "\n\n" + summaryNote
+}
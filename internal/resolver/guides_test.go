@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/budget"
+	"github.com/j0lvera/miso/internal/config"
+)
+
+func TestAssembleGuides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write guide: %v", err)
+		}
+		return path
+	}
+
+	shortContent := "# Short\nKeep functions small.\n"
+	short := write("short.md", shortContent)
+	long := write(
+		"long.md",
+		"# Long\n```sh\n# not a heading\n```\n"+strings.Repeat("word ", 2000)+
+			"\n## Errors\n",
+	)
+	medium := write("medium.md", "# Medium\n"+strings.Repeat("word ", 200))
+	plain := write("plain.md", strings.Repeat("word ", 2000))
+	missing := filepath.Join(dir, "missing.md")
+	names := []string{short, long, medium, missing, plain}
+
+	priority := map[string]int{long: -1, medium: 5, plain: -2}
+
+	tests := []struct {
+		name       string
+		maxTokens  int
+		want       []string
+		summarized []string
+		dropped    []string
+	}{
+		{
+			name:      "no budget",
+			maxTokens: 0,
+			want:      []string{short, long, medium, plain},
+		},
+		{
+			name:       "over budget",
+			maxTokens:  500,
+			want:       []string{short, long, medium},
+			summarized: []string{long},
+			dropped:    []string{plain},
+		},
+		{
+			name:      "headers count toward the budget",
+			maxTokens: budget.CountTokens(shortContent),
+			dropped:   []string{short, long, medium, plain},
+		},
+		{
+			name:      "nothing fits",
+			maxTokens: 1,
+			dropped:   []string{short, long, medium, plain},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				res := NewResolver(
+					&config.Config{
+						Guides: config.Guides{
+							MaxTokens: tt.maxTokens,
+							Priority:  priority,
+						},
+					},
+				)
+
+				set, err := res.AssembleGuides(names)
+				if err != nil {
+					t.Fatalf("AssembleGuides() error = %v", err)
+				}
+
+				if got := guideNames(set.Guides); !slices.Equal(got, tt.want) {
+					t.Errorf("Guides = %v, want %v", got, tt.want)
+				}
+				if got := set.Summarized(); !slices.Equal(got, tt.summarized) {
+					t.Errorf("Summarized = %v, want %v", got, tt.summarized)
+				}
+				if got := guideNames(set.Dropped); !slices.Equal(got, tt.dropped) {
+					t.Errorf("Dropped = %v, want %v", got, tt.dropped)
+				}
+			},
+		)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	content := "# Title\nIntro.\n```go\n# comment\n```\n## Section\r\nBody.\n"
+	want := "# Title\n## Section\n\n" + summaryNote
+	if got := outline(content); got != want {
+		t.Errorf("outline() = %q, want %q", got, want)
+	}
+
+	if got := outline("No headings here."); got != "" {
+		t.Errorf("outline() = %q, want empty", got)
+	}
+}
+
+// guideNames returns the names of guides.
+func guideNames(guides []Guide) []string {
+	var names []string
+	for _, g := range guides {
+		names = append(names, g.Name)
+	}
+	return names
+}
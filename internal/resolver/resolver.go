@@ -24,9 +24,70 @@ func NewResolver(cfg *config.Config) *Resolver {
 	}
 }
 
+// ModelSelection is the model override chosen for a file by its matched patterns.
+// Empty fields mean the configured llm defaults apply.
+type ModelSelection struct {
+	Model       string   // Model identifier, empty for the llm default
+	Temperature *float64 // Temperature override, nil for the llm default
+	Pattern     string   // Name of the pattern that set the model
+
+	Ensemble        []string // Ensemble models, nil to review with a single model
+	EnsemblePattern string   // Name of the pattern that set the ensemble, empty for the configured one
+	MinAgreement    int      // Ensemble agreement override, zero for the configured one
+}
+
 // GetGuides returns the appropriate review guide files for a given filename.
 // Performs pattern matching and returns the context guides for matched patterns.
 func (r *Resolver) GetGuides(filename string) ([]string, error) {
+	patterns, err := r.matchPatterns(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.matcher.GetMatchedGuides(patterns, false), nil
+}
+
+// GetModel returns the model override for a given filename.
+// Matched patterns are considered in config order and the first pattern that
+// sets a model wins; temperature and the ensemble settings are resolved the
+// same way, independently. The configured ensemble applies to files without
+// a pattern model or ensemble.
+func (r *Resolver) GetModel(filename string) (ModelSelection, error) {
+	var selection ModelSelection
+
+	patterns, err := r.matchPatterns(filename)
+	if err != nil {
+		return selection, err
+	}
+
+	for _, p := range patterns {
+		if selection.Model == "" && p.Model != "" {
+			selection.Model = p.Model
+			selection.Pattern = p.Name
+		}
+		if selection.Temperature == nil && p.Temperature != nil {
+			selection.Temperature = p.Temperature
+		}
+		if selection.Ensemble == nil && len(p.Ensemble) > 0 {
+			selection.Ensemble = p.Ensemble
+			selection.EnsemblePattern = p.Name
+		}
+		if selection.MinAgreement == 0 && p.MinAgreement > 0 {
+			selection.MinAgreement = p.MinAgreement
+		}
+	}
+
+	// A pattern's model opts its files out of the configured ensemble
+	if selection.Ensemble == nil && selection.Model == "" {
+		selection.Ensemble = r.config.Ensemble.Models
+	}
+
+	return selection, nil
+}
+
+// matchPatterns returns the patterns that match a file, scanning its content
+// only when a matched or content-only pattern requires it.
+func (r *Resolver) matchPatterns(filename string) ([]config.Pattern, error) {
 	// First try to match by filename only
 	filenameMatches, err := r.matcher.MatchFile(filename)
 	if err != nil {
@@ -35,7 +96,7 @@ func (r *Resolver) GetGuides(filename string) ([]string, error) {
 
 	// If we have filename matches and don't need content scanning, return early
 	if len(filenameMatches) > 0 && !r.needsContentScan(filenameMatches) {
-		return r.matcher.GetMatchedGuides(filenameMatches, false), nil
+		return filenameMatches, nil
 	}
 
 	// If we need content scanning or have patterns with only content matching
@@ -45,21 +106,16 @@ func (r *Resolver) GetGuides(filename string) ([]string, error) {
 		if err != nil {
 			// If we can't read the file but have filename matches, use those
 			if len(filenameMatches) > 0 {
-				return r.matcher.GetMatchedGuides(filenameMatches, false), nil
+				return filenameMatches, nil
 			}
 			return nil, err
 		}
 
-		contentMatches, err := r.matcher.MatchFileContent(filename, content)
-		if err != nil {
-			return nil, err
-		}
-
-		return r.matcher.GetMatchedGuides(contentMatches, false), nil
+		return r.matcher.MatchFileContent(filename, content)
 	}
 
-	// Return guides from filename matches
-	return r.matcher.GetMatchedGuides(filenameMatches, false), nil
+	// Return filename matches
+	return filenameMatches, nil
 }
 
 // GetDiffGuides returns the diff-specific guide files for a given filename.
@@ -104,12 +160,10 @@ func (r *Resolver) hasContentPatterns() bool {
 	return false
 }
 
-// LoadGuideContent loads the content of guide files from disk.
-// Tries multiple paths for each guide and returns a map of guide name to content.
-func (r *Resolver) LoadGuideContent(guides []string) (
-	map[string]string, error,
-) {
-	guideContent := make(map[string]string)
+// LoadGuideContent loads the content of guide files from disk, in the
+// order given. Tries multiple paths for each guide and skips missing ones.
+func (r *Resolver) LoadGuideContent(guides []string) ([]Guide, error) {
+	var loaded []Guide
 
 	for _, guide := range guides {
 		// Try multiple paths for guides
@@ -136,8 +190,14 @@ func (r *Resolver) LoadGuideContent(guides []string) (
 			continue
 		}
 
-		guideContent[guide] = string(content)
+		loaded = append(
+			loaded, Guide{
+				Name:     guide,
+				Content:  string(content),
+				Priority: r.config.Guides.Priority[guide],
+			},
+		)
 	}
 
-	return guideContent, nil
+	return loaded, nil
 }
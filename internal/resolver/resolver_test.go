@@ -3,6 +3,7 @@ package resolver
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/j0lvera/miso/internal/config"
@@ -134,3 +135,148 @@ func main() {
 		t.Errorf("Expected database.md, got %s", guides[0])
 	}
 }
+
+func TestResolverGetModel(t *testing.T) {
+	low := 0.1
+	high := 0.7
+
+	cfg := &config.Config{
+		ContentDefaults: config.ContentDefaults{
+			Strategy: "first_lines",
+			Lines:    50,
+		},
+		Ensemble: config.Ensemble{Models: []string{"model-c", "model-d"}},
+		Patterns: []config.Pattern{
+			{
+				Name:        "test-files",
+				Filename:    `_test\.go$`,
+				Context:     []string{"testing.md"},
+				Model:       "cheap-model",
+				Temperature: &low,
+				Stop:        true,
+			},
+			{
+				Name:         "handlers",
+				Filename:     `handlers/`,
+				Context:      []string{"handlers.md"},
+				Model:        "strong-model",
+				Ensemble:     []string{"model-a", "model-b"},
+				MinAgreement: 2,
+			},
+			{
+				Name:        "go-files",
+				Filename:    `\.go$`,
+				Context:     []string{"go.md"},
+				Model:       "default-go-model",
+				Temperature: &high,
+			},
+			{
+				Name:     "docs",
+				Filename: `\.md$`,
+				Context:  []string{"docs.md"},
+			},
+		},
+	}
+
+	resolver := NewResolver(cfg)
+
+	tests := []struct {
+		name            string
+		filename        string
+		wantModel       string
+		wantPattern     string
+		wantTemperature *float64
+		wantEnsemble    string
+		wantAgreement   int
+	}{
+		{
+			name:            "stop pattern sets model and temperature",
+			filename:        "main_test.go",
+			wantModel:       "cheap-model",
+			wantPattern:     "test-files",
+			wantTemperature: &low,
+		},
+		{
+			name:            "first matching pattern with a model wins",
+			filename:        "handlers/user.go",
+			wantModel:       "strong-model",
+			wantPattern:     "handlers",
+			wantTemperature: &high,
+			wantEnsemble:    "model-a,model-b",
+			wantAgreement:   2,
+		},
+		{
+			name:            "pattern model skips the configured ensemble",
+			filename:        "main.go",
+			wantModel:       "default-go-model",
+			wantPattern:     "go-files",
+			wantTemperature: &high,
+		},
+		{
+			name:         "match without model uses the configured ensemble",
+			filename:     "README.md",
+			wantEnsemble: "model-c,model-d",
+		},
+		{
+			name:         "no match",
+			filename:     "image.png",
+			wantEnsemble: "model-c,model-d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				selection, err := resolver.GetModel(tt.filename)
+				if err != nil {
+					t.Fatalf("GetModel() error = %v", err)
+				}
+
+				if selection.Model != tt.wantModel {
+					t.Errorf(
+						"GetModel() model = %q, want %q", selection.Model,
+						tt.wantModel,
+					)
+				}
+
+				if selection.Pattern != tt.wantPattern {
+					t.Errorf(
+						"GetModel() pattern = %q, want %q", selection.Pattern,
+						tt.wantPattern,
+					)
+				}
+
+				if ensemble := strings.Join(selection.Ensemble, ","); ensemble != tt.wantEnsemble {
+					t.Errorf(
+						"GetModel() ensemble = %q, want %q", ensemble,
+						tt.wantEnsemble,
+					)
+				}
+				if selection.MinAgreement != tt.wantAgreement {
+					t.Errorf(
+						"GetModel() min agreement = %d, want %d",
+						selection.MinAgreement, tt.wantAgreement,
+					)
+				}
+
+				switch {
+				case tt.wantTemperature == nil && selection.Temperature != nil:
+					t.Errorf(
+						"GetModel() temperature = %g, want nil",
+						*selection.Temperature,
+					)
+				case tt.wantTemperature != nil && selection.Temperature == nil:
+					t.Errorf(
+						"GetModel() temperature = nil, want %g",
+						*tt.wantTemperature,
+					)
+				case tt.wantTemperature != nil && *selection.Temperature != *tt.wantTemperature:
+					t.Errorf(
+						"GetModel() temperature = %g, want %g",
+						*selection.Temperature, *tt.wantTemperature,
+					)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,145 @@
+// Package rules runs the deterministic checks of the config, reporting
+// matching lines as suggestions without calling the LLM.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/agents"
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/git"
+)
+
+// severityLabels precede the title of a finding, like the labels the LLM
+// is asked to use.
+var severityLabels = map[string]string{
+	config.SeverityCritical:   "🔴 Critical",
+	config.SeverityWarning:    "🟡 Warning",
+	config.SeveritySuggestion: "💡 Suggestion",
+}
+
+// rule is a config rule with its regexes compiled.
+type rule struct {
+	config.Rule
+	filename *regexp.Regexp
+	pattern  *regexp.Regexp // Nil for literal rules
+}
+
+// matches reports whether line violates the rule.
+func (r *rule) matches(line string) bool {
+	if r.pattern != nil {
+		return r.pattern.MatchString(line)
+	}
+	return strings.Contains(line, r.Literal)
+}
+
+// finding returns the suggestion reporting a match on a line of the file.
+func (r *rule) finding(filename string, line int) agents.Suggestion {
+	title := r.Title
+	if title == "" {
+		title = r.Name
+	}
+	severity := r.Severity
+	if severity == "" {
+		severity = config.SeverityWarning
+	}
+
+	// The matched code isn't quoted, since reports expand any "\n" in it
+	body := fmt.Sprintf("Found by rule %s.", r.Name)
+	if r.Message != "" {
+		body = r.Message + "\n\n" + body
+	}
+
+	return agents.Suggestion{
+		ID:        fmt.Sprintf("rule-%s-%d", r.Name, line),
+		Title:     fmt.Sprintf("%s: %s", severityLabels[severity], title),
+		Body:      body,
+		Severity:  agents.Severity(severity),
+		File:      filename,
+		StartLine: line,
+		EndLine:   line,
+	}
+}
+
+// Checker runs the rules of a config. It is safe for concurrent use.
+type Checker struct {
+	rules []*rule
+}
+
+// New compiles the rules of a config.
+func New(rules []config.Rule) (*Checker, error) {
+	c := &Checker{}
+	for _, cfgRule := range rules {
+		r := &rule{Rule: cfgRule}
+
+		var err error
+		if r.filename, err = regexp.Compile(cfgRule.Filename); err != nil {
+			return nil, fmt.Errorf(
+				"invalid filename regex for rule %s: %w", cfgRule.Name, err,
+			)
+		}
+		if cfgRule.Pattern != "" {
+			if r.pattern, err = regexp.Compile(cfgRule.Pattern); err != nil {
+				return nil, fmt.Errorf(
+					"invalid pattern regex for rule %s: %w", cfgRule.Name, err,
+				)
+			}
+		}
+
+		c.rules = append(c.rules, r)
+	}
+	return c, nil
+}
+
+// Applies reports whether any rule checks the file.
+func (c *Checker) Applies(filename string) bool {
+	return len(c.rulesFor(filename)) > 0
+}
+
+// CheckFile checks every line of a file.
+func (c *Checker) CheckFile(filename, content string) []agents.Suggestion {
+	var suggestions []agents.Suggestion
+	for _, r := range c.rulesFor(filename) {
+		for i, line := range strings.Split(content, "\n") {
+			if r.matches(line) {
+				suggestions = append(suggestions, r.finding(filename, i+1))
+			}
+		}
+	}
+	return suggestions
+}
+
+// CheckDiff checks the lines of a diff as they are after the change: the
+// added lines, and the context lines for rules not limited to added ones.
+func (c *Checker) CheckDiff(filename string, diff *git.DiffData) []agents.Suggestion {
+	var suggestions []agents.Suggestion
+	for _, r := range c.rulesFor(filename) {
+		for _, hunk := range diff.Hunks {
+			for _, line := range hunk.Lines {
+				switch {
+				case line.Type == git.DiffLineAdded:
+				case line.Type == git.DiffLineContext && !r.AddedOnly:
+				default:
+					continue
+				}
+				if r.matches(line.Content) {
+					suggestions = append(suggestions, r.finding(filename, line.NewNum))
+				}
+			}
+		}
+	}
+	return suggestions
+}
+
+// rulesFor returns the rules that check the file, in config order.
+func (c *Checker) rulesFor(filename string) []*rule {
+	var matched []*rule
+	for _, r := range c.rules {
+		if r.filename.MatchString(filename) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/agents"
+	"github.com/j0lvera/miso/internal/config"
+	"github.com/j0lvera/miso/internal/git"
+)
+
+func newChecker(t *testing.T) *Checker {
+	t.Helper()
+
+	checker, err := New(
+		[]config.Rule{
+			{
+				Name:      "no-println",
+				Filename:  `\.go$`,
+				Pattern:   `fmt\.Println\(`,
+				AddedOnly: true,
+				Title:     "Debug print",
+				Message:   "Use the logger instead.",
+			},
+			{
+				Name:     "no-todo",
+				Literal:  "TODO",
+				Severity: config.SeveritySuggestion,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return checker
+}
+
+func TestChecker_CheckFile(t *testing.T) {
+	checker := newChecker(t)
+	code := "package main\n\n// TODO: flags\nfunc main() {\n\tfmt.Println(\"hi\")\n}"
+
+	tests := []struct {
+		name       string
+		filename   string
+		wantIDs    []string
+		wantTitles []string
+	}{
+		{
+			name:     "every rule",
+			filename: "cmd/main.go",
+			wantIDs:  []string{"rule-no-println-5", "rule-no-todo-3"},
+			wantTitles: []string{
+				"🟡 Warning: Debug print",
+				"💡 Suggestion: no-todo",
+			},
+		},
+		{
+			name:       "rules scoped by filename",
+			filename:   "README.md",
+			wantIDs:    []string{"rule-no-todo-3"},
+			wantTitles: []string{"💡 Suggestion: no-todo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				suggestions := checker.CheckFile(tt.filename, code)
+
+				var ids, titles []string
+				for _, suggestion := range suggestions {
+					ids = append(ids, suggestion.ID)
+					titles = append(titles, suggestion.Title)
+				}
+				if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+					t.Errorf("CheckFile() IDs = %v, want %v", ids, tt.wantIDs)
+				}
+				if strings.Join(titles, "|") != strings.Join(tt.wantTitles, "|") {
+					t.Errorf("CheckFile() titles = %q, want %q", titles, tt.wantTitles)
+				}
+			},
+		)
+	}
+
+	finding := checker.CheckFile("main.go", code)[0]
+	if !strings.HasPrefix(finding.Body, "Use the logger instead.") ||
+		!strings.Contains(finding.Body, "no-println") {
+		t.Errorf("Expected the message and rule in the body, got %q", finding.Body)
+	}
+	if finding.File != "main.go" || finding.StartLine != 5 || finding.EndLine != 5 ||
+		finding.Severity != agents.SeverityWarning {
+		t.Errorf("Expected a warning on main.go:5, got %+v", finding)
+	}
+}
+
+func TestChecker_CheckDiff(t *testing.T) {
+	checker := newChecker(t)
+	diff := &git.DiffData{
+		Hunks: []git.DiffHunk{
+			{
+				Lines: []git.DiffLine{
+					{Type: git.DiffLineContext, Content: "\tfmt.Println(\"old\") // TODO", NewNum: 10},
+					{Type: git.DiffLineRemoved, Content: "\tfmt.Println(\"gone\") // TODO", OldNum: 11},
+					{Type: git.DiffLineAdded, Content: "\tfmt.Println(\"new\")", NewNum: 11},
+				},
+			},
+		},
+	}
+
+	var ids []string
+	for _, suggestion := range checker.CheckDiff("main.go", diff) {
+		ids = append(ids, suggestion.ID)
+	}
+
+	// Removed lines are never checked, and context lines only by rules
+	// that aren't limited to added lines
+	want := "rule-no-println-11,rule-no-todo-10"
+	if strings.Join(ids, ",") != want {
+		t.Errorf("CheckDiff() IDs = %v, want %s", ids, want)
+	}
+}
+
+func TestChecker_Applies(t *testing.T) {
+	checker, err := New(
+		[]config.Rule{{Name: "sql", Filename: `\.sql$`, Literal: "DROP"}},
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if !checker.Applies("db/schema.sql") {
+		t.Error("Expected the rule to apply to SQL files")
+	}
+	if checker.Applies("main.go") {
+		t.Error("Expected the rule not to apply to Go files")
+	}
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// readFileTool reads a file of the repository, optionally a range of lines.
+var readFileTool = Tool{
+	Name: "read_file",
+	Description: fmt.Sprintf(
+		"Read a file of the repository at the reviewed revision. "+
+			"Returns numbered lines, at most %d per call.", maxReadLines,
+	),
+	Parameters: object(
+		[]string{"path"}, map[string]any{
+			"path":       property("string", "Path relative to the repository root."),
+			"start_line": property("integer", "First line to read, starting at 1."),
+			"end_line":   property("integer", "Last line to read."),
+		},
+	),
+	call: readFile,
+}
+
+// listDirTool lists the entries of a directory.
+var listDirTool = Tool{
+	Name:        "list_dir",
+	Description: "List the files and directories in a directory of the repository. Directories end with a slash.",
+	Parameters: object(
+		[]string{"path"}, map[string]any{
+			"path": property("string", `Directory relative to the repository root; "." for the root.`),
+		},
+	),
+	call: listDir,
+}
+
+func readFile(_ context.Context, fsys fs.FS, raw json.RawMessage) (string, error) {
+	var args struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	name, err := cleanPath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	start := max(args.StartLine, 1)
+	end := len(lines)
+	if args.EndLine > 0 {
+		end = min(args.EndLine, end)
+	}
+	if start > end {
+		return "", fmt.Errorf("%s has %d lines", name, len(lines))
+	}
+
+	var b strings.Builder
+	for i := start; i <= end; i++ {
+		if i-start == maxReadLines {
+			fmt.Fprintf(
+				&b, "[stopped after %d lines; read from line %d to continue]\n",
+				maxReadLines, i,
+			)
+			break
+		}
+		fmt.Fprintf(&b, "%d: %s\n", i, lines[i-1])
+	}
+	return b.String(), nil
+}
+
+func listDir(_ context.Context, fsys fs.FS, raw json.RawMessage) (string, error) {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	name, err := cleanPath(args.Path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() {
+			fmt.Fprintf(&b, "%s/\n", entry.Name())
+		} else {
+			fmt.Fprintf(&b, "%s\n", entry.Name())
+		}
+	}
+	if b.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// errSearchDone stops a walk once enough results were found.
+var errSearchDone = errors.New("search done")
+
+// grepTool searches the repository for a regular expression.
+var grepTool = Tool{
+	Name: "grep",
+	Description: fmt.Sprintf(
+		"Search the files of the repository for a regular expression (RE2 syntax). "+
+			"Returns up to %d matches as path:line: text. "+
+			"Use it to find the callers of a changed function.", maxGrepMatches,
+	),
+	Parameters: object(
+		[]string{"pattern"}, map[string]any{
+			"pattern": property("string", "Regular expression to search for."),
+			"path":    property("string", "Directory or file to search in; the whole repository by default."),
+		},
+	),
+	call: grep,
+}
+
+// findSymbolTool looks up the definition of a Go symbol.
+var findSymbolTool = Tool{
+	Name: "find_symbol",
+	Description: "Find the definition of a Go function, method, type, constant or variable. " +
+		`Use "Name" for package-level symbols or "Type.Method" for methods. ` +
+		"Returns the location and source of each definition.",
+	Parameters: object(
+		[]string{"name"}, map[string]any{
+			"name": property("string", `Symbol name, e.g. "NewParser" or "Parser.Load".`),
+		},
+	),
+	call: findSymbol,
+}
+
+func grep(ctx context.Context, fsys fs.FS, raw json.RawMessage) (string, error) {
+	var args struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	re, err := regexp.Compile(args.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	root, err := cleanPath(args.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	matches := 0
+	err = walkFiles(
+		ctx, fsys, root, func(name string, data []byte) error {
+			for i, line := range strings.Split(string(data), "\n") {
+				if !re.MatchString(line) {
+					continue
+				}
+				if matches == maxGrepMatches {
+					b.WriteString("[more matches omitted; narrow the pattern or path]\n")
+					return errSearchDone
+				}
+				matches++
+
+				line = strings.TrimSpace(line)
+				if len(line) > maxLineLength {
+					line = line[:maxLineLength] + "..."
+				}
+				fmt.Fprintf(&b, "%s:%d: %s\n", name, i+1, line)
+			}
+			return nil
+		},
+	)
+	if err != nil && !errors.Is(err, errSearchDone) {
+		return "", err
+	}
+
+	if matches == 0 {
+		return "no matches", nil
+	}
+	return b.String(), nil
+}
+
+func findSymbol(ctx context.Context, fsys fs.FS, raw json.RawMessage) (string, error) {
+	var args struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	recv, name, isMethod := strings.Cut(args.Name, ".")
+	if !isMethod {
+		recv, name = "", recv
+	}
+	if !token.IsIdentifier(name) || (isMethod && !token.IsIdentifier(recv)) {
+		return "", fmt.Errorf("invalid symbol %q", args.Name)
+	}
+
+	var b strings.Builder
+	found := 0
+	err := walkFiles(
+		ctx, fsys, ".", func(file string, data []byte) error {
+			if path.Ext(file) != ".go" || !bytes.Contains(data, []byte(name)) {
+				return nil
+			}
+
+			fset := token.NewFileSet()
+			parsed, err := parser.ParseFile(fset, file, data, parser.ParseComments)
+			if err != nil {
+				return nil // Skip files that don't parse
+			}
+
+			for _, decl := range parsed.Decls {
+				node := matchDecl(decl, recv, name)
+				if node == nil {
+					continue
+				}
+				if found == maxSymbols {
+					b.WriteString("[more definitions omitted]\n")
+					return errSearchDone
+				}
+				found++
+
+				start := fset.Position(node.Pos())
+				end := fset.Position(node.End())
+				lines := strings.Split(string(data), "\n")
+				fmt.Fprintf(&b, "%s:%d\n", file, start.Line)
+				for i := start.Line; i <= end.Line && i-start.Line < maxReadLines; i++ {
+					fmt.Fprintf(&b, "%d: %s\n", i, lines[i-1])
+				}
+				b.WriteString("\n")
+			}
+			return nil
+		},
+	)
+	if err != nil && !errors.Is(err, errSearchDone) {
+		return "", err
+	}
+
+	if found == 0 {
+		return fmt.Sprintf("no definition of %s found", args.Name), nil
+	}
+	return b.String(), nil
+}
+
+// matchDecl returns the part of decl that defines the symbol, including its
+// doc comment, or nil. Methods match when recv is their receiver type.
+func matchDecl(decl ast.Decl, recv, name string) ast.Node {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Name.Name != name || (d.Recv != nil) != (recv != "") {
+			return nil
+		}
+		if recv != "" && receiverName(d.Recv.List[0].Type) != recv {
+			return nil
+		}
+		return withDoc(d, d.Doc)
+	case *ast.GenDecl:
+		if recv != "" {
+			return nil
+		}
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				if s.Name.Name == name {
+					return genDeclSpec(d, s, s.Doc)
+				}
+			case *ast.ValueSpec:
+				for _, ident := range s.Names {
+					if ident.Name == name {
+						return genDeclSpec(d, s, s.Doc)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// receiverName returns the type name of a method receiver.
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr: // Generic receiver
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// genDeclSpec returns the whole declaration for a single spec, or the spec
+// alone inside a grouped declaration.
+func genDeclSpec(d *ast.GenDecl, spec ast.Spec, doc *ast.CommentGroup) ast.Node {
+	if len(d.Specs) == 1 {
+		return withDoc(d, d.Doc)
+	}
+	return withDoc(spec, doc)
+}
+
+// span is a node covering a declaration and its doc comment.
+type span struct {
+	pos, end token.Pos
+}
+
+func (s span) Pos() token.Pos { return s.pos }
+func (s span) End() token.Pos { return s.end }
+
+// withDoc extends node to start at its doc comment.
+func withDoc(node ast.Node, doc *ast.CommentGroup) ast.Node {
+	if doc == nil {
+		return node
+	}
+	return span{pos: doc.Pos(), end: node.End()}
+}
+
+// walkFiles calls fn with the contents of each file under root, skipping
+// vendored and large or binary files.
+func walkFiles(
+	ctx context.Context, fsys fs.FS, root string,
+	fn func(name string, data []byte) error,
+) error {
+	return fs.WalkDir(
+		fsys, root, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if name != root && skipDir(d.Name()) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil || info.Size() > maxSearchSize || !info.Mode().IsRegular() {
+				return nil
+			}
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) != -1 {
+				return nil
+			}
+
+			return fn(name, data)
+		},
+	)
+}
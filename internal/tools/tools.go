@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Limits keep tool results small enough to fit in the prompt.
+const (
+	maxReadLines   = 400       // Lines returned by a single read_file call
+	maxGrepMatches = 50        // Matches returned by a single grep call
+	maxSymbols     = 5         // Definitions returned by a single find_symbol call
+	maxLineLength  = 200       // Characters kept of each grep match
+	maxSearchSize  = 1 << 20   // Larger files are skipped by grep and find_symbol
+	maxOutputBytes = 32 * 1024 // Bytes of any tool result
+)
+
+// Tool is a read-only repository tool the model can call during a review.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema of the arguments
+	call        func(ctx context.Context, fsys fs.FS, args json.RawMessage) (string, error)
+}
+
+// Toolbox holds the repository tools for a review run. Tools read the files
+// of fsys, usually the tree of the reviewed head revision.
+type Toolbox struct {
+	fsys     fs.FS
+	revision string
+	tools    []Tool
+}
+
+// New creates a toolbox reading the files of fsys. revision identifies the
+// contents of fsys, e.g. a commit hash, or is empty for a working tree that
+// may change between runs.
+func New(fsys fs.FS, revision string) *Toolbox {
+	return &Toolbox{
+		fsys:     fsys,
+		revision: revision,
+		tools:    []Tool{readFileTool, listDirTool, grepTool, findSymbolTool},
+	}
+}
+
+// Revision returns the revision the tools read from.
+func (tb *Toolbox) Revision() string {
+	return tb.revision
+}
+
+// Tools returns the tools of the toolbox.
+func (tb *Toolbox) Tools() []Tool {
+	return tb.tools
+}
+
+// Definitions returns the tool definitions sent to the model.
+func (tb *Toolbox) Definitions() []llms.Tool {
+	definitions := make([]llms.Tool, 0, len(tb.tools))
+	for _, tool := range tb.tools {
+		definitions = append(
+			definitions, llms.Tool{
+				Type: "function",
+				Function: &llms.FunctionDefinition{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.Parameters,
+				},
+			},
+		)
+	}
+	return definitions
+}
+
+// Has reports whether the toolbox has a tool called name.
+func (tb *Toolbox) Has(name string) bool {
+	for _, tool := range tb.tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Call runs the named tool with its JSON arguments. Errors are returned as
+// the result text so the model can correct its call.
+func (tb *Toolbox) Call(ctx context.Context, name string, args string) string {
+	for _, tool := range tb.tools {
+		if tool.Name != name {
+			continue
+		}
+
+		if strings.TrimSpace(args) == "" {
+			args = "{}"
+		}
+		result, err := tool.call(ctx, tb.fsys, json.RawMessage(args))
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return truncateOutput(result)
+	}
+
+	return fmt.Sprintf("error: unknown tool %q", name)
+}
+
+// truncateOutput cuts a result to maxOutputBytes on a line boundary.
+func truncateOutput(s string) string {
+	if len(s) <= maxOutputBytes {
+		return s
+	}
+
+	cut := strings.LastIndexByte(s[:maxOutputBytes], '\n')
+	if cut <= 0 {
+		cut = maxOutputBytes
+	}
+	return s[:cut] + "\n[output truncated]"
+}
+
+// cleanPath turns a path from the model into a path valid for fs.FS.
+func cleanPath(p string) (string, error) {
+	p = strings.TrimPrefix(strings.TrimSpace(p), "./")
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ".", nil
+	}
+	if !fs.ValidPath(p) {
+		return "", fmt.Errorf("invalid path %q: use a path relative to the repository root", p)
+	}
+	return p, nil
+}
+
+// skipDir reports whether a directory is left out of searches.
+func skipDir(name string) bool {
+	return name == ".git" || name == "node_modules" || name == "vendor"
+}
+
+// object returns a JSON schema object with the given properties.
+func object(required []string, properties map[string]any) map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// property returns a JSON schema property.
+func property(typ, description string) map[string]any {
+	return map[string]any{"type": typ, "description": description}
+}
@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testRepo is a small repository for the tool tests.
+var testRepo = fstest.MapFS{
+	"go.mod": {Data: []byte("module example.com/app\n")},
+	"internal/store/store.go": {Data: []byte(`package store
+
+// Store keeps items in memory.
+type Store struct {
+	items map[string]int
+}
+
+// Get returns an item and whether it exists.
+func (s *Store) Get(key string) (int, bool) {
+	v, ok := s.items[key]
+	return v, ok
+}
+
+// Get is a package-level helper.
+func Get(s *Store, key string) int {
+	v, _ := s.Get(key)
+	return v
+}
+`)},
+	"cmd/app/main.go": {Data: []byte(`package main
+
+import "example.com/app/internal/store"
+
+func main() {
+	s := &store.Store{}
+	_ = store.Get(s, "a")
+}
+`)},
+	"vendor/dep/dep.go":  {Data: []byte("package dep\n\nfunc Get() {}\n")},
+	"assets/logo.png":    {Data: []byte("\x89PNG\x00\x00Get")},
+	"docs/empty/.keep":   {Data: []byte{}},
+	"internal/store/x.c": {Data: []byte("int Get(void);\n")},
+}
+
+func TestToolbox_Call(t *testing.T) {
+	tb := New(testRepo, "abc123")
+
+	tests := []struct {
+		name         string
+		tool         string
+		args         string
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			name:         "read file",
+			tool:         "read_file",
+			args:         `{"path":"cmd/app/main.go"}`,
+			wantContains: []string{"1: package main", `7: 	_ = store.Get(s, "a")`},
+		},
+		{
+			name:         "read line range",
+			tool:         "read_file",
+			args:         `{"path":"./internal/store/store.go","start_line":8,"end_line":9}`,
+			wantContains: []string{"8: // Get returns", "9: func (s *Store) Get"},
+			wantMissing:  []string{"3: // Store"},
+		},
+		{
+			name:         "read missing file",
+			tool:         "read_file",
+			args:         `{"path":"nope.go"}`,
+			wantContains: []string{"error:"},
+		},
+		{
+			name:         "path outside the repository",
+			tool:         "read_file",
+			args:         `{"path":"../etc/passwd"}`,
+			wantContains: []string{"error: invalid path"},
+		},
+		{
+			name:         "list root",
+			tool:         "list_dir",
+			args:         `{"path":"."}`,
+			wantContains: []string{"cmd/", "go.mod", "internal/"},
+		},
+		{
+			name:         "grep callers",
+			tool:         "grep",
+			args:         `{"pattern":"store\\.Get\\("}`,
+			wantContains: []string{`cmd/app/main.go:7: _ = store.Get(s, "a")`},
+			wantMissing:  []string{"vendor/", "logo.png"},
+		},
+		{
+			name:         "grep in a directory",
+			tool:         "grep",
+			args:         `{"pattern":"Get","path":"internal"}`,
+			wantContains: []string{"internal/store/store.go:", "internal/store/x.c:1:"},
+			wantMissing:  []string{"cmd/app"},
+		},
+		{
+			name:         "grep invalid pattern",
+			tool:         "grep",
+			args:         `{"pattern":"("}`,
+			wantContains: []string{"error: invalid pattern"},
+		},
+		{
+			name: "find function",
+			tool: "find_symbol",
+			args: `{"name":"Get"}`,
+			wantContains: []string{
+				"internal/store/store.go:14", "// Get is a package-level helper.",
+				"func Get(s *Store, key string) int {",
+			},
+			wantMissing: []string{"func (s *Store) Get", "vendor/"},
+		},
+		{
+			name: "find method",
+			tool: "find_symbol",
+			args: `{"name":"Store.Get"}`,
+			wantContains: []string{
+				"internal/store/store.go:8", "func (s *Store) Get(key string) (int, bool) {",
+			},
+			wantMissing: []string{"func Get("},
+		},
+		{
+			name:         "find type",
+			tool:         "find_symbol",
+			args:         `{"name":"Store"}`,
+			wantContains: []string{"// Store keeps items in memory.", "type Store struct {"},
+		},
+		{
+			name:         "unknown symbol",
+			tool:         "find_symbol",
+			args:         `{"name":"Missing"}`,
+			wantContains: []string{"no definition of Missing found"},
+		},
+		{
+			name:         "unknown tool",
+			tool:         "delete_file",
+			args:         `{}`,
+			wantContains: []string{`error: unknown tool "delete_file"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := tb.Call(context.Background(), tt.tool, tt.args)
+				for _, want := range tt.wantContains {
+					if !strings.Contains(got, want) {
+						t.Errorf("Call() = %q, want it to contain %q", got, want)
+					}
+				}
+				for _, missing := range tt.wantMissing {
+					if strings.Contains(got, missing) {
+						t.Errorf("Call() = %q, want it not to contain %q", got, missing)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestToolbox_Definitions(t *testing.T) {
+	tb := New(testRepo, "")
+
+	definitions := tb.Definitions()
+	if len(definitions) != 4 {
+		t.Fatalf("Expected 4 tools, got %d", len(definitions))
+	}
+	for _, definition := range definitions {
+		if !tb.Has(definition.Function.Name) {
+			t.Errorf("Has(%q) = false", definition.Function.Name)
+		}
+		if definition.Function.Parameters == nil {
+			t.Errorf("Tool %s has no parameters schema", definition.Function.Name)
+		}
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	long := strings.Repeat("0123456789\n", maxOutputBytes/10)
+	got := truncateOutput(long)
+	if len(got) > maxOutputBytes+len("\n[output truncated]") {
+		t.Errorf("truncateOutput() returned %d bytes", len(got))
+	}
+	if !strings.HasSuffix(got, "[output truncated]") {
+		t.Errorf("Expected a truncation note")
+	}
+	if truncateOutput("short") != "short" {
+		t.Errorf("Expected short output to be unchanged")
+	}
+}
@@ -0,0 +1,166 @@
+package triage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+var (
+	// ErrNothingToApply is returned for suggestions without code to replace.
+	ErrNothingToApply = errors.New("the suggestion has no code to replace")
+	// ErrOriginalNotFound is returned when the code a suggestion replaces
+	// isn't in the file, e.g. because it has changed since the review.
+	ErrOriginalNotFound = errors.New("the original code is not in the file")
+)
+
+// Apply replaces the original code of suggestion in the file at path with
+// the suggested code.
+func Apply(path string, suggestion agents.Suggestion) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	updated, err := applySuggestion(string(content), suggestion)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// applySuggestion replaces the original code of suggestion in content. The
+// original code is matched line by line ignoring surrounding whitespace, at
+// the match closest to the reported lines, since the file may have changed
+// since the review. Code quoted from a diff, with +, - and space markers,
+// is matched against the new side of the diff.
+func applySuggestion(content string, suggestion agents.Suggestion) (string, error) {
+	original := splitCode(suggestion.Original)
+	if len(original) == 0 {
+		return "", ErrNothingToApply
+	}
+	replacement := splitCode(suggestion.Suggestion)
+
+	lines := strings.Split(content, "\n")
+	at := findLines(lines, original, suggestion.StartLine-1)
+	if at < 0 && isDiff(original) && (len(replacement) == 0 || isDiff(replacement)) {
+		original, replacement = newSide(original), newSide(replacement)
+		if len(original) > 0 {
+			at = findLines(lines, original, suggestion.StartLine-1)
+		}
+	}
+	if at < 0 {
+		return "", ErrOriginalNotFound
+	}
+
+	// Models often drop the indentation of the code they quote
+	replacement = reindent(
+		replacement, indentation(original[0]), indentation(lines[at]),
+	)
+
+	updated := make([]string, 0, len(lines)-len(original)+len(replacement))
+	updated = append(updated, lines[:at]...)
+	updated = append(updated, replacement...)
+	updated = append(updated, lines[at+len(original):]...)
+	return strings.Join(updated, "\n"), nil
+}
+
+// splitCode splits the code of a suggestion into lines. Models sometimes
+// escape the newlines of multi-line code, which is unescaped like the body
+// of a suggestion; code with real newlines is kept, since its \n can only
+// be part of the code.
+func splitCode(code string) []string {
+	if !strings.Contains(code, "\n") {
+		code = strings.ReplaceAll(code, "\\n", "\n")
+	}
+	code = strings.Trim(code, "\n")
+	if strings.TrimSpace(code) == "" {
+		return nil
+	}
+	return strings.Split(code, "\n")
+}
+
+// findLines returns the index in lines where want starts, closest to near,
+// or -1 when it isn't there.
+func findLines(lines, want []string, near int) int {
+	found := -1
+	for i := 0; i+len(want) <= len(lines); i++ {
+		if !matchLines(lines[i:i+len(want)], want) {
+			continue
+		}
+		if found < 0 || distance(i, near) < distance(found, near) {
+			found = i
+		}
+	}
+	return found
+}
+
+// matchLines reports whether lines and want hold the same code, ignoring
+// surrounding whitespace.
+func matchLines(lines, want []string) bool {
+	for i := range want {
+		if strings.TrimSpace(lines[i]) != strings.TrimSpace(want[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// isDiff reports whether every line starts with a diff marker.
+func isDiff(lines []string) bool {
+	for _, line := range lines {
+		if line == "" || !strings.ContainsRune("+- ", rune(line[0])) {
+			return false
+		}
+	}
+	return true
+}
+
+// newSide returns the lines of the new side of a diff, without markers.
+func newSide(lines []string) []string {
+	var side []string
+	for _, line := range lines {
+		if line[0] != '-' {
+			side = append(side, line[1:])
+		}
+	}
+	return side
+}
+
+// indentation returns the leading whitespace of line.
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// reindent moves lines indented under from to be indented under to.
+func reindent(lines []string, from, to string) []string {
+	if from == to {
+		return lines
+	}
+	reindented := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		reindented[i] = to + strings.TrimPrefix(line, from)
+	}
+	return reindented
+}
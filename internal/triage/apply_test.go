@@ -0,0 +1,128 @@
+package triage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+func TestApplySuggestion(t *testing.T) {
+	content := "package main\n\nfunc main() {\n\tx := 1\n\tfmt.Println(x)\n}\n\nfunc other() {\n\tx := 1\n}\n"
+
+	tests := []struct {
+		name       string
+		suggestion agents.Suggestion
+		want       string
+		wantErr    error
+	}{
+		{
+			name: "at the reported lines",
+			suggestion: agents.Suggestion{
+				Original:   "\tx := 1",
+				Suggestion: "\tx := 2",
+				StartLine:  9,
+				EndLine:    9,
+			},
+			want: "package main\n\nfunc main() {\n\tx := 1\n\tfmt.Println(x)\n}\n\nfunc other() {\n\tx := 2\n}\n",
+		},
+		{
+			name: "closest match to moved lines",
+			suggestion: agents.Suggestion{
+				Original:   "x := 1",
+				Suggestion: "x := 2",
+				StartLine:  3,
+			},
+			want: "package main\n\nfunc main() {\n\tx := 2\n\tfmt.Println(x)\n}\n\nfunc other() {\n\tx := 1\n}\n",
+		},
+		{
+			name: "several lines without indentation",
+			suggestion: agents.Suggestion{
+				Original:   "x := 1\nfmt.Println(x)",
+				Suggestion: "x := 1\n\nlog.Println(x)",
+			},
+			want: "package main\n\nfunc main() {\n\tx := 1\n\n\tlog.Println(x)\n}\n\nfunc other() {\n\tx := 1\n}\n",
+		},
+		{
+			name: "escaped newlines",
+			suggestion: agents.Suggestion{
+				Original:   `x := 1\nfmt.Println(x)`,
+				Suggestion: `x := 2\nfmt.Println(x)`,
+			},
+			want: "package main\n\nfunc main() {\n\tx := 2\n\tfmt.Println(x)\n}\n\nfunc other() {\n\tx := 1\n}\n",
+		},
+		{
+			name: "diff markers",
+			suggestion: agents.Suggestion{
+				Original:   "+\tfmt.Println(x)",
+				Suggestion: "-\tfmt.Println(x)\n+\tfmt.Printf(\"%d\\n\", x)",
+			},
+			want: "package main\n\nfunc main() {\n\tx := 1\n\tfmt.Printf(\"%d\\n\", x)\n}\n\nfunc other() {\n\tx := 1\n}\n",
+		},
+		{
+			name: "deletion",
+			suggestion: agents.Suggestion{
+				Original: "\tfmt.Println(x)",
+			},
+			want: "package main\n\nfunc main() {\n\tx := 1\n}\n\nfunc other() {\n\tx := 1\n}\n",
+		},
+		{
+			name:       "no original code",
+			suggestion: agents.Suggestion{Suggestion: "x := 2"},
+			wantErr:    ErrNothingToApply,
+		},
+		{
+			name: "changed since the review",
+			suggestion: agents.Suggestion{
+				Original:   "y := 1",
+				Suggestion: "y := 2",
+			},
+			wantErr: ErrOriginalNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := applySuggestion(content, tt.suggestion)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("applySuggestion() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("applySuggestion() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("applySuggestion() = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho $1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	err := Apply(path, agents.Suggestion{Original: "echo $1", Suggestion: `echo "$1"`})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if want := "#!/bin/sh\necho \"$1\"\n"; string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the file mode to be kept, got %v", info.Mode())
+	}
+}
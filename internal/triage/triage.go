@@ -0,0 +1,362 @@
+// Package triage steps through the suggestions of a review in a terminal UI
+// to apply, skip, dismiss or copy each of them.
+package triage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// Item is a suggestion to triage.
+type Item struct {
+	Path       string // Path of the file the suggestion applies to
+	Root       string // Directory Path is relative to; empty for the working directory
+	Suggestion agents.Suggestion
+}
+
+// File returns the path to read and write the file of the item at.
+func (i Item) File() string {
+	if i.Root == "" || filepath.IsAbs(i.Path) {
+		return i.Path
+	}
+	return filepath.Join(i.Root, i.Path)
+}
+
+// Location returns the path of the item followed by its lines, if known.
+func (i Item) Location() string {
+	s := i.Suggestion
+	switch {
+	case s.StartLine == 0:
+		return i.Path
+	case s.EndLine > s.StartLine:
+		return fmt.Sprintf("%s:%d-%d", i.Path, s.StartLine, s.EndLine)
+	default:
+		return fmt.Sprintf("%s:%d", i.Path, s.StartLine)
+	}
+}
+
+// Action is what was decided for a suggestion.
+type Action string
+
+// Actions of a decision.
+const (
+	Undecided Action = ""
+	Accepted  Action = "accepted" // Applied to the file
+	Skipped   Action = "skipped"
+	Dismissed Action = "dismissed"
+)
+
+// Decision is the outcome of triaging an item.
+type Decision struct {
+	Item   Item
+	Action Action
+	Reason string // Why the suggestion was dismissed
+	Copied bool   // The suggestion was copied to the clipboard
+}
+
+// Options configures a triage session.
+type Options struct {
+	// Render returns the suggestion of an item as shown in the terminal.
+	Render func(Item) string
+	// Apply applies an accepted suggestion; defaults to Apply.
+	Apply func(Item) error
+	// Copy copies text to the clipboard; defaults to an OSC 52 sequence,
+	// which terminals and tmux forward to the system clipboard.
+	Copy func(text string) error
+}
+
+// Run lets the user triage items in a full-screen terminal UI and returns a
+// decision for each of them, in order. Items left when the user quits are
+// Undecided.
+func Run(items []Item, opts Options) ([]Decision, error) {
+	if opts.Render == nil {
+		opts.Render = func(item Item) string {
+			return item.Suggestion.Title + "\n\n" + item.Suggestion.Body
+		}
+	}
+	if opts.Apply == nil {
+		opts.Apply = func(item Item) error {
+			return Apply(item.File(), item.Suggestion)
+		}
+	}
+	if opts.Copy == nil {
+		opts.Copy = func(text string) error {
+			termenv.NewOutput(os.Stderr).Copy(text)
+			return nil
+		}
+	}
+
+	final, err := tea.NewProgram(newModel(items, opts), tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the triage UI: %w", err)
+	}
+	return final.(*model).decisions, nil
+}
+
+var (
+	headerStyle = lipgloss.NewStyle().Bold(true)
+	statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	helpStyle   = lipgloss.NewStyle().Faint(true)
+)
+
+// chromeLines is the most lines around the rendered suggestion: the header
+// and a blank line above it, and a blank line, the dismissal reason, the
+// status and the help below it.
+const chromeLines = 6
+
+// model is the state of the triage UI.
+type model struct {
+	opts      Options
+	decisions []Decision
+	current   int      // Index of the item on screen
+	lines     []string // Rendered lines of the current item
+	offset    int      // First rendered line on screen
+	height    int      // Terminal height, zero until known
+	reason    []rune   // Dismissal reason being typed
+	typing    bool     // The user is typing a dismissal reason
+	status    string   // Outcome of the last action
+}
+
+func newModel(items []Item, opts Options) *model {
+	m := &model{opts: opts, decisions: make([]Decision, len(items))}
+	for i, item := range items {
+		m.decisions[i].Item = item
+	}
+	m.show(0)
+	return m
+}
+
+func (m *model) Init() tea.Cmd {
+	if len(m.decisions) == 0 {
+		return tea.Quit
+	}
+	return nil
+}
+
+// show puts item i on screen.
+func (m *model) show(i int) {
+	m.current = i
+	m.offset = 0
+	m.lines = nil
+	if i < len(m.decisions) {
+		rendered := strings.TrimRight(m.opts.Render(m.decisions[i].Item), "\n")
+		m.lines = strings.Split(rendered, "\n")
+	}
+}
+
+// next moves on to the following item, ending the session after the last.
+func (m *model) next() tea.Cmd {
+	if m.current+1 >= len(m.decisions) {
+		return tea.Quit
+	}
+	m.show(m.current + 1)
+	return nil
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.scroll(0)
+	case tea.KeyMsg:
+		if m.typing {
+			return m, m.typeReason(msg)
+		}
+		return m, m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// handleKey runs the action bound to key.
+func (m *model) handleKey(key tea.KeyMsg) tea.Cmd {
+	decision := &m.decisions[m.current]
+	m.status = ""
+
+	switch key.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "a":
+		if decision.Action == Accepted {
+			m.status = "Already applied."
+			return nil
+		}
+		if err := m.opts.Apply(decision.Item); err != nil {
+			if errors.Is(err, ErrNothingToApply) {
+				m.status = "Nothing to apply: skip or dismiss it instead."
+			} else {
+				m.status = fmt.Sprintf("Couldn't apply the suggestion: %v", err)
+			}
+			return nil
+		}
+		decision.Action = Accepted
+		return m.next()
+	case "s":
+		if decision.Action != Accepted {
+			decision.Action = Skipped
+		}
+		return m.next()
+	case "d":
+		if decision.Action == Accepted {
+			m.status = "Already applied."
+			return nil
+		}
+		m.typing = true
+		m.reason = []rune(decision.Reason)
+	case "c":
+		if err := m.opts.Copy(copyText(decision.Item)); err != nil {
+			m.status = fmt.Sprintf("Couldn't copy the suggestion: %v", err)
+			return nil
+		}
+		decision.Copied = true
+		m.status = "Copied to the clipboard."
+	case "p", "left":
+		if m.current > 0 {
+			m.show(m.current - 1)
+		}
+	case "n", "right":
+		if m.current+1 < len(m.decisions) {
+			m.show(m.current + 1)
+		}
+	case "up", "k":
+		m.scroll(-1)
+	case "down", "j":
+		m.scroll(1)
+	case "pgup", "b":
+		m.scroll(-m.pageHeight())
+	case "pgdown", " ", "f":
+		m.scroll(m.pageHeight())
+	}
+	return nil
+}
+
+// typeReason edits the dismissal reason being typed.
+func (m *model) typeReason(key tea.KeyMsg) tea.Cmd {
+	switch key.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEsc:
+		m.typing = false
+	case tea.KeyEnter:
+		reason := strings.TrimSpace(string(m.reason))
+		if reason == "" {
+			m.status = "Type why the suggestion is dismissed, or esc to cancel."
+			return nil
+		}
+		m.typing = false
+		m.status = ""
+		decision := &m.decisions[m.current]
+		decision.Action = Dismissed
+		decision.Reason = reason
+		return m.next()
+	case tea.KeyBackspace:
+		if len(m.reason) > 0 {
+			m.reason = m.reason[:len(m.reason)-1]
+		}
+	case tea.KeySpace:
+		m.reason = append(m.reason, ' ')
+	case tea.KeyRunes:
+		m.reason = append(m.reason, key.Runes...)
+	}
+	return nil
+}
+
+// pageHeight returns the number of rendered lines that fit on screen.
+func (m *model) pageHeight() int {
+	if m.height == 0 {
+		return len(m.lines)
+	}
+	return max(m.height-chromeLines, 1)
+}
+
+// scroll moves the rendered suggestion by delta lines, within bounds.
+func (m *model) scroll(delta int) {
+	m.offset = min(m.offset+delta, len(m.lines)-m.pageHeight())
+	m.offset = max(m.offset, 0)
+}
+
+func (m *model) View() string {
+	if m.current >= len(m.decisions) {
+		return ""
+	}
+	decision := m.decisions[m.current]
+
+	header := fmt.Sprintf(
+		"[%d/%d] %s", m.current+1, len(m.decisions), decision.Item.Location(),
+	)
+	if decision.Action != Undecided {
+		header += fmt.Sprintf(" (%s)", decision.Action)
+	}
+
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(header) + "\n\n")
+	end := min(m.offset+m.pageHeight(), len(m.lines))
+	b.WriteString(strings.Join(m.lines[m.offset:end], "\n"))
+	b.WriteString("\n\n")
+
+	if m.typing {
+		b.WriteString("Reason for dismissing: " + string(m.reason) + "█\n")
+		b.WriteString(statusStyle.Render(m.status) + "\n")
+		b.WriteString(helpStyle.Render("enter dismiss · esc cancel"))
+	} else {
+		b.WriteString(statusStyle.Render(m.status) + "\n")
+		b.WriteString(
+			helpStyle.Render(
+				"a accept · s skip · d dismiss · c copy · ←/→ previous/next · ↑/↓ scroll · q quit",
+			),
+		)
+	}
+	return b.String()
+}
+
+// copyText returns the suggested code of an item, or its title and body
+// when it has none.
+func copyText(item Item) string {
+	s := item.Suggestion
+	if s.Suggestion != "" {
+		return s.Suggestion
+	}
+	return fmt.Sprintf("%s: %s\n\n%s", item.Location(), s.Title, s.Body)
+}
+
+// WriteSummary writes the counts of each decision, followed by the accepted
+// and dismissed suggestions.
+func WriteSummary(w io.Writer, decisions []Decision) {
+	counts := make(map[Action]int)
+	copied := 0
+	for _, decision := range decisions {
+		counts[decision.Action]++
+		if decision.Copied {
+			copied++
+		}
+	}
+
+	fmt.Fprintf(w, "\n=== Triage ===\n")
+	fmt.Fprintf(
+		w, "Accepted: %d · Skipped: %d · Dismissed: %d · Undecided: %d · Copied: %d\n",
+		counts[Accepted], counts[Skipped], counts[Dismissed], counts[Undecided],
+		copied,
+	)
+
+	for _, decision := range decisions {
+		item := decision.Item
+		switch decision.Action {
+		case Accepted:
+			fmt.Fprintf(w, "  ✅ %s %s\n", item.Location(), item.Suggestion.Title)
+		case Dismissed:
+			fmt.Fprintf(
+				w, "  ✖ %s %s: %s\n", item.Location(), item.Suggestion.Title,
+				decision.Reason,
+			)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package triage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/j0lvera/miso/internal/agents"
+)
+
+// keys returns the key messages of typing s, with "⏎" for enter and "⎋"
+// for escape.
+func keys(s string) []tea.KeyMsg {
+	var msgs []tea.KeyMsg
+	for _, r := range s {
+		switch r {
+		case '⏎':
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEnter})
+		case '⎋':
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEsc})
+		case ' ':
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+		default:
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	return msgs
+}
+
+func TestModel(t *testing.T) {
+	items := []Item{
+		{Path: "a.go", Suggestion: agents.Suggestion{Title: "Fix a", Original: "a", StartLine: 3}},
+		{Path: "b.go", Suggestion: agents.Suggestion{Title: "Fix b"}},
+		{Path: "c.go", Suggestion: agents.Suggestion{Title: "Fix c", Suggestion: "c := 1"}},
+		{Path: "d.go", Suggestion: agents.Suggestion{Title: "Fix d"}},
+	}
+
+	var applied []string
+	var copied []string
+	m := newModel(
+		items, Options{
+			Render: func(item Item) string { return item.Suggestion.Title },
+			Apply: func(item Item) error {
+				if item.Suggestion.Original == "" {
+					return ErrNothingToApply
+				}
+				applied = append(applied, item.Path)
+				return nil
+			},
+			Copy: func(text string) error {
+				copied = append(copied, text)
+				return nil
+			},
+		},
+	)
+
+	// Accept a, fail to accept b and dismiss it after cancelling once, then
+	// copy and skip c
+	var quit bool
+	for _, msg := range keys("aad⎋dnot a bug⏎cs") {
+		_, cmd := m.Update(msg)
+		if cmd != nil {
+			quit = true
+		}
+	}
+
+	if quit {
+		t.Error("Expected the session to go on until the last item")
+	}
+	if m.current != 3 {
+		t.Errorf("Expected the fourth item on screen, got %d", m.current)
+	}
+	if !strings.Contains(m.View(), "[4/4] d.go") {
+		t.Errorf("Unexpected view:\n%s", m.View())
+	}
+
+	want := []Decision{
+		{Item: items[0], Action: Accepted},
+		{Item: items[1], Action: Dismissed, Reason: "not a bug"},
+		{Item: items[2], Action: Skipped, Copied: true},
+		{Item: items[3]},
+	}
+	for i := range want {
+		got := m.decisions[i]
+		if got.Item.Path != want[i].Item.Path || got.Action != want[i].Action ||
+			got.Reason != want[i].Reason || got.Copied != want[i].Copied {
+			t.Errorf("decision %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if len(applied) != 1 || applied[0] != "a.go" {
+		t.Errorf("Expected a.go applied, got %v", applied)
+	}
+	if len(copied) != 1 || copied[0] != "c := 1" {
+		t.Errorf("Expected the suggested code copied, got %v", copied)
+	}
+
+	// Going back to an applied suggestion doesn't change it
+	for _, msg := range keys("ppps") {
+		m.Update(msg)
+	}
+	if m.decisions[0].Action != Accepted || m.current != 1 {
+		t.Errorf("Expected a.go still accepted, got %+v", m.decisions[0])
+	}
+
+	// Deciding the last item ends the session
+	m.show(3)
+	if _, cmd := m.Update(keys("s")[0]); cmd == nil {
+		t.Error("Expected the session to end after the last item")
+	}
+}
+
+func TestModel_ApplyError(t *testing.T) {
+	items := []Item{{Path: "a.go", Suggestion: agents.Suggestion{Title: "Fix a"}}}
+	m := newModel(
+		items, Options{
+			Render: func(item Item) string { return item.Suggestion.Title },
+			Apply: func(item Item) error {
+				return errors.New("permission denied")
+			},
+		},
+	)
+
+	m.Update(keys("a")[0])
+	if m.decisions[0].Action != Undecided {
+		t.Errorf("Expected the suggestion undecided, got %s", m.decisions[0].Action)
+	}
+	if !strings.Contains(m.View(), "Couldn't apply the suggestion: permission denied") {
+		t.Errorf("Expected the error in the view, got:\n%s", m.View())
+	}
+}
+
+func TestModel_Scroll(t *testing.T) {
+	items := []Item{{Path: "a.go"}}
+	m := newModel(
+		items, Options{
+			Render: func(Item) string {
+				return "1\n2\n3\n4\n5\n6\n7\n8\n9\n10"
+			},
+		},
+	)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: chromeLines + 4})
+
+	tests := []struct {
+		key        tea.KeyMsg
+		wantOffset int
+	}{
+		{tea.KeyMsg{Type: tea.KeyDown}, 1},
+		{tea.KeyMsg{Type: tea.KeyPgDown}, 5},
+		{tea.KeyMsg{Type: tea.KeyPgDown}, 6},
+		{tea.KeyMsg{Type: tea.KeyUp}, 5},
+		{tea.KeyMsg{Type: tea.KeyPgUp}, 1},
+		{tea.KeyMsg{Type: tea.KeyPgUp}, 0},
+	}
+	for _, tt := range tests {
+		m.Update(tt.key)
+		if m.offset != tt.wantOffset {
+			t.Errorf("after %s offset = %d, want %d", tt.key, m.offset, tt.wantOffset)
+		}
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	decisions := []Decision{
+		{
+			Item: Item{
+				Path:       "a.go",
+				Suggestion: agents.Suggestion{Title: "Fix a", StartLine: 3, EndLine: 5},
+			},
+			Action: Accepted,
+			Copied: true,
+		},
+		{
+			Item:   Item{Path: "b.go", Suggestion: agents.Suggestion{Title: "Fix b"}},
+			Action: Dismissed,
+			Reason: "not a bug",
+		},
+		{
+			Item:   Item{Path: "c.go", Suggestion: agents.Suggestion{Title: "Fix c"}},
+			Action: Skipped,
+		},
+		{Item: Item{Path: "d.go"}},
+	}
+
+	var b strings.Builder
+	WriteSummary(&b, decisions)
+
+	want := "\n=== Triage ===\n" +
+		"Accepted: 1 · Skipped: 1 · Dismissed: 1 · Undecided: 1 · Copied: 1\n" +
+		"  ✅ a.go:3-5 Fix a\n" +
+		"  ✖ b.go Fix b: not a bug\n"
+	if b.String() != want {
+		t.Errorf("WriteSummary() = %q, want %q", b.String(), want)
+	}
+}
+
+func TestItem_File(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want string
+	}{
+		{name: "working directory", item: Item{Path: "cmd/main.go"}, want: "cmd/main.go"},
+		{
+			name: "relative to the root",
+			item: Item{Path: "cmd/main.go", Root: "/repo"},
+			want: "/repo/cmd/main.go",
+		},
+		{
+			name: "absolute path",
+			item: Item{Path: "/tmp/main.go", Root: "/repo"},
+			want: "/tmp/main.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.item.File(); got != tt.want {
+					t.Errorf("File() = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}